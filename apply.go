@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/apply"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// applyCommand converges a cluster on a spec file.
+const applyCommand = "apply"
+
+type applyArgs struct {
+	specFile  string
+	stateFile string
+	dryRun    bool
+	prune     bool
+}
+
+func parseApplyArgs(args []string) (applyArgs, error) {
+	parsed := applyArgs{}
+
+	flags := flag.NewFlagSet(applyCommand, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&parsed.specFile, "spec", "", "YAML or JSON file of the cluster spec")
+	flags.StringVar(&parsed.stateFile, "state", "", "file holding the cluster info, created with the cluster")
+	flags.BoolVar(&parsed.dryRun, "dry-run", false, "only print the changes")
+	flags.BoolVar(&parsed.prune, "prune", false, "delete the node pools missing from the spec")
+
+	if err := flags.Parse(args); err != nil {
+		return parsed, fmt.Errorf("%s: %v", applyCommand, err)
+	}
+
+	if flags.NArg() > 0 {
+		return parsed, fmt.Errorf("%s: unexpected arguments %v", applyCommand, flags.Args())
+	}
+
+	if parsed.specFile == "" || parsed.stateFile == "" {
+		return parsed, fmt.Errorf("%s: --spec and --state are required", applyCommand)
+	}
+
+	return parsed, nil
+}
+
+// runApply prints the changes converging the cluster of the state file on the
+// spec, then makes them unless it is a dry run. The state is saved the way
+// the driver saves it, so Rancher can take the cluster over later.
+func runApply(ctx context.Context, factory service.DigitalOceanFactory, args []string, stdout io.Writer) error {
+	parsed, err := parseApplyArgs(args)
+
+	if err != nil {
+		return err
+	}
+
+	clusterState, nodePools, err := readSpec(parsed.specFile)
+
+	if err != nil {
+		return err
+	}
+
+	clusterInfo, err := readClusterInfo(parsed.stateFile, true)
+
+	if err != nil {
+		return err
+	}
+
+	if clusterInfo == nil {
+		clusterInfo = &types.ClusterInfo{}
+	} else {
+		savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(clusterInfo)
+
+		if err != nil {
+			return err
+		}
+
+		clusterState.ClusterID = savedState.ClusterID
+	}
+
+	reconciler := apply.NewReconciler(factory(clusterState.DigitalOceanCredential(), clusterState.API))
+
+	plan, err := reconciler.Plan(ctx, clusterState, nodePools, parsed.prune)
+
+	if err != nil {
+		return err
+	}
+
+	plan.Print(stdout)
+
+	if parsed.dryRun {
+		return nil
+	}
+
+	appliedState, err := reconciler.Apply(ctx, plan)
+
+	if appliedState.ClusterID == "" {
+		return err
+	}
+
+	if saveErr := appliedState.Save(clusterInfo); saveErr != nil {
+		return saveErr
+	}
+
+	if writeErr := writeClusterInfo(parsed.stateFile, clusterInfo); writeErr != nil && err == nil {
+		err = writeErr
+	}
+
+	return err
+}
+
+func readSpec(path string) (state.Cluster, []state.NodePool, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return state.Cluster{}, nil, err
+	}
+
+	spec, err := apply.ParseSpec(data)
+
+	if err != nil {
+		return state.Cluster{}, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	clusterState, nodePools, err := spec.States(options.NewBuilder().BuildCreateOptions(), state.NewBuilder())
+
+	if err != nil {
+		return clusterState, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := service.ValidateAPI(clusterState.API); err != nil {
+		return clusterState, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := clusterState.DigitalOceanCredential().Validate(); err != nil {
+		return clusterState, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return clusterState, nodePools, nil
+}
+
+// runApplyCLI runs apply with the DigitalOcean client settings of the
+// environment.
+func runApplyCLI(args []string) error {
+	ctx, done, err := setupCLI()
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return runApply(ctx, service.NewDigitalOceanFactory(), args, os.Stdout)
+}
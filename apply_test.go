@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const applySpec = `
+token: spec-token
+name: git-cluster
+region-slug: nyc1
+version-slug: 1.17.6-do.0
+node-pools:
+  - name: workers
+    size: s-1vcpu-2gb
+    count: %d
+`
+
+func TestApplyCommand(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	server.SetProvisioningPolls(0)
+
+	factory := service.NewDigitalOceanFactoryWithConfig(service.ClientConfig{BaseURL: server.URL})
+
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	specFile := filepath.Join(dir, "spec.yaml")
+	stateFile := filepath.Join(dir, "state.json")
+
+	writeFile(t, specFile, fmt.Sprintf(applySpec, 2))
+
+	output := &bytes.Buffer{}
+	err := runApply(context.TODO(), factory, []string{"--spec", specFile, "--state", stateFile, "--dry-run"}, output)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "+ create cluster git-cluster", "Diff printed")
+
+	_, err = readClusterInfo(stateFile, false)
+	assert.Error(t, err, "Nothing written on a dry run")
+
+	output.Reset()
+	err = runApply(context.TODO(), factory, []string{"--spec", specFile, "--state", stateFile}, output)
+
+	assert.NoError(t, err, "Cluster created")
+
+	info, err := readClusterInfo(stateFile, false)
+	assert.NoError(t, err)
+
+	clusterState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(info)
+
+	assert.NoError(t, err, "State saved like the driver does")
+	assert.NotEmpty(t, clusterState.ClusterID, "Cluster ID saved")
+	assert.NotEmpty(t, clusterState.NodePoolID, "Managed node pool saved")
+	assert.Equal(t, "git-cluster", clusterState.Name, "Spec settings saved")
+
+	writeFile(t, specFile, fmt.Sprintf(applySpec, 4))
+
+	output.Reset()
+	err = runApply(context.TODO(), factory, []string{"--spec", specFile, "--state", stateFile}, output)
+
+	assert.NoError(t, err, "Cluster updated")
+	assert.Contains(t, output.String(), "count: 2 -> 4", "Update printed")
+
+	cluster, _ := server.Cluster(clusterState.ClusterID)
+	assert.Equal(t, 4, cluster.NodePools[0].Count, "Node pool resized")
+
+	output.Reset()
+	err = runApply(context.TODO(), factory, []string{"--spec", specFile, "--state", stateFile}, output)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "No changes", "Converged")
+}
+
+func TestApplyArguments(t *testing.T) {
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	specFile := filepath.Join(dir, "spec.yaml")
+	writeFile(t, specFile, "name: no-token\nnode-pools:\n  - {name: workers, size: s-1vcpu-2gb, count: 1}\n")
+
+	factory := service.NewDigitalOceanFactoryWithConfig(service.ClientConfig{})
+
+	for _, args := range [][]string{
+		{},
+		{"--spec", specFile},
+		{"--spec", specFile, "--state", filepath.Join(dir, "state.json"), "extra"},
+		{"--spec", filepath.Join(dir, "missing.yaml"), "--state", filepath.Join(dir, "state.json")},
+		{"--spec", specFile, "--state", filepath.Join(dir, "state.json")},
+	} {
+		assert.Error(t, runApply(context.TODO(), factory, args, &bytes.Buffer{}), "args %v", args)
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/client"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/tracing"
+)
+
+// command runs one driver call from the command line, the way Rancher makes
+// it over gRPC, with the cluster info kept in a state file.
+type command struct {
+	description string
+	// optionFlags returns the flags of the options file, nil when the
+	// command takes no options.
+	optionFlags func(types.Driver, context.Context) (*types.DriverFlags, error)
+	// defaults fills options missing from the file with their default.
+	defaults bool
+	// newCluster lets the state file be missing.
+	newCluster bool
+	// stateless commands take no state file.
+	stateless  bool
+	extraFlags func(*flag.FlagSet, *commandInput)
+	// run returns the cluster info to write back, nil to leave the state
+	// file as it is.
+	run func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error)
+}
+
+type commandInput struct {
+	options     *types.DriverOptions
+	clusterInfo *types.ClusterInfo
+	version     string
+	count       int64
+	stdout      io.Writer
+}
+
+var commands = map[string]command{
+	"create-options": {
+		description: "print the options accepted on create",
+		stateless:   true,
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			return nil, printDriverFlags(ctx, input.stdout, driver.GetDriverCreateOptions)
+		},
+	},
+	"update-options": {
+		description: "print the options accepted on update",
+		stateless:   true,
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			return nil, printDriverFlags(ctx, input.stdout, driver.GetDriverUpdateOptions)
+		},
+	},
+	"capabilities": {
+		description: "print the capabilities of the driver",
+		stateless:   true,
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			capabilities, err := driver.GetCapabilities(ctx)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, printJSON(input.stdout, capabilities)
+		},
+	},
+	"create": {
+		description: "create a cluster from the options and save its state",
+		optionFlags: types.Driver.GetDriverCreateOptions,
+		defaults:    true,
+		newCluster:  true,
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			return driver.Create(ctx, input.options, input.clusterInfo)
+		},
+	},
+	"update": {
+		description: "update the cluster of the state with the options",
+		optionFlags: types.Driver.GetDriverUpdateOptions,
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			return driver.Update(ctx, input.clusterInfo, input.options)
+		},
+	},
+	"remove": {
+		description: "remove the cluster of the state",
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			return nil, driver.Remove(ctx, input.clusterInfo)
+		},
+	},
+	"post-check": {
+		description: "read the endpoint, credentials and node count of the cluster into the state",
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			return driver.PostCheck(ctx, input.clusterInfo)
+		},
+	},
+	"get-version": {
+		description: "print the Kubernetes version of the cluster",
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			version, err := driver.GetVersion(ctx, input.clusterInfo)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, printJSON(input.stdout, version)
+		},
+	},
+	"set-version": {
+		description: "upgrade the cluster to --version",
+		extraFlags: func(flags *flag.FlagSet, input *commandInput) {
+			flags.StringVar(&input.version, "version", "", "Kubernetes version to upgrade to")
+		},
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			if input.version == "" {
+				return nil, errors.New("--version is required")
+			}
+
+			version := &types.KubernetesVersion{Version: input.version}
+
+			return input.clusterInfo, driver.SetVersion(ctx, input.clusterInfo, version)
+		},
+	},
+	"get-size": {
+		description: "print the node count of the cluster",
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			count, err := driver.GetClusterSize(ctx, input.clusterInfo)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, printJSON(input.stdout, count)
+		},
+	},
+	"set-size": {
+		description: "resize the node pool of the cluster to --count nodes",
+		extraFlags: func(flags *flag.FlagSet, input *commandInput) {
+			flags.Int64Var(&input.count, "count", 0, "node count")
+		},
+		run: func(ctx context.Context, driver types.Driver, input *commandInput) (*types.ClusterInfo, error) {
+			if input.count <= 0 {
+				return nil, errors.New("--count must be greater than zero")
+			}
+
+			count := &types.NodeCount{Count: input.count}
+
+			return input.clusterInfo, driver.SetClusterSize(ctx, input.clusterInfo, count)
+		},
+	},
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func isCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	_, ok := commands[args[0]]
+
+	return ok
+}
+
+// runCommand runs the command named by the first argument with driver.
+func runCommand(ctx context.Context, driver types.Driver, args []string, stdout io.Writer) error {
+	name := args[0]
+	cmd := commands[name]
+	input := &commandInput{stdout: stdout}
+
+	var optionsFile, stateFile string
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+
+	if !cmd.stateless {
+		flags.StringVar(&stateFile, "state", "", "file holding the cluster info, written back after the call")
+	}
+
+	if cmd.optionFlags != nil {
+		flags.StringVar(&optionsFile, "options", "", "JSON or YAML file of the driver options")
+	}
+
+	if cmd.extraFlags != nil {
+		cmd.extraFlags(flags, input)
+	}
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%s: unexpected arguments %v", name, flags.Args())
+	}
+
+	if stateFile == "" && !cmd.stateless {
+		return fmt.Errorf("%s: --state is required", name)
+	}
+
+	if cmd.optionFlags != nil {
+		if optionsFile == "" {
+			return fmt.Errorf("%s: --options is required", name)
+		}
+
+		driverFlags, err := cmd.optionFlags(driver, ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if input.options, err = readOptions(optionsFile, driverFlags, cmd.defaults); err != nil {
+			return err
+		}
+	}
+
+	if !cmd.stateless {
+		clusterInfo, err := readClusterInfo(stateFile, cmd.newCluster)
+
+		if err != nil {
+			return err
+		}
+
+		input.clusterInfo = clusterInfo
+	}
+
+	clusterInfo, err := cmd.run(ctx, driver, input)
+
+	if clusterInfo != nil {
+		// saved even on failure, Rancher keeps the info returned with errors
+		if writeErr := writeClusterInfo(stateFile, clusterInfo); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+
+	return err
+}
+
+func readOptions(path string, flags *types.DriverFlags, defaults bool) (*types.DriverOptions, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := options.ParseValues(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	driverOptions, err := values.DriverOptions(flags, defaults)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return driverOptions, nil
+}
+
+// readClusterInfo reads the state file. A missing file gives nil when
+// allowMissing is set.
+func readClusterInfo(path string, allowMissing bool) (*types.ClusterInfo, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) && allowMissing {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	clusterInfo := &types.ClusterInfo{}
+
+	if err := json.Unmarshal(data, clusterInfo); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return clusterInfo, nil
+}
+
+// writeClusterInfo replaces the state file in one step, it holds the token of
+// the cluster so only the owner can read it.
+func writeClusterInfo(path string, clusterInfo *types.ClusterInfo) error {
+	data, err := json.MarshalIndent(clusterInfo, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func printDriverFlags(ctx context.Context, writer io.Writer,
+	getOptions func(context.Context) (*types.DriverFlags, error)) error {
+	flags, err := getOptions(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return printJSON(writer, flags)
+}
+
+func printJSON(writer io.Writer, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(writer, string(data))
+
+	return err
+}
+
+// setupCLI configures logging, checks the API settings and starts tracing
+// like the gRPC server does. The context is cancelled on SIGINT or SIGTERM,
+// the returned func stops tracing.
+func setupCLI() (context.Context, func(), error) {
+	if err := logging.Configure(os.Getenv(logging.LevelEnv), os.Getenv(logging.FormatEnv)); err != nil {
+		return nil, nil, err
+	}
+
+	clientConfig, err := service.ClientConfigFromEnv()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := clientConfig.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := signalContext()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.ConfigFromEnv())
+
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return ctx, func() {
+		shutdownTracing(context.Background())
+		cancel()
+	}, nil
+}
+
+// runCLI runs the command of args with an in-process driver.
+func runCLI(args []string) error {
+	ctx, done, err := setupCLI()
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	driver := doks.NewDriver()
+
+	return runCommand(ctx, doks.NewInstrumentedDriver(&driver), args, os.Stdout)
+}
+
+// clientCommand runs the commands against a driver server over gRPC.
+const clientCommand = "client"
+
+const defaultConnectTimeout = 30 * time.Second
+
+// runClient runs the command following the client flags against the driver
+// server at --address rather than an in-process driver.
+func runClient(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet(clientCommand, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	address := flags.String("address", "", "address of the driver gRPC server, e.g. 127.0.0.1:9000")
+	connectTimeout := flags.Duration("connect-timeout", defaultConnectTimeout, "time to wait for the server to answer")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %v", clientCommand, err)
+	}
+
+	if *address == "" {
+		return fmt.Errorf("%s: --address is required", clientCommand)
+	}
+
+	if !isCommand(flags.Args()) {
+		return fmt.Errorf("%s: a command is required, one of %v", clientCommand, commandNames())
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	dialCtx, cancelDial := context.WithTimeout(ctx, *connectTimeout)
+	defer cancelDial()
+
+	driver, err := client.Dial(dialCtx, *address)
+
+	if err != nil {
+		return fmt.Errorf("error connecting to the driver at %s: %v", *address, err)
+	}
+
+	defer driver.Close()
+
+	return runCommand(ctx, driver, flags.Args(), stdout)
+}
+
+// signalContext is cancelled on SIGINT or SIGTERM, or by the returned func.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
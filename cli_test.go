@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCommandDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "doks-cli")
+	assert.NoError(t, err)
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func writeFile(t *testing.T, path, content string) {
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func runTestCommand(t *testing.T, args ...string) (string, error) {
+	driver := doks.NewDriver()
+	stdout := &bytes.Buffer{}
+
+	err := runCommand(context.TODO(), &driver, args, stdout)
+
+	return stdout.String(), err
+}
+
+func TestCommandsAgainstFakeAPI(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	server.SetProvisioningPolls(0)
+
+	os.Setenv(service.APIURLEnv, server.URL)
+	defer os.Unsetenv(service.APIURLEnv)
+
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	optionsFile := filepath.Join(dir, "options.yaml")
+	stateFile := filepath.Join(dir, "state.json")
+
+	writeFile(t, optionsFile, `
+token: fake-token
+name: cli-cluster
+region-slug: nyc1
+version-slug: 1.17.6-do.0
+node-pool-name: workers
+node-pool-size: s-1vcpu-2gb
+node-pool-count: 2
+`)
+
+	_, err := runTestCommand(t, "create", "--options", optionsFile, "--state", stateFile)
+
+	assert.NoError(t, err, "Cluster created")
+	assert.Len(t, server.RequestsTo(http.MethodPost, "/v2/kubernetes/clusters"), 1, "Create sent")
+
+	info, err := readClusterInfo(stateFile, false)
+
+	assert.NoError(t, err, "State written")
+
+	clusterState := struct {
+		ClusterID string `json:"cluster_id"`
+	}{}
+	assert.NoError(t, json.Unmarshal([]byte(info.Metadata["state"]), &clusterState), "Driver state saved")
+
+	stat, err := os.Stat(stateFile)
+
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm(), "State readable by the owner only")
+
+	output, err := runTestCommand(t, "get-size", "--state", stateFile)
+
+	assert.NoError(t, err)
+
+	count := types.NodeCount{}
+	assert.NoError(t, json.Unmarshal([]byte(output), &count))
+	assert.Equal(t, int64(2), count.Count, "Node count printed")
+
+	_, err = runTestCommand(t, "set-size", "--state", stateFile, "--count", "3")
+	assert.NoError(t, err, "Cluster resized")
+
+	output, err = runTestCommand(t, "get-size", "--state", stateFile)
+
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal([]byte(output), &count))
+	assert.Equal(t, int64(3), count.Count, "New node count printed")
+
+	output, err = runTestCommand(t, "get-version", "--state", stateFile)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "1.17.6-do.0", "Version printed")
+
+	_, err = runTestCommand(t, "remove", "--state", stateFile)
+
+	assert.NoError(t, err, "Cluster removed")
+	assert.Len(t, server.RequestsTo(http.MethodDelete, "/v2/kubernetes/clusters/"+clusterState.ClusterID), 1,
+		"Delete sent")
+}
+
+func TestCommandArguments(t *testing.T) {
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	stateFile := filepath.Join(dir, "state.json")
+	optionsFile := filepath.Join(dir, "options.yaml")
+
+	writeFile(t, optionsFile, "unknown-option: value\n")
+
+	for _, args := range [][]string{
+		{"get-size"},
+		{"create", "--state", stateFile},
+		{"create", "--state", stateFile, "--options", optionsFile},
+		{"create", "--state", stateFile, "--options", filepath.Join(dir, "missing.yaml")},
+		{"get-size", "--state", stateFile},
+		{"set-size", "--state", stateFile, "--count", "0"},
+		{"get-size", "--state", stateFile, "extra"},
+	} {
+		_, err := runTestCommand(t, args...)
+
+		assert.Error(t, err, "args %v", args)
+	}
+
+	assert.True(t, isCommand([]string{"post-check", "--state", stateFile}))
+	assert.False(t, isCommand([]string{"9000"}), "Port is not a command")
+	assert.False(t, isCommand(nil))
+}
+
+func TestClusterInfoRoundTrip(t *testing.T) {
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	stateFile := filepath.Join(dir, "state.json")
+
+	info, err := readClusterInfo(stateFile, true)
+
+	assert.NoError(t, err, "Missing state allowed")
+	assert.Nil(t, info)
+
+	_, err = readClusterInfo(stateFile, false)
+	assert.Error(t, err, "Missing state reported")
+
+	saved := &types.ClusterInfo{Endpoint: "https://cluster", NodeCount: 3, Metadata: map[string]string{"state": "{}"}}
+
+	assert.NoError(t, writeClusterInfo(stateFile, saved))
+
+	info, err = readClusterInfo(stateFile, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, saved, info, "Info read back")
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1, "No temporary file left")
+}
+
+func TestStatelessCommands(t *testing.T) {
+	output, err := runTestCommand(t, "create-options")
+
+	assert.NoError(t, err)
+
+	flags := types.DriverFlags{}
+	assert.NoError(t, json.Unmarshal([]byte(output), &flags))
+	assert.Contains(t, flags.Options, "region-slug", "Create options printed")
+
+	output, err = runTestCommand(t, "capabilities")
+
+	assert.NoError(t, err)
+	assert.Contains(t, output, "capabilities", "Capabilities printed")
+
+	_, err = runTestCommand(t, "capabilities", "--state", "state.json")
+	assert.Error(t, err, "No state taken")
+}
+
+func TestClientArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"capabilities"},
+		{"--address", "127.0.0.1:1"},
+		{"--address", "127.0.0.1:1", "unknown"},
+	} {
+		assert.Error(t, runClient(args, ioutil.Discard), "args %v", args)
+	}
+}
@@ -0,0 +1,438 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// Action is what a change does to a resource.
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionUpgrade Action = "upgrade"
+	ActionDelete  Action = "delete"
+)
+
+var actionSymbols = map[Action]string{
+	ActionCreate:  "+",
+	ActionUpdate:  "~",
+	ActionUpgrade: "~",
+	ActionDelete:  "-",
+}
+
+const (
+	resourceCluster  = "cluster"
+	resourceNodePool = "node pool"
+)
+
+// FieldChange is a field a change sets, From is empty on create.
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// Change is one call Apply makes to the DigitalOcean API.
+type Change struct {
+	Action   Action
+	Resource string
+	Name     string
+	Fields   []FieldChange
+	apply    func(ctx context.Context, digitalOcean service.DigitalOcean, plan *Plan) error
+}
+
+// Plan lists the changes converging the cluster on the spec.
+type Plan struct {
+	Cluster   state.Cluster
+	NodePools []state.NodePool
+	Changes   []Change
+	// Unmanaged lists the node pools missing from the spec, deleted only
+	// when pruning.
+	Unmanaged []string
+}
+
+// HasChanges tells whether the cluster differs from the spec.
+func (plan *Plan) HasChanges() bool {
+	return len(plan.Changes) > 0
+}
+
+// Print writes the plan as a diff.
+func (plan *Plan) Print(writer io.Writer) {
+	for _, change := range plan.Changes {
+		fmt.Fprintf(writer, "%s %s %s %s\n", actionSymbols[change.Action], change.Action, change.Resource, change.Name)
+
+		for _, field := range change.Fields {
+			if change.Action == ActionCreate {
+				fmt.Fprintf(writer, "    %s: %s\n", field.Field, field.To)
+				continue
+			}
+
+			fmt.Fprintf(writer, "    %s: %s -> %s\n", field.Field, field.From, field.To)
+		}
+	}
+
+	for _, name := range plan.Unmanaged {
+		fmt.Fprintf(writer, "! node pool %s is not in the spec, kept unless pruning\n", name)
+	}
+
+	if !plan.HasChanges() {
+		fmt.Fprintln(writer, "No changes, the cluster matches the spec.")
+	}
+}
+
+// Reconciler compares clusters with their spec and converges them through
+// the DigitalOcean service.
+type Reconciler struct {
+	digitalOcean service.DigitalOcean
+}
+
+func NewReconciler(digitalOcean service.DigitalOcean) Reconciler {
+	return Reconciler{digitalOcean: digitalOcean}
+}
+
+// Plan compares the cluster of clusterState with the desired settings, a new
+// cluster is planned when clusterState has no cluster ID. Node pools missing
+// from nodePools are deleted when prune is set.
+func (reconciler Reconciler) Plan(ctx context.Context, clusterState state.Cluster, nodePools []state.NodePool,
+	prune bool) (*Plan, error) {
+
+	plan := &Plan{Cluster: clusterState, NodePools: nodePools}
+
+	if clusterState.ClusterID == "" {
+		plan.Changes = append(plan.Changes, createClusterChange(clusterState, nodePools[0]))
+
+		for i := range nodePools[1:] {
+			plan.Changes = append(plan.Changes, createNodePoolChange(nodePools, i+1))
+		}
+
+		return plan, nil
+	}
+
+	clusterStatus, err := reconciler.digitalOcean.GetCluster(ctx, clusterState.ClusterID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := clusterChanges(clusterState, clusterStatus)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Changes = changes
+
+	liveNodePools, err := reconciler.digitalOcean.ListNodePools(ctx, clusterState.ClusterID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := plan.planNodePools(liveNodePools, prune); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// Apply makes the changes of the plan in order. The cluster state returned
+// holds the cluster and managed node pool IDs known so far, also on error, so
+// it can be saved.
+func (reconciler Reconciler) Apply(ctx context.Context, plan *Plan) (state.Cluster, error) {
+	for _, change := range plan.Changes {
+		if err := change.apply(ctx, reconciler.digitalOcean, plan); err != nil {
+			return plan.clusterState(), fmt.Errorf("error applying %s of %s %s: %v", change.Action,
+				change.Resource, change.Name, err)
+		}
+	}
+
+	return plan.clusterState(), nil
+}
+
+func (plan *Plan) clusterState() state.Cluster {
+	clusterState := plan.Cluster
+	clusterState.NodePoolID = plan.NodePools[0].ID
+
+	return clusterState
+}
+
+func createClusterChange(clusterState state.Cluster, nodePool state.NodePool) Change {
+	fields := []FieldChange{
+		{Field: "region-slug", To: clusterState.RegionSlug},
+		{Field: "version-slug", To: clusterState.VersionSlug},
+		{Field: "tags", To: formatList(clusterState.Tags)},
+	}
+
+	if clusterState.VPCID != "" {
+		fields = append(fields, FieldChange{Field: "vpc-id", To: clusterState.VPCID})
+	}
+
+	if clusterState.AutoUpgrade != nil {
+		fields = append(fields, FieldChange{Field: "auto-upgraded", To: strconv.FormatBool(*clusterState.AutoUpgrade)})
+	}
+
+	fields = append(fields, FieldChange{Field: "node-pool", To: nodePool.Name})
+
+	return Change{
+		Action:   ActionCreate,
+		Resource: resourceCluster,
+		Name:     clusterState.Name,
+		Fields:   fields,
+		apply: func(ctx context.Context, digitalOcean service.DigitalOcean, plan *Plan) error {
+			clusterID, nodePoolID, err := digitalOcean.CreateCluster(ctx, plan.Cluster, plan.NodePools[0])
+
+			if err != nil {
+				return err
+			}
+
+			plan.Cluster.ClusterID = clusterID
+			plan.NodePools[0].ID = nodePoolID
+
+			return digitalOcean.WaitClusterCreated(ctx, clusterID)
+		},
+	}
+}
+
+func clusterChanges(clusterState state.Cluster, clusterStatus *state.ClusterStatus) ([]Change, error) {
+	if clusterState.RegionSlug != "" && clusterState.RegionSlug != clusterStatus.RegionSlug {
+		return nil, fmt.Errorf("region-slug cannot change from %s to %s without recreating the cluster",
+			clusterStatus.RegionSlug, clusterState.RegionSlug)
+	}
+
+	if clusterState.VPCID != "" && clusterState.VPCID != clusterStatus.VPCID {
+		return nil, fmt.Errorf("vpc-id cannot change from %s to %s without recreating the cluster",
+			clusterStatus.VPCID, clusterState.VPCID)
+	}
+
+	changes := []Change{}
+
+	if clusterState.VersionSlug != "" && clusterState.VersionSlug != clusterStatus.VersionSlug {
+		changes = append(changes, Change{
+			Action:   ActionUpgrade,
+			Resource: resourceCluster,
+			Name:     clusterState.Name,
+			Fields:   []FieldChange{{Field: "version-slug", From: clusterStatus.VersionSlug, To: clusterState.VersionSlug}},
+			apply: func(ctx context.Context, digitalOcean service.DigitalOcean, plan *Plan) error {
+				return digitalOcean.UpgradeKubernetesVersion(ctx, plan.Cluster.ClusterID, plan.Cluster.VersionSlug)
+			},
+		})
+	}
+
+	fields := []FieldChange{}
+
+	if clusterState.Name != clusterStatus.Name {
+		fields = append(fields, FieldChange{Field: "name", From: clusterStatus.Name, To: clusterState.Name})
+	}
+
+	from, to := formatList(state.UserTags(clusterStatus.Tags)), formatList(state.UserTags(clusterState.Tags))
+
+	if from != to {
+		fields = append(fields, FieldChange{Field: "tags", From: from, To: to})
+	}
+
+	if clusterState.AutoUpgrade != nil && *clusterState.AutoUpgrade != clusterStatus.AutoUpgrade {
+		fields = append(fields, FieldChange{Field: "auto-upgraded", From: strconv.FormatBool(clusterStatus.AutoUpgrade),
+			To: strconv.FormatBool(*clusterState.AutoUpgrade)})
+	}
+
+	if len(fields) > 0 {
+		changes = append(changes, Change{
+			Action:   ActionUpdate,
+			Resource: resourceCluster,
+			Name:     clusterState.Name,
+			Fields:   fields,
+			apply: func(ctx context.Context, digitalOcean service.DigitalOcean, plan *Plan) error {
+				return digitalOcean.UpdateCluster(ctx, plan.Cluster.ClusterID, plan.Cluster)
+			},
+		})
+	}
+
+	return changes, nil
+}
+
+// planNodePools matches the node pools by name. Updates come before creates
+// and deletes last, so the cluster keeps capacity while converging.
+func (plan *Plan) planNodePools(liveNodePools []state.NodePool, prune bool) error {
+	live := map[string]state.NodePool{}
+
+	for _, nodePool := range liveNodePools {
+		live[nodePool.Name] = nodePool
+	}
+
+	creates := []Change{}
+
+	for i := range plan.NodePools {
+		desired := &plan.NodePools[i]
+		current, ok := live[desired.Name]
+
+		if !ok {
+			creates = append(creates, createNodePoolChange(plan.NodePools, i))
+			continue
+		}
+
+		delete(live, desired.Name)
+		desired.ID = current.ID
+
+		if desired.Size != current.Size {
+			return fmt.Errorf("size of node pool %s cannot change from %s to %s, add a new node pool instead",
+				desired.Name, current.Size, desired.Size)
+		}
+
+		if isAutoScaled(*desired) {
+			// the autoscaler owns the count
+			desired.Count = current.Count
+		}
+
+		if fields := nodePoolFields(*desired, current); len(fields) > 0 {
+			plan.Changes = append(plan.Changes, updateNodePoolChange(i, desired.Name, fields))
+		}
+	}
+
+	plan.Changes = append(plan.Changes, creates...)
+
+	names := make([]string, 0, len(live))
+
+	for name := range live {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !prune {
+			plan.Unmanaged = append(plan.Unmanaged, name)
+			continue
+		}
+
+		plan.Changes = append(plan.Changes, deleteNodePoolChange(live[name]))
+	}
+
+	return nil
+}
+
+func nodePoolFields(desired, current state.NodePool) []FieldChange {
+	fields := []FieldChange{}
+
+	if desired.Count != current.Count {
+		fields = append(fields, FieldChange{Field: "count", From: strconv.Itoa(current.Count),
+			To: strconv.Itoa(desired.Count)})
+	}
+
+	if isAutoScaled(desired) != isAutoScaled(current) {
+		fields = append(fields, FieldChange{Field: "autoscale", From: strconv.FormatBool(isAutoScaled(current)),
+			To: strconv.FormatBool(isAutoScaled(desired))})
+	}
+
+	if isAutoScaled(desired) {
+		if desired.MinNodes != current.MinNodes {
+			fields = append(fields, FieldChange{Field: "min-nodes", From: strconv.Itoa(current.MinNodes),
+				To: strconv.Itoa(desired.MinNodes)})
+		}
+
+		if desired.MaxNodes != current.MaxNodes {
+			fields = append(fields, FieldChange{Field: "max-nodes", From: strconv.Itoa(current.MaxNodes),
+				To: strconv.Itoa(desired.MaxNodes)})
+		}
+	}
+
+	if from, to := formatList(state.UserTags(current.Tags)), formatList(state.UserTags(desired.Tags)); from != to {
+		fields = append(fields, FieldChange{Field: "tags", From: from, To: to})
+	}
+
+	if from, to := formatLabels(current.Labels), formatLabels(desired.Labels); from != to {
+		fields = append(fields, FieldChange{Field: "labels", From: from, To: to})
+	}
+
+	return fields
+}
+
+func createNodePoolChange(nodePools []state.NodePool, index int) Change {
+	nodePool := nodePools[index]
+	fields := []FieldChange{
+		{Field: "size", To: nodePool.Size},
+		{Field: "count", To: strconv.Itoa(nodePool.Count)},
+	}
+
+	if isAutoScaled(nodePool) {
+		fields = append(fields, FieldChange{Field: "autoscale",
+			To: fmt.Sprintf("%d to %d nodes", nodePool.MinNodes, nodePool.MaxNodes)})
+	}
+
+	if len(nodePool.Labels) > 0 {
+		fields = append(fields, FieldChange{Field: "labels", To: formatLabels(nodePool.Labels)})
+	}
+
+	return Change{
+		Action:   ActionCreate,
+		Resource: resourceNodePool,
+		Name:     nodePool.Name,
+		Fields:   fields,
+		apply: func(ctx context.Context, digitalOcean service.DigitalOcean, plan *Plan) error {
+			nodePoolID, err := digitalOcean.CreateNodePool(ctx, plan.Cluster.ClusterID,
+				state.TagNodePool(plan.Cluster, plan.NodePools[index]))
+
+			if err != nil {
+				return err
+			}
+
+			plan.NodePools[index].ID = nodePoolID
+
+			return nil
+		},
+	}
+}
+
+func updateNodePoolChange(index int, name string, fields []FieldChange) Change {
+	return Change{
+		Action:   ActionUpdate,
+		Resource: resourceNodePool,
+		Name:     name,
+		Fields:   fields,
+		apply: func(ctx context.Context, digitalOcean service.DigitalOcean, plan *Plan) error {
+			nodePool := state.TagNodePool(plan.Cluster, plan.NodePools[index])
+
+			return digitalOcean.UpdateNodePool(ctx, plan.Cluster.ClusterID, nodePool.ID, nodePool)
+		},
+	}
+}
+
+func deleteNodePoolChange(nodePool state.NodePool) Change {
+	return Change{
+		Action:   ActionDelete,
+		Resource: resourceNodePool,
+		Name:     nodePool.Name,
+		apply: func(ctx context.Context, digitalOcean service.DigitalOcean, plan *Plan) error {
+			return digitalOcean.DeleteNodePool(ctx, plan.Cluster.ClusterID, nodePool.ID)
+		},
+	}
+}
+
+func isAutoScaled(nodePool state.NodePool) bool {
+	return nodePool.AutoScale != nil && *nodePool.AutoScale
+}
+
+func formatList(list []string) string {
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+
+	return "[" + strings.Join(sorted, ", ") + "]"
+}
+
+func formatLabels(labels map[string]string) string {
+	list := make([]string, 0, len(labels))
+
+	for key, value := range labels {
+		list = append(list, key+"="+value)
+	}
+
+	return formatList(list)
+}
@@ -0,0 +1,181 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeReconciler() (*fake.Server, Reconciler) {
+	server := fake.NewServer()
+	server.SetProvisioningPolls(0)
+
+	factory := service.NewDigitalOceanFactoryWithConfig(service.ClientConfig{BaseURL: server.URL})
+
+	return server, NewReconciler(factory(state.Credential{Token: "spec-token"}, state.API{}))
+}
+
+func specStates(t *testing.T, spec string) (state.Cluster, []state.NodePool) {
+	parsed, err := ParseSpec([]byte(spec))
+	assert.NoError(t, err)
+
+	clusterState, nodePools, err := parsed.States(options.NewBuilder().BuildCreateOptions(), state.NewBuilder())
+	assert.NoError(t, err)
+
+	return clusterState, nodePools
+}
+
+func applySpec(t *testing.T, reconciler Reconciler, clusterID, spec string, prune bool) (*Plan, state.Cluster) {
+	clusterState, nodePools := specStates(t, spec)
+	clusterState.ClusterID = clusterID
+
+	plan, err := reconciler.Plan(context.TODO(), clusterState, nodePools, prune)
+
+	if !assert.NoError(t, err) {
+		return nil, clusterState
+	}
+
+	applied, err := reconciler.Apply(context.TODO(), plan)
+	assert.NoError(t, err)
+
+	return plan, applied
+}
+
+func TestPlanNewCluster(t *testing.T) {
+	server, reconciler := newFakeReconciler()
+	defer server.Close()
+
+	plan, applied := applySpec(t, reconciler, "", specYAML, false)
+
+	assert.Len(t, plan.Changes, 2, "Cluster and extra node pool created")
+	assert.Equal(t, ActionCreate, plan.Changes[0].Action)
+	assert.Equal(t, "cluster", plan.Changes[0].Resource)
+	assert.NotEmpty(t, applied.ClusterID, "Cluster ID kept")
+	assert.NotEmpty(t, applied.NodePoolID, "Managed node pool ID kept")
+
+	cluster, ok := server.Cluster(applied.ClusterID)
+
+	assert.True(t, ok)
+	assert.Len(t, cluster.NodePools, 2, "Both node pools exist")
+
+	output := &bytes.Buffer{}
+	plan.Print(output)
+
+	assert.Contains(t, output.String(), "+ create cluster production")
+	assert.Contains(t, output.String(), "+ create node pool batch")
+}
+
+func TestPlanConverged(t *testing.T) {
+	server, reconciler := newFakeReconciler()
+	defer server.Close()
+
+	_, applied := applySpec(t, reconciler, "", specYAML, false)
+
+	clusterState, nodePools := specStates(t, specYAML)
+	clusterState.ClusterID = applied.ClusterID
+
+	plan, err := reconciler.Plan(context.TODO(), clusterState, nodePools, false)
+
+	assert.NoError(t, err)
+	assert.False(t, plan.HasChanges(), "Nothing to change")
+
+	output := &bytes.Buffer{}
+	plan.Print(output)
+
+	assert.Contains(t, output.String(), "No changes")
+}
+
+func TestPlanUpdates(t *testing.T) {
+	server, reconciler := newFakeReconciler()
+	defer server.Close()
+
+	_, applied := applySpec(t, reconciler, "", specYAML, false)
+
+	updated := `
+token: spec-token
+name: production
+region-slug: nyc1
+version-slug: 1.18.3-do.0
+tags: [team-a, team-b]
+node-pools:
+  - name: workers
+    size: s-2vcpu-4gb
+    count: 3
+    labels:
+      role: worker
+  - name: web
+    size: s-1vcpu-2gb
+    count: 1
+`
+
+	plan, _ := applySpec(t, reconciler, applied.ClusterID, updated, false)
+	output := &bytes.Buffer{}
+	plan.Print(output)
+
+	assert.Contains(t, output.String(), "~ upgrade cluster production\n    version-slug: 1.17.6-do.0 -> 1.18.3-do.0")
+	assert.Contains(t, output.String(), "tags: [team-a] -> [team-a, team-b]")
+	assert.Contains(t, output.String(), "~ update node pool workers\n    count: 2 -> 3")
+	assert.Contains(t, output.String(), "+ create node pool web")
+	assert.Contains(t, output.String(), "! node pool batch is not in the spec")
+
+	cluster, _ := server.Cluster(applied.ClusterID)
+
+	assert.Equal(t, "1.18.3-do.0", cluster.VersionSlug, "Cluster upgraded")
+	assert.Len(t, cluster.NodePools, 3, "Unmanaged node pool kept")
+	assert.Empty(t, server.RequestsTo(http.MethodDelete, "/v2/kubernetes/clusters/"+applied.ClusterID+
+		"/node_pools/"+plan.NodePools[1].ID), "Nothing deleted")
+
+	plan, _ = applySpec(t, reconciler, applied.ClusterID, updated, true)
+
+	assert.Len(t, plan.Changes, 1, "Only the prune left")
+	assert.Equal(t, ActionDelete, plan.Changes[0].Action)
+
+	cluster, _ = server.Cluster(applied.ClusterID)
+	assert.Len(t, cluster.NodePools, 2, "Node pool pruned")
+}
+
+func TestPlanImmutableFields(t *testing.T) {
+	server, reconciler := newFakeReconciler()
+	defer server.Close()
+
+	_, applied := applySpec(t, reconciler, "", specYAML, false)
+
+	clusterState, nodePools := specStates(t, specYAML)
+	clusterState.ClusterID = applied.ClusterID
+	clusterState.RegionSlug = "ams3"
+
+	_, err := reconciler.Plan(context.TODO(), clusterState, nodePools, false)
+	assert.Error(t, err, "Region cannot change")
+
+	clusterState, nodePools = specStates(t, specYAML)
+	clusterState.ClusterID = applied.ClusterID
+	nodePools[0].Size = "s-4vcpu-8gb"
+
+	_, err = reconciler.Plan(context.TODO(), clusterState, nodePools, false)
+	assert.Error(t, err, "Node pool size cannot change")
+}
+
+func TestApplyKeepsClusterIDOnFailure(t *testing.T) {
+	server, reconciler := newFakeReconciler()
+	defer server.Close()
+
+	server.Fail(fake.Failure{Method: http.MethodPost, Path: "/v2/kubernetes/clusters/", Status: http.StatusInternalServerError,
+		Message: "node pool creation failed"})
+
+	clusterState, nodePools := specStates(t, specYAML)
+
+	plan, err := reconciler.Plan(context.TODO(), clusterState, nodePools, false)
+	assert.NoError(t, err)
+
+	applied, err := reconciler.Apply(context.TODO(), plan)
+
+	assert.Error(t, err, "Node pool failure reported")
+	assert.NotEmpty(t, applied.ClusterID, "Created cluster kept in the state")
+}
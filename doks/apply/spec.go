@@ -0,0 +1,196 @@
+// Package apply converges a cluster on a spec file, so clusters can be kept
+// in Git and reconciled without going through Rancher.
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"gopkg.in/yaml.v2"
+)
+
+// nodePoolOptionPrefix starts the options describing the node pool Rancher
+// manages, which a spec lists under node-pools instead.
+const nodePoolOptionPrefix = "node-pool-"
+
+// driverOnlyOptions are read by the driver alone, a spec refuses them rather
+// than ignoring them.
+var driverOnlyOptions = map[string]string{
+	"clone-from-cluster-id": "export the cluster instead",
+	"project-id":            "assign the cluster to a project with update",
+	"project-name":          "assign the cluster to a project with update",
+}
+
+// Spec describes a cluster with the options Rancher accepts, plus its node
+// pools. The first node pool is the one Rancher manages.
+type Spec struct {
+	Options   options.Values `yaml:",inline"`
+	NodePools []NodePoolSpec `yaml:"node-pools"`
+}
+
+// NodePoolSpec describes a node pool of the spec.
+type NodePoolSpec struct {
+	Name      string            `yaml:"name"`
+	Size      string            `yaml:"size"`
+	Count     int               `yaml:"count"`
+	AutoScale bool              `yaml:"autoscale"`
+	MinNodes  int               `yaml:"min-nodes"`
+	MaxNodes  int               `yaml:"max-nodes"`
+	Tags      []string          `yaml:"tags"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+// ParseSpec reads a spec written in YAML or JSON.
+func ParseSpec(data []byte) (Spec, error) {
+	spec := Spec{}
+
+	if err := yaml.UnmarshalStrict(data, &spec); err != nil {
+		return spec, fmt.Errorf("error reading the spec: %v", err)
+	}
+
+	return spec, spec.validate()
+}
+
+func (spec Spec) validate() error {
+	for name := range spec.Options {
+		if strings.HasPrefix(name, nodePoolOptionPrefix) {
+			return fmt.Errorf("option %q is set by node-pools in a spec", name)
+		}
+
+		if hint, ok := driverOnlyOptions[name]; ok {
+			return fmt.Errorf("option %q is not supported in a spec, %s", name, hint)
+		}
+	}
+
+	if len(spec.NodePools) == 0 {
+		return errors.New("the spec has no node pool")
+	}
+
+	names := map[string]bool{}
+
+	for _, nodePool := range spec.NodePools {
+		if nodePool.Name == "" || nodePool.Size == "" {
+			return errors.New("every node pool needs a name and a size")
+		}
+
+		if names[nodePool.Name] {
+			return fmt.Errorf("node pool %q is listed twice", nodePool.Name)
+		}
+
+		names[nodePool.Name] = true
+	}
+
+	return nil
+}
+
+// DriverOptions returns the options Rancher would send for the spec, with the
+// first node pool as the managed one.
+func (spec Spec) DriverOptions(flags *types.DriverFlags) (*types.DriverOptions, error) {
+	values := options.Values{}
+
+	for name, value := range spec.Options {
+		values[name] = value
+	}
+
+	managed := spec.NodePools[0]
+
+	values["node-pool-name"] = managed.Name
+	values["node-pool-size"] = managed.Size
+	values["node-pool-count"] = managed.initialCount()
+	values["node-pool-autoscale"] = managed.AutoScale
+	values["node-pool-min"] = managed.MinNodes
+	values["node-pool-max"] = managed.MaxNodes
+	values["node-pool-labels"] = labelList(managed.Labels)
+
+	return values.DriverOptions(flags, true)
+}
+
+// States builds the cluster state the same way the driver does on create,
+// and the state of every node pool of the spec.
+func (spec Spec) States(flags *types.DriverFlags, builder state.Builder) (state.Cluster, []state.NodePool, error) {
+	driverOptions, err := spec.DriverOptions(flags)
+
+	if err != nil {
+		return state.Cluster{}, nil, err
+	}
+
+	clusterState, managed, err := builder.BuildStatesFromOpts(driverOptions)
+
+	if err != nil {
+		return clusterState, nil, err
+	}
+
+	if clusterState.Name == "" {
+		return clusterState, nil, errors.New("the spec has no cluster name")
+	}
+
+	managed.Tags = spec.NodePools[0].Tags
+	nodePools := []state.NodePool{managed}
+
+	for _, nodePool := range spec.NodePools[1:] {
+		nodePools = append(nodePools, nodePool.state())
+	}
+
+	return clusterState, nodePools, nil
+}
+
+func (nodePool NodePoolSpec) state() state.NodePool {
+	autoScale := nodePool.AutoScale
+	nodePoolState := state.NodePool{
+		Name:      nodePool.Name,
+		Size:      nodePool.Size,
+		Count:     nodePool.initialCount(),
+		Tags:      nodePool.Tags,
+		Labels:    nodePool.Labels,
+		AutoScale: &autoScale,
+	}
+
+	if autoScale {
+		nodePoolState.MinNodes = nodePool.MinNodes
+		nodePoolState.MaxNodes = nodePool.MaxNodes
+	}
+
+	if nodePoolState.Labels == nil {
+		nodePoolState.Labels = map[string]string{}
+	}
+
+	return nodePoolState
+}
+
+// initialCount is the count of the node pool, an autoscaled one may leave
+// it out to start with its minimum.
+func (nodePool NodePoolSpec) initialCount() int {
+	if nodePool.Count > 0 || !nodePool.AutoScale {
+		return nodePool.Count
+	}
+
+	if nodePool.MinNodes > 0 {
+		return nodePool.MinNodes
+	}
+
+	return 1
+}
+
+// labelList writes labels as the key=value list of the driver options.
+func labelList(labels map[string]string) []interface{} {
+	list := make([]string, 0, len(labels))
+
+	for key, value := range labels {
+		list = append(list, key+"="+value)
+	}
+
+	sort.Strings(list)
+
+	values := make([]interface{}, len(list))
+
+	for i, label := range list {
+		values[i] = label
+	}
+
+	return values
+}
@@ -0,0 +1,87 @@
+package apply
+
+import (
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const specYAML = `
+token: spec-token
+name: production
+region-slug: nyc1
+version-slug: 1.17.6-do.0
+tags: [team-a]
+node-pools:
+  - name: workers
+    size: s-2vcpu-4gb
+    count: 2
+    labels:
+      role: worker
+  - name: batch
+    size: s-4vcpu-8gb
+    autoscale: true
+    min-nodes: 1
+    max-nodes: 5
+`
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(specYAML))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "production", spec.Options["name"], "Cluster options inline")
+	assert.Len(t, spec.NodePools, 2, "Node pools read")
+	assert.Equal(t, map[string]string{"role": "worker"}, spec.NodePools[0].Labels, "Labels read")
+}
+
+func TestParseSpecInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"name: production\n",
+		"name: production\nnode-pools:\n  - name: workers\n",
+		"name: production\nnode-pools:\n  - {name: a, size: s-1vcpu-2gb}\n  - {name: a, size: s-1vcpu-2gb}\n",
+		"name: production\nnode-pools:\n  - {name: a, size: s-1vcpu-2gb, sizes: 3}\n",
+		"node-pool-count: 3\nnode-pools:\n  - {name: a, size: s-1vcpu-2gb}\n",
+		"clone-from-cluster-id: abcd\nnode-pools:\n  - {name: a, size: s-1vcpu-2gb}\n",
+		"project-name: team-a\nnode-pools:\n  - {name: a, size: s-1vcpu-2gb}\n",
+	} {
+		_, err := ParseSpec([]byte(spec))
+
+		assert.Error(t, err, "spec %q", spec)
+	}
+}
+
+func TestSpecDriverOptions(t *testing.T) {
+	spec, _ := ParseSpec([]byte(specYAML))
+
+	driverOptions, err := spec.DriverOptions(options.NewBuilder().BuildCreateOptions())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "workers", driverOptions.StringOptions["node-pool-name"], "First node pool managed by Rancher")
+	assert.Equal(t, int64(2), driverOptions.IntOptions["node-pool-count"], "Count equals")
+	assert.Equal(t, []string{"role=worker"}, driverOptions.StringSliceOptions["node-pool-labels"].Value,
+		"Labels as options")
+}
+
+func TestSpecStates(t *testing.T) {
+	spec, _ := ParseSpec([]byte(specYAML))
+
+	clusterState, nodePools, err := spec.States(options.NewBuilder().BuildCreateOptions(), state.NewBuilder())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "production", clusterState.Name, "Name equals")
+	assert.Equal(t, "spec-token", clusterState.Token, "Token equals")
+	assert.Equal(t, []string{"team-a"}, clusterState.Tags, "Tags equal")
+	assert.NotNil(t, clusterState.AutoUpgrade, "Default applied")
+	assert.Len(t, nodePools, 2)
+	assert.Equal(t, "workers", nodePools[0].Name)
+	assert.Equal(t, 2, nodePools[0].Count)
+	assert.True(t, *nodePools[1].AutoScale, "Autoscale set")
+	assert.Equal(t, 5, nodePools[1].MaxNodes, "Max nodes equals")
+
+	spec.Options["name"] = ""
+
+	_, _, err = spec.States(options.NewBuilder().BuildCreateOptions(), state.NewBuilder())
+	assert.Error(t, err, "Name required")
+}
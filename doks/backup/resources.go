@@ -0,0 +1,496 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	resourcesDir     = "resources"
+	clusterScopedDir = "_cluster"
+	coreGroupDir     = "core"
+
+	// exportPageSize is how many objects a list call of the export reads.
+	exportPageSize = 500
+
+	serviceAccountTokenType = "kubernetes.io/service-account-token"
+)
+
+// skippedResources are recreated by Kubernetes or DigitalOcean and must not be
+// restored from a backup.
+var skippedResources = map[string]bool{
+	"events":                           true,
+	"events.events.k8s.io":             true,
+	"nodes":                            true,
+	"endpoints":                        true,
+	"componentstatuses":                true,
+	"leases.coordination.k8s.io":       true,
+	"controllerrevisions.apps":         true,
+	"csinodes.storage.k8s.io":          true,
+	"volumeattachments.storage.k8s.io": true,
+}
+
+// systemNamespaces are managed by Kubernetes and DigitalOcean. Neither they
+// nor the objects they hold are backed up or restored.
+var systemNamespaces = map[string]bool{
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// managedClusterResources are cluster scoped resources DigitalOcean installs
+// and keeps up to date. Objects of these kinds that already exist in the
+// target cluster are never overwritten by a restore.
+var managedClusterResources = map[string]bool{
+	"apiservices.apiregistration.k8s.io":                           true,
+	"mutatingwebhookconfigurations.admissionregistration.k8s.io":   true,
+	"validatingwebhookconfigurations.admissionregistration.k8s.io": true,
+	"storageclasses.storage.k8s.io":                                true,
+	"priorityclasses.scheduling.k8s.io":                            true,
+}
+
+// managedObjectLabels mark the objects Kubernetes and the cluster add-ons
+// reconcile on their own.
+var managedObjectLabels = []string{
+	"kubernetes.io/bootstrapping",
+	"addonmanager.kubernetes.io/mode",
+}
+
+// restoreFirst lists the resources other objects depend on, in restore order.
+var restoreFirst = []string{
+	"customresourcedefinitions.apiextensions.k8s.io",
+	"namespaces",
+}
+
+// Resources exports every namespaced and cluster scoped object of a cluster
+// to a gzipped tarball and applies such a tarball back to a cluster.
+type Resources interface {
+	Export(writer io.Writer) error
+	Import(reader io.Reader) error
+}
+
+type ResourcesFactory func(restConfig *rest.Config) (Resources, error)
+
+func NewResourcesFactory() ResourcesFactory {
+	return func(restConfig *rest.Config) (Resources, error) {
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating discovery client")
+		}
+
+		dynamicClient, err := dynamic.NewForConfig(restConfig)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating dynamic client")
+		}
+
+		return newResources(discoveryClient, dynamicClient), nil
+	}
+}
+
+type resourcesImpl struct {
+	discovery discovery.DiscoveryInterface
+	dynamic   dynamic.Interface
+}
+
+func newResources(discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface) Resources {
+	return &resourcesImpl{
+		discovery: discoveryClient,
+		dynamic:   dynamicClient,
+	}
+}
+
+type backupObject struct {
+	resource   schema.GroupVersionResource
+	namespaced bool
+	object     *unstructured.Unstructured
+}
+
+func (r *resourcesImpl) Export(writer io.Writer) error {
+	resourceLists, err := r.discovery.ServerPreferredResources()
+
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return errors.Wrap(err, "error discovering cluster resources")
+	}
+
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, resourceList := range resourceLists {
+		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+
+		if err != nil {
+			return errors.Wrapf(err, "error parsing group version %s", resourceList.GroupVersion)
+		}
+
+		for _, apiResource := range resourceList.APIResources {
+			if !isBackupResource(groupVersion, apiResource) {
+				continue
+			}
+
+			resource := groupVersion.WithResource(apiResource.Name)
+
+			err = r.exportResource(tarWriter, resource, apiResource.Namespaced)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return errors.Wrap(err, "error closing backup tarball")
+	}
+
+	return gzipWriter.Close()
+}
+
+func (r *resourcesImpl) exportResource(tarWriter *tar.Writer, resource schema.GroupVersionResource,
+	namespaced bool) error {
+
+	listOptions := metav1.ListOptions{Limit: exportPageSize}
+
+	for {
+		list, err := r.dynamic.Resource(resource).List(listOptions)
+
+		if err != nil {
+			return errors.Wrapf(err, "error listing %s", resource.String())
+		}
+
+		for i := range list.Items {
+			err = exportObject(tarWriter, resource, namespaced, &list.Items[i])
+
+			if err != nil {
+				return err
+			}
+		}
+
+		listOptions.Continue = list.GetContinue()
+
+		if listOptions.Continue == "" {
+			return nil
+		}
+	}
+}
+
+func exportObject(tarWriter *tar.Writer, resource schema.GroupVersionResource, namespaced bool,
+	object *unstructured.Unstructured) error {
+
+	if len(object.GetOwnerReferences()) > 0 || !isBackupObject(resource, namespaced, object) {
+		return nil
+	}
+
+	sanitizeObject(object)
+
+	content, err := json.Marshal(object.Object)
+
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling %s %s", resource.Resource, object.GetName())
+	}
+
+	header := &tar.Header{
+		Name: objectPath(resource, namespaced, object),
+		Mode: 0600,
+		Size: int64(len(content)),
+	}
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return errors.Wrap(err, "error writing backup tarball")
+	}
+
+	if _, err := tarWriter.Write(content); err != nil {
+		return errors.Wrap(err, "error writing backup tarball")
+	}
+
+	return nil
+}
+
+func (r *resourcesImpl) Import(reader io.Reader) error {
+	objects, err := readBackupObjects(reader)
+
+	if err != nil {
+		return err
+	}
+
+	failures := []string{}
+
+	for _, backupObject := range objects {
+		err := r.applyObject(backupObject)
+
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d objects could not be restored: %s", len(failures), strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+func (r *resourcesImpl) applyObject(backupObject backupObject) error {
+	var client dynamic.ResourceInterface = r.dynamic.Resource(backupObject.resource)
+
+	if backupObject.namespaced {
+		client = r.dynamic.Resource(backupObject.resource).Namespace(backupObject.object.GetNamespace())
+	}
+
+	_, err := client.Create(backupObject.object, metav1.CreateOptions{})
+
+	if err == nil {
+		return nil
+	}
+
+	if !apierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "error creating %s %s", backupObject.resource.Resource, backupObject.object.GetName())
+	}
+
+	if !backupObject.namespaced && isManagedObject(backupObject.resource, backupObject.object) {
+		return nil
+	}
+
+	existing, err := client.Get(backupObject.object.GetName(), metav1.GetOptions{})
+
+	if err != nil {
+		return errors.Wrapf(err, "error getting %s %s", backupObject.resource.Resource, backupObject.object.GetName())
+	}
+
+	backupObject.object.SetResourceVersion(existing.GetResourceVersion())
+
+	_, err = client.Update(backupObject.object, metav1.UpdateOptions{})
+
+	if err != nil {
+		return errors.Wrapf(err, "error updating %s %s", backupObject.resource.Resource, backupObject.object.GetName())
+	}
+
+	return nil
+}
+
+func readBackupObjects(reader io.Reader) ([]backupObject, error) {
+	gzipReader, err := gzip.NewReader(reader)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading backup tarball")
+	}
+
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	objects := []backupObject{}
+
+	for {
+		header, err := tarReader.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading backup tarball")
+		}
+
+		resource, namespaced, err := parseObjectPath(header.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(tarReader)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", header.Name)
+		}
+
+		object := &unstructured.Unstructured{}
+
+		if err := object.UnmarshalJSON(content); err != nil {
+			return nil, errors.Wrapf(err, "error decoding %s", header.Name)
+		}
+
+		// Backups taken by older versions may hold objects the export now
+		// leaves out, so they are filtered and sanitized again.
+		if !isBackupObject(resource, namespaced, object) {
+			continue
+		}
+
+		sanitizeObject(object)
+
+		objects = append(objects, backupObject{resource: resource, namespaced: namespaced, object: object})
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return restorePriority(objects[i]) < restorePriority(objects[j])
+	})
+
+	return objects, nil
+}
+
+// restorePriority orders objects so that the resources listed in restoreFirst
+// come first, followed by the other cluster scoped and then namespaced objects.
+func restorePriority(object backupObject) int {
+	name := resourceName(object.resource)
+
+	for i, first := range restoreFirst {
+		if name == first {
+			return i
+		}
+	}
+
+	if !object.namespaced {
+		return len(restoreFirst)
+	}
+
+	return len(restoreFirst) + 1
+}
+
+func isBackupResource(groupVersion schema.GroupVersion, apiResource metav1.APIResource) bool {
+	if strings.Contains(apiResource.Name, "/") {
+		return false
+	}
+
+	if skippedResources[resourceName(groupVersion.WithResource(apiResource.Name))] {
+		return false
+	}
+
+	return hasVerbs(apiResource.Verbs, "list", "create")
+}
+
+// isBackupObject leaves out the system namespaces with their objects, the
+// service account token secrets and the kubernetes service, which the cluster
+// creates again on its own.
+func isBackupObject(resource schema.GroupVersionResource, namespaced bool, object *unstructured.Unstructured) bool {
+	if namespaced && systemNamespaces[object.GetNamespace()] {
+		return false
+	}
+
+	switch resourceName(resource) {
+	case "namespaces":
+		return !systemNamespaces[object.GetName()]
+	case "secrets":
+		secretType, _, _ := unstructured.NestedString(object.Object, "type")
+		return secretType != serviceAccountTokenType
+	case "services":
+		return object.GetNamespace() != "default" || object.GetName() != "kubernetes"
+	}
+
+	return true
+}
+
+// isManagedObject tells the cluster scoped objects the cluster owns, such as
+// the system: roles and bindings, the add-on manifests and the DigitalOcean
+// storage classes and webhooks, so a restore creates them when missing and
+// leaves them as they are otherwise.
+func isManagedObject(resource schema.GroupVersionResource, object *unstructured.Unstructured) bool {
+	if managedClusterResources[resourceName(resource)] || strings.HasPrefix(object.GetName(), "system:") {
+		return true
+	}
+
+	labels := object.GetLabels()
+
+	for _, label := range managedObjectLabels {
+		if _, ok := labels[label]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasVerbs(verbs metav1.Verbs, required ...string) bool {
+	for _, requiredVerb := range required {
+		found := false
+
+		for _, verb := range verbs {
+			if verb == requiredVerb {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func resourceName(resource schema.GroupVersionResource) string {
+	if resource.Group == "" {
+		return resource.Resource
+	}
+
+	return resource.Resource + "." + resource.Group
+}
+
+// sanitizeObject drops the fields the API server owns so the object can be
+// created again in any cluster. Service IPs are assigned by the cluster too,
+// only headless services keep theirs.
+func sanitizeObject(object *unstructured.Unstructured) {
+	for _, field := range []string{"uid", "resourceVersion", "selfLink", "creationTimestamp", "generation", "managedFields"} {
+		unstructured.RemoveNestedField(object.Object, "metadata", field)
+	}
+
+	unstructured.RemoveNestedField(object.Object, "status")
+
+	if object.GetKind() != "Service" {
+		return
+	}
+
+	if clusterIP, _, _ := unstructured.NestedString(object.Object, "spec", "clusterIP"); clusterIP != "None" {
+		unstructured.RemoveNestedField(object.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(object.Object, "spec", "clusterIPs")
+	}
+}
+
+func objectPath(resource schema.GroupVersionResource, namespaced bool, object *unstructured.Unstructured) string {
+	group := resource.Group
+
+	if group == "" {
+		group = coreGroupDir
+	}
+
+	namespace := clusterScopedDir
+
+	if namespaced {
+		namespace = object.GetNamespace()
+	}
+
+	return path.Join(resourcesDir, group, resource.Version, resource.Resource, namespace, object.GetName()+".json")
+}
+
+func parseObjectPath(objectPath string) (schema.GroupVersionResource, bool, error) {
+	parts := strings.Split(objectPath, "/")
+
+	if len(parts) != 6 || parts[0] != resourcesDir {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unexpected entry %s in backup tarball", objectPath)
+	}
+
+	group := parts[1]
+
+	if group == coreGroupDir {
+		group = ""
+	}
+
+	resource := schema.GroupVersionResource{Group: group, Version: parts[2], Resource: parts[3]}
+
+	return resource, parts[4] != clusterScopedDir, nil
+}
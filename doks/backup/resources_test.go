@@ -0,0 +1,345 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+var (
+	namespacesResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	configMapsResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	servicesResource   = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+)
+
+type discoveryStub struct {
+	fakediscovery.FakeDiscovery
+}
+
+func (d *discoveryStub) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return d.Resources, nil
+}
+
+func newDiscoveryStub() *discoveryStub {
+	verbs := metav1.Verbs{"create", "delete", "get", "list", "update"}
+
+	return &discoveryStub{
+		FakeDiscovery: fakediscovery.FakeDiscovery{
+			Fake: &kubetesting.Fake{
+				Resources: []*metav1.APIResourceList{
+					{
+						GroupVersion: "v1",
+						APIResources: []metav1.APIResource{
+							{Name: "namespaces", Namespaced: false, Kind: "Namespace", Verbs: verbs},
+							{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: verbs},
+							{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: verbs},
+							{Name: "services", Namespaced: true, Kind: "Service", Verbs: verbs},
+							{Name: "events", Namespaced: true, Kind: "Event", Verbs: verbs},
+							{Name: "pods/log", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newObject(kind, namespace, name string, data map[string]interface{}) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"uid":             "3b0a5e1c-" + name,
+			"resourceVersion": "42",
+		},
+	}}
+
+	if namespace != "" {
+		object.SetNamespace(namespace)
+	}
+
+	if data != nil {
+		object.Object["data"] = data
+	}
+
+	return object
+}
+
+func TestExportAndImportResources(t *testing.T) {
+	ownedConfigMap := newObject("ConfigMap", "team-a", "owned", nil)
+	ownedConfigMap.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "web", UID: "1"}})
+
+	sourceClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newObject("Namespace", "", "team-a", nil),
+		newObject("ConfigMap", "team-a", "settings", map[string]interface{}{"mode": "restored"}),
+		ownedConfigMap,
+		newObject("Event", "team-a", "web.1", nil),
+	)
+
+	buffer := &bytes.Buffer{}
+
+	err := newResources(newDiscoveryStub(), sourceClient).Export(buffer)
+
+	assert.NoError(t, err, "Not error in export resources")
+
+	objects, err := readBackupObjects(bytes.NewReader(buffer.Bytes()))
+
+	assert.NoError(t, err, "Not error in read backup objects")
+	assert.Len(t, objects, 2, "Owned objects and events skipped")
+	assert.Equal(t, "namespaces", objects[0].resource.Resource, "Namespaces restored first")
+	assert.Empty(t, objects[1].object.GetUID(), "Server fields removed")
+	assert.Empty(t, objects[1].object.GetResourceVersion(), "Server fields removed")
+
+	targetClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newObject("ConfigMap", "team-a", "settings", map[string]interface{}{"mode": "changed"}),
+	)
+
+	err = newResources(newDiscoveryStub(), targetClient).Import(bytes.NewReader(buffer.Bytes()))
+
+	assert.NoError(t, err, "Not error in import resources")
+
+	namespace, err := targetClient.Resource(namespacesResource).Get("team-a", metav1.GetOptions{})
+
+	assert.NoError(t, err, "Namespace restored")
+	assert.Equal(t, "team-a", namespace.GetName(), "Namespace name equals")
+
+	configMap, err := targetClient.Resource(configMapsResource).Namespace("team-a").Get("settings", metav1.GetOptions{})
+
+	assert.NoError(t, err, "ConfigMap restored")
+	assert.Equal(t, map[string]interface{}{"mode": "restored"}, configMap.Object["data"], "Existing object updated")
+}
+
+func TestExportSkipsClusterManagedObjects(t *testing.T) {
+	tokenSecret := newObject("Secret", "team-a", "default-token-abcd", nil)
+	tokenSecret.Object["type"] = "kubernetes.io/service-account-token"
+
+	webService := newObject("Service", "team-a", "web", nil)
+	webService.Object["spec"] = map[string]interface{}{"clusterIP": "10.245.0.10", "clusterIPs": []interface{}{"10.245.0.10"}}
+
+	headlessService := newObject("Service", "team-a", "db", nil)
+	headlessService.Object["spec"] = map[string]interface{}{"clusterIP": "None"}
+
+	sourceClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newObject("Namespace", "", "kube-system", nil),
+		newObject("ConfigMap", "kube-system", "coredns", nil),
+		newObject("Service", "default", "kubernetes", nil),
+		tokenSecret,
+		newObject("Secret", "team-a", "password", nil),
+		webService,
+		headlessService,
+	)
+
+	buffer := &bytes.Buffer{}
+
+	err := newResources(newDiscoveryStub(), sourceClient).Export(buffer)
+
+	assert.NoError(t, err, "Not error in export resources")
+
+	objects, err := readBackupObjects(bytes.NewReader(buffer.Bytes()))
+
+	assert.NoError(t, err, "Not error in read backup objects")
+
+	names := map[string]*unstructured.Unstructured{}
+
+	for _, object := range objects {
+		names[object.object.GetName()] = object.object
+	}
+
+	assert.Len(t, names, 3, "System namespaces, token secrets and the kubernetes service skipped")
+	assert.Contains(t, names, "password")
+
+	_, found, _ := unstructured.NestedFieldNoCopy(names["web"].Object, "spec", "clusterIP")
+	assert.False(t, found, "Cluster IP removed")
+
+	_, found, _ = unstructured.NestedFieldNoCopy(names["web"].Object, "spec", "clusterIPs")
+	assert.False(t, found, "Cluster IPs removed")
+
+	clusterIP, _, _ := unstructured.NestedString(names["db"].Object, "spec", "clusterIP")
+	assert.Equal(t, "None", clusterIP, "Headless service kept headless")
+}
+
+func TestApplyObjectKeepsManagedClusterObjects(t *testing.T) {
+	clusterRolesResource := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1",
+		Resource: "clusterroles"}
+
+	newClusterRole := func(name, rule string) *unstructured.Unstructured {
+		clusterRole := newObject("ClusterRole", "", name, nil)
+		clusterRole.SetAPIVersion("rbac.authorization.k8s.io/v1")
+		clusterRole.Object["rules"] = []interface{}{rule}
+		return clusterRole
+	}
+
+	targetClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		newClusterRole("system:node", "cluster"),
+		newClusterRole("team-a-reader", "cluster"),
+	)
+
+	r := newResources(newDiscoveryStub(), targetClient).(*resourcesImpl)
+
+	for _, name := range []string{"system:node", "team-a-reader"} {
+		err := r.applyObject(backupObject{resource: clusterRolesResource, object: newClusterRole(name, "backup")})
+
+		assert.NoError(t, err, "Not error in apply object")
+	}
+
+	systemRole, _ := targetClient.Resource(clusterRolesResource).Get("system:node", metav1.GetOptions{})
+
+	assert.Equal(t, []interface{}{"cluster"}, systemRole.Object["rules"], "Managed object not overwritten")
+
+	teamRole, _ := targetClient.Resource(clusterRolesResource).Get("team-a-reader", metav1.GetOptions{})
+
+	assert.Equal(t, []interface{}{"backup"}, teamRole.Object["rules"], "User object updated")
+}
+
+func TestIsManagedObject(t *testing.T) {
+	storageClasses := schema.GroupVersionResource{Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"}
+
+	addon := newObject("ConfigMap", "", "cilium", nil)
+	addon.SetLabels(map[string]string{"addonmanager.kubernetes.io/mode": "Reconcile"})
+
+	assert.True(t, isManagedObject(storageClasses, newObject("StorageClass", "", "do-block-storage", nil)),
+		"Storage classes managed")
+	assert.True(t, isManagedObject(namespacesResource, addon), "Add-on objects managed")
+	assert.False(t, isManagedObject(namespacesResource, newObject("Namespace", "", "team-a", nil)),
+		"User objects not managed")
+}
+
+func TestReadBackupObjectsFiltersOldBackups(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+
+	systemObject := newObject("ConfigMap", "kube-system", "coredns", nil)
+	service := newObject("Service", "team-a", "web", nil)
+	service.Object["spec"] = map[string]interface{}{"clusterIP": "10.245.0.10"}
+
+	for _, object := range []*unstructured.Unstructured{systemObject, service} {
+		resource := configMapsResource
+
+		if object.GetKind() == "Service" {
+			resource = servicesResource
+		}
+
+		assert.NoError(t, exportObjectUnfiltered(tarWriter, resource, object))
+	}
+
+	assert.NoError(t, tarWriter.Close())
+
+	objects, err := readBackupObjects(gzipped(t, buffer.Bytes()))
+
+	assert.NoError(t, err, "Not error in read backup objects")
+	assert.Len(t, objects, 1, "Objects of system namespaces not restored")
+	assert.Empty(t, objects[0].object.GetUID(), "Server fields removed")
+	assert.Empty(t, objects[0].object.GetResourceVersion(), "Server fields removed")
+
+	_, found, _ := unstructured.NestedFieldNoCopy(objects[0].object.Object, "spec", "clusterIP")
+	assert.False(t, found, "Cluster IP not restored")
+}
+
+type pagedResource struct {
+	dynamic.NamespaceableResourceInterface
+	pages     []*unstructured.UnstructuredList
+	continues []string
+}
+
+func (resource *pagedResource) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	resource.continues = append(resource.continues, opts.Continue)
+	page := resource.pages[0]
+	resource.pages = resource.pages[1:]
+
+	return page, nil
+}
+
+type pagedDynamic struct {
+	dynamic.Interface
+	resource *pagedResource
+}
+
+func (client pagedDynamic) Resource(_ schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return client.resource
+}
+
+func TestExportResourceReadsEveryPage(t *testing.T) {
+	firstPage := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*newObject("ConfigMap", "team-a", "first", nil)}}
+	firstPage.SetContinue("page-2")
+	secondPage := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*newObject("ConfigMap", "team-a", "second", nil)}}
+
+	resource := &pagedResource{pages: []*unstructured.UnstructuredList{firstPage, secondPage}}
+	resources := &resourcesImpl{dynamic: pagedDynamic{resource: resource}}
+
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+
+	err := resources.exportResource(tarWriter, configMapsResource, true)
+
+	assert.NoError(t, err, "Not error in export resource")
+	assert.NoError(t, tarWriter.Close())
+	assert.Equal(t, []string{"", "page-2"}, resource.continues, "Next page requested with the continue token")
+
+	objects, err := readBackupObjects(gzipped(t, buffer.Bytes()))
+
+	assert.NoError(t, err, "Not error in read backup objects")
+	assert.Len(t, objects, 2, "Objects of every page exported")
+}
+
+func exportObjectUnfiltered(tarWriter *tar.Writer, resource schema.GroupVersionResource,
+	object *unstructured.Unstructured) error {
+
+	content, err := json.Marshal(object.Object)
+
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{Name: objectPath(resource, true, object), Mode: 0600, Size: int64(len(content))}
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = tarWriter.Write(content)
+
+	return err
+}
+
+func gzipped(t *testing.T, content []byte) *bytes.Reader {
+	buffer := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buffer)
+
+	_, err := gzipWriter.Write(content)
+
+	assert.NoError(t, err)
+	assert.NoError(t, gzipWriter.Close())
+
+	return bytes.NewReader(buffer.Bytes())
+}
+
+func TestParseObjectPath(t *testing.T) {
+	resource, namespaced, err := parseObjectPath("resources/apps/v1/deployments/team-a/web.json")
+
+	assert.NoError(t, err, "Not error in parse object path")
+	assert.Equal(t, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, resource)
+	assert.True(t, namespaced, "Deployment is namespaced")
+
+	resource, namespaced, err = parseObjectPath("resources/core/v1/namespaces/_cluster/team-a.json")
+
+	assert.NoError(t, err, "Not error in parse object path")
+	assert.Equal(t, namespacesResource, resource, "Core group restored")
+	assert.False(t, namespaced, "Namespace is cluster scoped")
+
+	_, _, err = parseObjectPath("etc/passwd")
+
+	assert.Error(t, err, "Error with unexpected entry")
+}
@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// Store keeps backup tarballs in an object storage bucket.
+type Store interface {
+	Upload(ctx context.Context, key string, body io.Reader) error
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// spacesRegionPattern matches Spaces region slugs such as nyc3, which name the
+// host of the default endpoint.
+var spacesRegionPattern = regexp.MustCompile(`^[a-z]+[0-9]+$`)
+
+type StoreFactory func(backupState state.Backup) (Store, error)
+
+func NewStoreFactory() StoreFactory {
+	return newS3Store
+}
+
+type s3Store struct {
+	bucket   string
+	client   *s3.S3
+	uploader *s3manager.Uploader
+}
+
+// newS3Store builds a Store for DigitalOcean Spaces or any S3 compatible
+// endpoint. Without an endpoint the Spaces endpoint of the region is used.
+func newS3Store(backupState state.Backup) (Store, error) {
+	if backupState.Bucket == "" {
+		return nil, errors.New("backup bucket was not reported")
+	}
+
+	if backupState.AccessKey == "" || backupState.SecretKey == "" {
+		return nil, errors.New("backup access key and secret key were not reported")
+	}
+
+	endpoint := backupState.Endpoint
+
+	if endpoint == "" {
+		if !spacesRegionPattern.MatchString(backupState.Region) {
+			return nil, fmt.Errorf("backup region %q is not a Spaces region, set it or an endpoint", backupState.Region)
+		}
+
+		endpoint = fmt.Sprintf("https://%s.digitaloceanspaces.com", backupState.Region)
+	}
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String(backupState.Region),
+		Credentials:      credentials.NewStaticCredentials(backupState.AccessKey, backupState.SecretKey, ""),
+		S3ForcePathStyle: aws.Bool(backupState.PathStyle),
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating object storage session")
+	}
+
+	return &s3Store{
+		bucket:   backupState.Bucket,
+		client:   s3.New(awsSession),
+		uploader: s3manager.NewUploader(awsSession),
+	}, nil
+}
+
+func (store *s3Store) Upload(ctx context.Context, key string, body io.Reader) error {
+	_, err := store.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "error uploading backup %s", key)
+	}
+
+	return nil
+}
+
+func (store *s3Store) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	output, err := store.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error downloading backup %s", key)
+	}
+
+	return output.Body, nil
+}
+
+func (store *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := store.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "error deleting backup %s", key)
+	}
+
+	return nil
+}
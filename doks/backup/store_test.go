@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewS3StoreRequiresRegionWithoutEndpoint(t *testing.T) {
+	backupState := state.Backup{Bucket: "backups", AccessKey: "key", SecretKey: "secret"}
+
+	_, err := newS3Store(backupState)
+
+	assert.Error(t, err, "Error without region or endpoint")
+
+	backupState.Region = "evil.example.com/"
+
+	_, err = newS3Store(backupState)
+
+	assert.Error(t, err, "Error with a region that is not a Spaces region")
+
+	backupState.Region = "nyc3"
+
+	_, err = newS3Store(backupState)
+
+	assert.NoError(t, err, "Spaces region accepted")
+
+	backupState.Region = ""
+	backupState.Endpoint = "https://minio.example.com"
+
+	_, err = newS3Store(backupState)
+
+	assert.NoError(t, err, "Endpoint accepted without region")
+}
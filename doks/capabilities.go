@@ -0,0 +1,103 @@
+package doks
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/types"
+)
+
+const (
+	loadBalancerProvider = "DigitalOcean Load Balancer"
+	nodePortRange        = "30000-32767"
+)
+
+type kubernetesMinorVersion struct {
+	major int
+	minor int
+}
+
+func (version kubernetesMinorVersion) atLeast(other kubernetesMinorVersion) bool {
+	if version.major != other.major {
+		return version.major > other.major
+	}
+
+	return version.minor >= other.minor
+}
+
+// versionCapabilities lists the load balancer protocols the DigitalOcean cloud
+// controller manager shipped from a DOKS minor version on. HTTP, HTTPS and
+// HTTP2 are forwarding rule options of a TCP port, not L4 protocols, so they
+// are not reported.
+type versionCapabilities struct {
+	since     kubernetesMinorVersion
+	protocols []string
+}
+
+// capabilitiesByVersion is ordered from the newest version to the oldest.
+var capabilitiesByVersion = []versionCapabilities{
+	{since: kubernetesMinorVersion{major: 1, minor: 26}, protocols: []string{"TCP", "UDP"}},
+	{since: kubernetesMinorVersion{major: 1, minor: 0}, protocols: []string{"TCP"}},
+}
+
+// parseVersionSlug reads the Kubernetes minor version out of a DOKS version
+// slug such as 1.17.5-do.0.
+func parseVersionSlug(versionSlug string) (kubernetesMinorVersion, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(versionSlug, "v"), ".", 3)
+
+	if len(parts) < 2 {
+		return kubernetesMinorVersion{}, false
+	}
+
+	major, err := strconv.Atoi(parts[0])
+
+	if err != nil {
+		return kubernetesMinorVersion{}, false
+	}
+
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+
+	if err != nil {
+		return kubernetesMinorVersion{}, false
+	}
+
+	return kubernetesMinorVersion{major: major, minor: minor}, true
+}
+
+// capabilitiesForVersion finds the table entry for a version slug. An empty or
+// unknown slug stands for the latest version, which is what DigitalOcean uses
+// when no version is requested.
+func capabilitiesForVersion(versionSlug string) versionCapabilities {
+	version, ok := parseVersionSlug(versionSlug)
+
+	if !ok {
+		return capabilitiesByVersion[0]
+	}
+
+	for _, capabilities := range capabilitiesByVersion {
+		if version.atLeast(capabilities.since) {
+			return capabilities
+		}
+	}
+
+	return capabilitiesByVersion[len(capabilitiesByVersion)-1]
+}
+
+// buildK8SCapabilities describes what the DigitalOcean cloud controller manager
+// offers to services of type LoadBalancer and NodePort on the given version.
+// Health checks and the proxy protocol are configured through service
+// annotations on every version.
+func buildK8SCapabilities(versionSlug string) *types.K8SCapabilities {
+	capabilities := capabilitiesForVersion(versionSlug)
+
+	return &types.K8SCapabilities{
+		L4LoadBalancer: &types.LoadBalancerCapabilities{
+			Enabled:              true,
+			Provider:             loadBalancerProvider,
+			ProtocolsSupported:   append([]string(nil), capabilities.protocols...),
+			HealthCheckSupported: true,
+		},
+		NodePoolScalingSupported: true,
+		NodePortRange:            nodePortRange,
+	}
+}
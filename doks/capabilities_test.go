@@ -0,0 +1,76 @@
+package doks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersionSlug(t *testing.T) {
+	version, ok := parseVersionSlug("1.17.5-do.0")
+
+	assert.True(t, ok, "Version slug parsed")
+	assert.Equal(t, kubernetesMinorVersion{major: 1, minor: 17}, version, "Minor version equals")
+
+	_, ok = parseVersionSlug("latest")
+
+	assert.False(t, ok, "Unknown version slug not parsed")
+}
+
+func TestBuildK8SCapabilities(t *testing.T) {
+	capabilities := buildK8SCapabilities("1.17.5-do.0")
+
+	assert.True(t, capabilities.L4LoadBalancer.Enabled, "L4 load balancer enabled")
+	assert.True(t, capabilities.L4LoadBalancer.HealthCheckSupported, "Health check supported")
+	assert.Equal(t, []string{"TCP"}, capabilities.L4LoadBalancer.ProtocolsSupported,
+		"UDP not supported on older versions")
+	assert.Equal(t, "30000-32767", capabilities.NodePortRange, "NodePort range equals")
+	assert.True(t, capabilities.NodePoolScalingSupported, "Node pool scaling supported")
+}
+
+func TestBuildK8SCapabilitiesByVersion(t *testing.T) {
+	versions := map[string][]string{
+		"1.25.16-do.0": {"TCP"},
+		"1.26.3-do.0":  {"TCP", "UDP"},
+		"1.29.1-do.0":  {"TCP", "UDP"},
+		"":             {"TCP", "UDP"},
+		"latest":       {"TCP", "UDP"},
+	}
+
+	for versionSlug, protocols := range versions {
+		capabilities := buildK8SCapabilities(versionSlug)
+
+		assert.Equal(t, protocols, capabilities.L4LoadBalancer.ProtocolsSupported,
+			"Protocols equal for version %q", versionSlug)
+	}
+}
+
+func TestGetK8SCapabilities(t *testing.T) {
+	returnClusterState := state.Cluster{VersionSlug: "1.18.3-do.0"}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStatesFromOptsMock: func(_ *types.DriverOptions) (state.Cluster, state.NodePool, error) {
+			return returnClusterState, state.NodePool{}, nil
+		},
+	}
+
+	driver := Driver{
+		stateBuilder: stateBuilderMock,
+	}
+
+	options := &types.DriverOptions{}
+
+	stateBuilderMock.On("BuildStatesFromOpts", options).Return(returnClusterState, state.NodePool{}, nil)
+
+	capabilities, err := driver.GetK8SCapabilities(context.TODO(), options)
+
+	stateBuilderMock.AssertExpectations(t)
+
+	assert.NoError(t, err, "Not error in get k8s capabilities")
+	assert.Equal(t, "DigitalOcean Load Balancer", capabilities.L4LoadBalancer.Provider, "Provider equals")
+	assert.Equal(t, []string{"TCP"}, capabilities.L4LoadBalancer.ProtocolsSupported,
+		"Capabilities follow the cluster version")
+}
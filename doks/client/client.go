@@ -0,0 +1,54 @@
+// Package client calls a running driver server over gRPC, the way Rancher
+// does, for manual and end-to-end testing.
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/kontainer-engine/types"
+)
+
+// DriverName is the name the client reports for the driver.
+const DriverName = "doks"
+
+// readyPollInterval is how often Dial checks whether the server answers.
+const readyPollInterval = 100 * time.Millisecond
+
+// Dial connects to the driver server at address, e.g. 127.0.0.1:9000, and
+// waits until it answers or ctx is done. The driver must be closed after use.
+func Dial(ctx context.Context, address string) (types.CloseableDriver, error) {
+	driver, err := types.NewClient(DriverName, address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := WaitReady(ctx, driver, readyPollInterval); err != nil {
+		driver.Close()
+		return nil, err
+	}
+
+	return driver, nil
+}
+
+// WaitReady calls GetCapabilities every interval until it succeeds. The last
+// error is returned when ctx is done first.
+func WaitReady(ctx context.Context, driver types.Driver, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := driver.GetCapabilities(ctx)
+
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+		}
+	}
+}
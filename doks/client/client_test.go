@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks"
+	"github.com/stretchr/testify/assert"
+)
+
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func TestDialWaitsForServer(t *testing.T) {
+	address := freeAddress(t)
+	driver := doks.NewDriver()
+	server := types.NewServer(&driver, make(chan string, 1))
+
+	// the server is left running, Stop is not safe before Serve returns
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		server.Serve(address, make(chan error, 1))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := Dial(ctx, address)
+
+	assert.NoError(t, err, "Server answered once started")
+
+	if err != nil {
+		return
+	}
+
+	defer client.Close()
+
+	capabilities, err := client.GetCapabilities(ctx)
+
+	assert.NoError(t, err)
+	assert.True(t, capabilities.HasSetClusterSizeCapability(), "Capabilities read over gRPC")
+
+	flags, err := client.GetDriverCreateOptions(ctx)
+
+	assert.NoError(t, err)
+	assert.Contains(t, flags.Options, "node-pool-count", "Create options read over gRPC")
+}
+
+func TestDialTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	_, err := Dial(ctx, freeAddress(t))
+
+	assert.Error(t, err, "No server reported")
+}
@@ -0,0 +1,135 @@
+package doks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// cloneCluster copies the version, tags, auto upgrade, maintenance window and
+// node pools of the cluster to clone into the states built from the options.
+// The name, region and VPC of the options are kept. The VPC of the source is
+// reused when none is given and both clusters share a region. The first node
+// pool becomes the one Rancher manages, the others are returned to be created
+// once the cluster runs. Taints cannot be set with the DigitalOcean client in
+// use, so a cluster with tainted node pools is refused rather than cloned
+// without them.
+func cloneCluster(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster) (state.Cluster, state.NodePool, []state.NodePool, error) {
+
+	source, err := digitalOceanService.GetCluster(ctx, clusterState.CloneFromClusterID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error GetCluster in cloneCluster")
+		return clusterState, state.NodePool{}, nil, err
+	}
+
+	sourceNodePools, err := digitalOceanService.ListNodePools(ctx, clusterState.CloneFromClusterID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error ListNodePools in cloneCluster")
+		return clusterState, state.NodePool{}, nil, err
+	}
+
+	if len(sourceNodePools) == 0 {
+		return clusterState, state.NodePool{}, nil,
+			fmt.Errorf("cluster %s to clone has no node pool", clusterState.CloneFromClusterID)
+	}
+
+	if tainted := taintedNodePools(sourceNodePools); len(tainted) > 0 {
+		return clusterState, state.NodePool{}, nil, service.NewValidationError("clone cluster",
+			fmt.Sprintf("node pools of cluster %s have taints, which cannot be copied: %s",
+				clusterState.CloneFromClusterID, strings.Join(tainted, ", ")))
+	}
+
+	autoUpgrade := source.AutoUpgrade
+
+	clusterState.VersionSlug = source.VersionSlug
+	clusterState.Tags = state.UserTags(source.Tags)
+	clusterState.AutoUpgrade = &autoUpgrade
+	clusterState.MaintenancePolicy = source.MaintenancePolicy
+
+	if clusterState.RegionSlug == "" {
+		clusterState.RegionSlug = source.RegionSlug
+	}
+
+	if clusterState.VPCID == "" && clusterState.RegionSlug == source.RegionSlug {
+		clusterState.VPCID = source.VPCID
+	}
+
+	nodePools := make([]state.NodePool, 0, len(sourceNodePools))
+
+	for _, sourceNodePool := range sourceNodePools {
+		nodePools = append(nodePools, cloneNodePool(sourceNodePool))
+	}
+
+	return clusterState, nodePools[0], nodePools[1:], nil
+}
+
+// taintedNodePools describes the taints of each node pool that has any.
+func taintedNodePools(nodePools []state.NodePool) []string {
+	tainted := []string{}
+
+	for _, nodePool := range nodePools {
+		if len(nodePool.Taints) == 0 {
+			continue
+		}
+
+		taints := make([]string, 0, len(nodePool.Taints))
+
+		for _, taint := range nodePool.Taints {
+			taints = append(taints, taint.String())
+		}
+
+		tainted = append(tainted, fmt.Sprintf("%s (%s)", nodePool.Name, strings.Join(taints, " ")))
+	}
+
+	return tainted
+}
+
+// cloneNodePool keeps the settings of a node pool, without its ID and nodes.
+func cloneNodePool(nodePool state.NodePool) state.NodePool {
+	autoScale := nodePool.AutoScale != nil && *nodePool.AutoScale
+	labels := map[string]string{}
+
+	for key, value := range nodePool.Labels {
+		labels[key] = value
+	}
+
+	clone := state.NodePool{
+		Name:      nodePool.Name,
+		Size:      nodePool.Size,
+		Count:     nodePool.Count,
+		Tags:      state.UserTags(nodePool.Tags),
+		Labels:    labels,
+		AutoScale: &autoScale,
+	}
+
+	if autoScale {
+		clone.MinNodes = nodePool.MinNodes
+		clone.MaxNodes = nodePool.MaxNodes
+	}
+
+	return clone
+}
+
+// createClonedNodePools adds the node pools of the cloned cluster besides the
+// one created with it.
+func createClonedNodePools(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster, nodePools []state.NodePool) error {
+
+	for _, nodePool := range nodePools {
+		nodePool = state.TagNodePool(clusterState, nodePool)
+
+		if _, err := digitalOceanService.CreateNodePool(ctx, clusterState.ClusterID, nodePool); err != nil {
+			logging.FromContext(ctx).WithError(err).Debug("Error CreateNodePool in createClonedNodePools")
+			return err
+		}
+	}
+
+	return nil
+}
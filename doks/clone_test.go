@@ -0,0 +1,217 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func sourceClusterMock() *DigitalOceanMock {
+	autoScale, fixed := true, false
+
+	return &DigitalOceanMock{
+		getClusterMock: func(_ context.Context, _ string) (*state.ClusterStatus, error) {
+			return &state.ClusterStatus{
+				ID:                "source",
+				Name:              "production",
+				RegionSlug:        "nyc1",
+				VPCID:             "source-vpc",
+				VersionSlug:       "1.18.3-do.0",
+				Tags:              []string{"k8s", "k8s:source", "team-a"},
+				AutoUpgrade:       true,
+				MaintenancePolicy: &state.MaintenancePolicy{StartTime: "04:00", Day: "sunday"},
+			}, nil
+		},
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return []state.NodePool{
+				{ID: "pool-1", Name: "workers", Size: "s-2vcpu-4gb", Count: 3, AutoScale: &fixed,
+					Tags: []string{"k8s", "k8s:worker", "web"}, Labels: map[string]string{"role": "worker"},
+					Nodes: []state.Node{{ID: "node-1"}}},
+				{ID: "pool-2", Name: "batch", Size: "s-4vcpu-8gb", Count: 1, AutoScale: &autoScale,
+					MinNodes: 1, MaxNodes: 5},
+			}, nil
+		},
+	}
+}
+
+func TestCloneCluster(t *testing.T) {
+	digitalOceanMock := sourceClusterMock()
+
+	digitalOceanMock.On("GetCluster", mock.Anything, "source")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "source")
+
+	clusterState, managed, others, err := cloneCluster(context.TODO(), digitalOceanMock,
+		state.Cluster{Name: "copy", RegionSlug: "nyc1", CloneFromClusterID: "source"})
+
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "copy", clusterState.Name, "Name of the options kept")
+	assert.Equal(t, "1.18.3-do.0", clusterState.VersionSlug, "Version cloned")
+	assert.Equal(t, []string{"team-a"}, clusterState.Tags, "User tags cloned")
+	assert.True(t, *clusterState.AutoUpgrade, "Auto upgrade cloned")
+	assert.Equal(t, &state.MaintenancePolicy{StartTime: "04:00", Day: "sunday"}, clusterState.MaintenancePolicy)
+	assert.Equal(t, "source-vpc", clusterState.VPCID, "VPC of the same region reused")
+
+	assert.Equal(t, "workers", managed.Name)
+	assert.Empty(t, managed.ID, "ID left out")
+	assert.Empty(t, managed.Nodes, "Nodes left out")
+	assert.Equal(t, []string{"web"}, managed.Tags)
+	assert.Equal(t, map[string]string{"role": "worker"}, managed.Labels)
+
+	assert.Len(t, others, 1)
+	assert.Equal(t, "batch", others[0].Name)
+	assert.True(t, *others[0].AutoScale)
+	assert.Equal(t, 5, others[0].MaxNodes)
+}
+
+func TestCloneClusterInAnotherRegion(t *testing.T) {
+	digitalOceanMock := sourceClusterMock()
+
+	digitalOceanMock.On("GetCluster", mock.Anything, "source")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "source")
+
+	clusterState, _, _, err := cloneCluster(context.TODO(), digitalOceanMock,
+		state.Cluster{Name: "copy", RegionSlug: "ams3", CloneFromClusterID: "source"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ams3", clusterState.RegionSlug, "Region overridden")
+	assert.Empty(t, clusterState.VPCID, "VPC of another region not reused")
+}
+
+func TestCloneClusterWithoutNodePools(t *testing.T) {
+	digitalOceanMock := sourceClusterMock()
+	digitalOceanMock.listNodePoolsMock = func(_ context.Context, _ string) ([]state.NodePool, error) {
+		return nil, nil
+	}
+
+	digitalOceanMock.On("GetCluster", mock.Anything, "source")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "source")
+
+	_, _, _, err := cloneCluster(context.TODO(), digitalOceanMock,
+		state.Cluster{Name: "copy", CloneFromClusterID: "source"})
+
+	assert.Error(t, err)
+}
+
+func TestCloneClusterWithTaints(t *testing.T) {
+	digitalOceanMock := sourceClusterMock()
+	digitalOceanMock.listNodePoolsMock = func(_ context.Context, _ string) ([]state.NodePool, error) {
+		return []state.NodePool{
+			{ID: "pool-1", Name: "workers", Size: "s-2vcpu-4gb", Count: 3},
+			{ID: "pool-2", Name: "gpu", Size: "g-2vcpu-8gb", Count: 1,
+				Taints: []state.Taint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}}},
+		}, nil
+	}
+
+	digitalOceanMock.On("GetCluster", mock.Anything, "source")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "source")
+
+	_, _, _, err := cloneCluster(context.TODO(), digitalOceanMock,
+		state.Cluster{Name: "copy", CloneFromClusterID: "source"})
+
+	assert.True(t, service.IsValidation(err), "Tainted node pools not cloned")
+	assert.Contains(t, err.Error(), "gpu (gpu=true:NoSchedule)", "Taints reported")
+}
+
+func TestDriverCreateClone(t *testing.T) {
+	clusterState := state.Cluster{Token: "token", Name: "copy", RegionSlug: "nyc1", CloneFromClusterID: "source"}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStatesFromOptsMock: func(do *types.DriverOptions) (state.Cluster, state.NodePool, error) {
+			return clusterState, state.NodePool{Name: "ignored", Size: "s-1vcpu-2gb", Count: 1}, nil
+		},
+	}
+
+	var created []state.NodePool
+
+	digitalOceanMock := sourceClusterMock()
+	digitalOceanMock.getAccountMock = activeAccount
+	digitalOceanMock.createClusterMock = func(_ context.Context, _ state.Cluster, _ state.NodePool) (string, string, error) {
+		return "abcd", "pool", nil
+	}
+	digitalOceanMock.waitClusterCreated = func(_ context.Context, _ string) error {
+		return nil
+	}
+	digitalOceanMock.createNodePoolMock = func(_ context.Context, _ string, nodePool state.NodePool) (string, error) {
+		created = append(created, nodePool)
+		return "pool-" + nodePool.Name, nil
+	}
+
+	driver := Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean { return digitalOceanMock },
+	}
+
+	options := &types.DriverOptions{}
+
+	stateBuilderMock.On("BuildStatesFromOpts", options)
+	digitalOceanMock.On("GetCluster", mock.Anything, "source")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "source")
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("CreateCluster", mock.Anything,
+		mock.MatchedBy(func(cluster state.Cluster) bool { return cluster.VersionSlug == "1.18.3-do.0" }),
+		mock.MatchedBy(func(nodePool state.NodePool) bool { return nodePool.Name == "workers" }))
+	digitalOceanMock.On("WaitClusterCreated", mock.Anything, "abcd")
+	digitalOceanMock.On("CreateNodePool", mock.Anything, "abcd", mock.Anything)
+
+	info, err := driver.Create(context.TODO(), options, nil)
+
+	stateBuilderMock.AssertExpectations(t)
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, info)
+	assert.Len(t, created, 1, "Other node pools created")
+	assert.Equal(t, "batch", created[0].Name)
+}
+
+func TestDriverCreateCloneKeepsStateWhenNodePoolFails(t *testing.T) {
+	clusterState := state.Cluster{Token: "token", Name: "copy", RegionSlug: "nyc1", CloneFromClusterID: "source"}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStatesFromOptsMock: func(do *types.DriverOptions) (state.Cluster, state.NodePool, error) {
+			return clusterState, state.NodePool{}, nil
+		},
+	}
+
+	digitalOceanMock := sourceClusterMock()
+	digitalOceanMock.getAccountMock = activeAccount
+	digitalOceanMock.createClusterMock = func(_ context.Context, _ state.Cluster, _ state.NodePool) (string, string, error) {
+		return "abcd", "pool", nil
+	}
+	digitalOceanMock.waitClusterCreated = func(_ context.Context, _ string) error {
+		return nil
+	}
+	digitalOceanMock.createNodePoolMock = func(_ context.Context, _ string, _ state.NodePool) (string, error) {
+		return "", errors.New("node pool quota exceeded")
+	}
+
+	driver := Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean { return digitalOceanMock },
+	}
+
+	stateBuilderMock.On("BuildStatesFromOpts", mock.Anything)
+	digitalOceanMock.On("GetCluster", mock.Anything, "source")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "source")
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("CreateCluster", mock.Anything, mock.Anything, mock.Anything)
+	digitalOceanMock.On("WaitClusterCreated", mock.Anything, "abcd")
+	digitalOceanMock.On("CreateNodePool", mock.Anything, "abcd", mock.Anything)
+
+	info, err := driver.Create(context.TODO(), &types.DriverOptions{}, nil)
+
+	assert.Error(t, err)
+
+	savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(info)
+
+	assert.NoError(t, err, "State returned with the error")
+	assert.Equal(t, "abcd", savedState.ClusterID, "Created cluster kept for Remove")
+}
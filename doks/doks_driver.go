@@ -3,8 +3,9 @@ package doks
 import (
 	"context"
 	"errors"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/backup"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
 	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
-	"github.com/sirupsen/logrus"
 
 	"github.com/rancher/kontainer-engine/types"
 	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
@@ -15,6 +16,8 @@ type Driver struct {
 	stateBuilder       state.Builder
 	optionsBuilder     options.Builder
 	digitalOceanFactory service.DigitalOceanFactory
+	backupStoreFactory backup.StoreFactory
+	backupResourcesFactory backup.ResourcesFactory
 	driverCapabilities types.Capabilities
 }
 
@@ -23,6 +26,8 @@ func NewDriver() Driver {
 		stateBuilder:   state.NewBuilder(),
 		optionsBuilder: options.NewBuilder(),
 		digitalOceanFactory: service.NewDigitalOceanFactory(),
+		backupStoreFactory: backup.NewStoreFactory(),
+		backupResourcesFactory: backup.NewResourcesFactory(),
 		driverCapabilities: types.Capabilities{
 			Capabilities: make(map[int64]bool),
 		},
@@ -32,82 +37,144 @@ func NewDriver() Driver {
 	driver.driverCapabilities.AddCapability(types.SetVersionCapability)
 	driver.driverCapabilities.AddCapability(types.GetClusterSizeCapability)
 	driver.driverCapabilities.AddCapability(types.SetClusterSizeCapability)
+	driver.driverCapabilities.AddCapability(types.EtcdBackupCapability)
 
 	return driver
 }
 
-func (driver *Driver) GetDriverCreateOptions(_ context.Context) (*types.DriverFlags, error) {
-	logrus.Debug("DOKS.Driver.GetDriverCreateOptions(...) called")
+func (driver *Driver) GetDriverCreateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	operationContext(ctx, "GetDriverCreateOptions")
 	return driver.optionsBuilder.BuildCreateOptions(), nil
 }
 
-func (driver *Driver) GetDriverUpdateOptions(_ context.Context) (*types.DriverFlags, error) {
-	logrus.Debug("DOKS.Driver.GetDriverUpdateOptions(...) called")
+func (driver *Driver) GetDriverUpdateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	operationContext(ctx, "GetDriverUpdateOptions")
 	return driver.optionsBuilder.BuildUpdateOptions(), nil
 }
 
 func (driver *Driver) Create(ctx context.Context, opts *types.DriverOptions, _ *types.ClusterInfo) (*types.ClusterInfo, error) {
-	logrus.Debug("DOKS.Driver.Create(...) called")
+	ctx = operationContext(ctx, "Create")
 	clusterState, nodePoolState, err := driver.stateBuilder.BuildStatesFromOpts(opts)
 
 	if err != nil{
-		logrus.Debugf("Error building clusterState: %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error building clusterState")
 		return nil, err
 	}
 
-	if clusterState.Token == ""{
-		logrus.Debugf("Error token not found: %v",err)
-		err = errors.New("token was not reported")
+	err = clusterState.DigitalOceanCredential().Validate()
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error credential not found")
 		return nil, err
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	err = service.ValidateAPI(clusterState.API)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error invalid DigitalOcean API settings")
+		return nil, err
+	}
+
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
+
+	var clonedNodePools []state.NodePool
+
+	if clusterState.CloneFromClusterID != "" {
+		clusterState, nodePoolState, clonedNodePools, err = cloneCluster(ctx, digitalOceanService, clusterState)
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Debug("Error clone cluster")
+			return nil, err
+		}
+	}
+
+	clusterState, err = resolveProject(ctx, digitalOceanService, clusterState)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error resolve project")
+		return nil, err
+	}
+
+	droplets, autoscale := requiredDroplets(nodePoolState), autoscaleDroplets(nodePoolState)
+
+	for _, nodePool := range clonedNodePools {
+		droplets += requiredDroplets(nodePool)
+		autoscale += autoscaleDroplets(nodePool)
+	}
+
+	account, err := preflightCheck(ctx, digitalOceanService, droplets, autoscale)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error preflight check in create")
+		return nil, err
+	}
+
+	clusterState.TeamUUID = account.TeamUUID
 
 	clusterID, nodePoolID, err := digitalOceanService.CreateCluster(ctx, clusterState, nodePoolState)
 
 	if err != nil {
-		logrus.Debugf("Error crate cluster: %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error create cluster")
 		return nil, err
 	}
 
 	clusterState.ClusterID = clusterID
 	clusterState.NodePoolID = nodePoolID
+	ctx = clusterContext(ctx, clusterState)
 
 	info := &types.ClusterInfo{}
 
 	err = clusterState.Save(info)
 
 	if err != nil {
-		logrus.Debugf("Error save clusterState: %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error save clusterState")
 		return nil, err
 	}
 
+	if clusterState.ProjectID != "" {
+		err = digitalOceanService.AssignClusterToProject(ctx, clusterState.ProjectID, clusterID)
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Debug("Error assign cluster to project")
+			return info, err
+		}
+	}
+
 	err = digitalOceanService.WaitClusterCreated(ctx,clusterID)
 
 	if err != nil {
-		logrus.Debugf("Error wait cluster: %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error wait cluster")
 		return nil, err
 	}
 
+	err = createClonedNodePools(ctx, digitalOceanService, clusterState, clonedNodePools)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error create cloned node pools")
+		return info, err
+	}
+
 	return info, nil
 }
 
 func (driver *Driver) PostCheck(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
-	logrus.Debug("DOKS.Driver.PostCheck(...) called")
+	ctx = operationContext(ctx, "PostCheck")
 
 	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
 
 	if err != nil {
-		logrus.Debugf("Error build clusterState: %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error build clusterState")
 		return nil, err
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
 
 	kubeConfig, err := digitalOceanService.GetKubeConfig(clusterState.ClusterID)
 
 	if err != nil {
-		logrus.Debugf("Error get kubeConfig %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error get kubeConfig")
 		return nil, err
 	}
 
@@ -125,84 +192,150 @@ func (driver *Driver) PostCheck(ctx context.Context, clusterInfo *types.ClusterI
 		return nil, errors.New("the kubeconfig file is invalid. Token not found")
 	}
 
-	nodePool, err := digitalOceanService.GetNodePool(ctx, clusterState.ClusterID, clusterState.NodePoolID)
+	nodePools, err := digitalOceanService.ListNodePools(ctx, clusterState.ClusterID)
 
 	if err != nil {
-		logrus.Debugf("Error get node count %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error get node count")
 		return nil, err
 	}
 
+	clusterStatus, err := digitalOceanService.GetCluster(ctx, clusterState.ClusterID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error get cluster status")
+		return nil, err
+	}
+
+	clusterStatus.Save(clusterInfo)
+
 	clusterInfo.Version = clusterState.VersionSlug
-	clusterInfo.NodeCount = int64(nodePool.Count)
+
+	if clusterStatus.VersionSlug != "" {
+		clusterInfo.Version = clusterStatus.VersionSlug
+	}
+
+	clusterInfo.NodeCount = int64(totalNodeCount(nodePools))
+
+	driver.autoRepairNodes(ctx, digitalOceanService, clusterState, clusterInfo)
 
 	return clusterInfo, nil
 }
 
 func (driver *Driver) Update(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions) (*types.ClusterInfo, error) {
-	logrus.Debug("DOKS.Driver.Update(...) called")
+	ctx = operationContext(ctx, "Update")
+
+	clusterState, isUpdateCluster, err :=  driver.checkClusterStateUpdates(ctx, clusterInfo, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	clusterState, err = driver.rotateCredential(ctx, clusterInfo, clusterState, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodePoolState, err := driver.checkNodePoolStateUpdates(ctx, clusterState, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
+
+	newDroplets, autoscale, err := additionalDroplets(ctx, digitalOceanService, clusterState, nodePoolState)
+
+	if err != nil {
+		return nil, err
+	}
 
-	clusterState, isUpdateCluster, err :=  driver.checkClusterStateUpdates(clusterInfo, opts)
+	_, err = preflightCheck(ctx, digitalOceanService, newDroplets, autoscale)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error preflight check in update")
+		return nil, err
+	}
+
+	clusterState, err = driver.moveToProject(ctx, digitalOceanService, clusterInfo, clusterState, opts)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if isUpdateCluster {
-		digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
 		updateClusterErr := digitalOceanService.UpdateCluster(ctx, clusterState.ClusterID, clusterState)
 		if updateClusterErr != nil {
-			logrus.Debugf("Error update cluster %v",updateClusterErr)
+			logging.FromContext(ctx).WithError(updateClusterErr).Debug("Error update cluster")
 			return nil, updateClusterErr
 		}
 		saveClusterStateErr := clusterState.Save(clusterInfo)
 		if saveClusterStateErr != nil {
-			logrus.Debugf("Error save cluster state %v",saveClusterStateErr)
+			logging.FromContext(ctx).WithError(saveClusterStateErr).Debug("Error save cluster state")
 			return nil, saveClusterStateErr
 		}
 	}
 
-	nodePoolState, err := driver.checkNodePoolStateUpdates(clusterInfo, opts)
-
-	if err != nil {
-		return nil, err
-	}
-
 	if nodePoolState != nil {
-		digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
 		updateNodePoolErr := digitalOceanService.UpdateNodePool(
-			ctx, clusterState.ClusterID, clusterState.NodePoolID, *nodePoolState)
+			ctx, clusterState.ClusterID, clusterState.NodePoolID, state.TagNodePool(clusterState, *nodePoolState))
 		if updateNodePoolErr != nil {
-			logrus.Debugf("Error in update node pool %v",updateNodePoolErr)
+			logging.FromContext(ctx).WithError(updateNodePoolErr).Debug("Error in update node pool")
 			return nil, updateNodePoolErr
 		}
 	}
 
+	nodeRecycle := driver.stateBuilder.BuildNodeRecycleFromOpts(opts)
+
+	clusterState, err = driver.recycleRequestedNodes(ctx, digitalOceanService, clusterInfo, clusterState, nodeRecycle)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error in recycle nodes")
+		return clusterInfo, err
+	}
+
+	driver.autoRepairNodes(ctx, digitalOceanService, clusterState, clusterInfo)
+
 	return clusterInfo, nil
 }
 
 func (driver *Driver) Remove(ctx context.Context, clusterInfo *types.ClusterInfo) error {
-	logrus.Debug("DOKS.Driver.Remove(...) called")
+	ctx = operationContext(ctx, "Remove")
 
 	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
 
 	if err != nil {
-		logrus.Debugf("Error build state %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error build state")
 		return err
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
+
+	_, err = preflightCheck(ctx, digitalOceanService, 0, 0)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error preflight check in remove")
+		return err
+	}
 
 	err = digitalOceanService.DeleteCluster(ctx, clusterState.ClusterID)
 
+	if service.IsNotFound(err) {
+		logging.FromContext(ctx).Info("DOKS cluster was already deleted")
+		return nil
+	}
+
 	if err != nil {
-		logrus.Debugf("Error delete cluster %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error delete cluster")
 		return err
 	}
 
 	err = digitalOceanService.WaitClusterDeleted(ctx, clusterState.ClusterID)
 
 	if err != nil {
-		logrus.Debugf("Error wait delete cluster %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error wait delete cluster")
 		return err
 	}
 
@@ -210,21 +343,22 @@ func (driver *Driver) Remove(ctx context.Context, clusterInfo *types.ClusterInfo
 }
 
 func (driver *Driver) GetVersion(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.KubernetesVersion, error) {
-	logrus.Debug("DOKS.Driver.GetVersion(...) called")
+	ctx = operationContext(ctx, "GetVersion")
 
 	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
 
 	if err != nil {
-		logrus.Debugf("Error BuildClusterStateFromClusterInfo in get version %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildClusterStateFromClusterInfo in get version")
 		return nil, err
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
 
 	kubernetesVersion, err :=  digitalOceanService.GetKubernetesClusterVersion(ctx, clusterState.ClusterID)
 
 	if err != nil {
-		logrus.Debugf("Error digital ocean service get cluster version in get version %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error digital ocean service get cluster version in get version")
 		return nil, err
 	}
 
@@ -232,21 +366,22 @@ func (driver *Driver) GetVersion(ctx context.Context, clusterInfo *types.Cluster
 }
 
 func (driver *Driver) SetVersion(ctx context.Context, clusterInfo *types.ClusterInfo, version *types.KubernetesVersion) error {
-	logrus.Debug("DOKS.Driver.SetVersion(...) called")
+	ctx = operationContext(ctx, "SetVersion")
 
 	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
 
 	if err != nil {
-		logrus.Debugf("Error build state from cluster info in set version %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error build state from cluster info in set version")
 		return err
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
 
 	err = digitalOceanService.UpgradeKubernetesVersion(ctx, clusterState.ClusterID, version.Version)
 
 	if err != nil {
-		logrus.Debugf("Error upgrade kubernetes version %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error upgrade kubernetes version")
 		return err
 	}
 
@@ -254,98 +389,136 @@ func (driver *Driver) SetVersion(ctx context.Context, clusterInfo *types.Cluster
 }
 
 func (driver *Driver) GetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.NodeCount, error) {
-	logrus.Debug("DOKS.Driver.GetClusterSize(...) called")
+	ctx = operationContext(ctx, "GetClusterSize")
 
 	clusterState, err :=  driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
 
 	if err != nil {
-		logrus.Debugf("Error BuildClusterStateFromClusterInfo in GetClusterSize %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildClusterStateFromClusterInfo in GetClusterSize")
 		return nil, err
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
 
-	nodePool, err := digitalOceanService.GetNodePool(ctx, clusterState.ClusterID, clusterState.NodePoolID)
+	nodePools, err := digitalOceanService.ListNodePools(ctx, clusterState.ClusterID)
 
 	if err != nil {
-		logrus.Debugf("Error GetNodePool in GetClusterSize")
+		logging.FromContext(ctx).WithError(err).Debug("Error ListNodePools in GetClusterSize")
 		return nil, err
 	}
 
-	return &types.NodeCount{Count: int64(nodePool.Count)}, nil
+	driver.autoRepairNodesInBackground(ctx, digitalOceanService, clusterState, clusterInfo)
+
+	return &types.NodeCount{Count: int64(totalNodeCount(nodePools))}, nil
 }
 
 func (driver *Driver) SetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo, count *types.NodeCount) error {
-	logrus.Debug("DOKS.Driver.SetClusterSize(...) called")
+	ctx = operationContext(ctx, "SetClusterSize")
 
 	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
 
 	if err != nil {
-		logrus.Debugf("Error BuildClusterStateFromClusterInfo in SetClusterSize")
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildClusterStateFromClusterInfo in SetClusterSize")
 		return err
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
 
-	nodePool, err := digitalOceanService.GetNodePool(ctx,clusterState.ClusterID,clusterState.NodePoolID)
+	nodePools, err := digitalOceanService.ListNodePools(ctx, clusterState.ClusterID)
 
 	if err != nil {
-		logrus.Debugf("Error GetNodePool in SetClusterSize")
+		logging.FromContext(ctx).WithError(err).Debug("Error ListNodePools in SetClusterSize")
 		return err
 	}
 
-	if nodePool.AutoScale != nil && *nodePool.AutoScale {
-		if int64(nodePool.MinNodes) > count.Count || int64(nodePool.MaxNodes) < count.Count{
-			return errors.New("count must be greater than minNodes and less than maxNodes")
-		}
-	}
+	nodePools, err = orderNodePools(nodePools, clusterState.NodePoolID)
 
-	nodePool.Count = int(count.Count)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error orderNodePools in SetClusterSize")
+		return err
+	}
 
-	err = digitalOceanService.UpdateNodePool(ctx, clusterState.ClusterID,clusterState.NodePoolID, *nodePool)
+	counts, err := distributeNodeCount(nodePools, clusterState.ScalingPolicy, int(count.Count))
 
 	if err != nil {
-		logrus.Debugf("Error UpdateNodePool in SetClusterSize")
+		logging.FromContext(ctx).WithError(err).Debug("Error distributeNodeCount in SetClusterSize")
 		return err
 	}
 
+	for i, nodePool := range nodePools {
+		if nodePool.Count == counts[i] {
+			continue
+		}
+
+		nodePool.Count = counts[i]
+
+		err = digitalOceanService.UpdateNodePool(ctx, clusterState.ClusterID, nodePool.ID,
+			state.TagNodePool(clusterState, nodePool))
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Debug("Error UpdateNodePool in SetClusterSize")
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (driver *Driver) GetCapabilities(_ context.Context) (*types.Capabilities, error) {
-	logrus.Debug("DOKS.Driver.GetCapabilities(...) called")
+func (driver *Driver) GetCapabilities(ctx context.Context) (*types.Capabilities, error) {
+	operationContext(ctx, "GetCapabilities")
 	return &driver.driverCapabilities, nil
 }
 
 func (*Driver) RemoveLegacyServiceAccount(ctx context.Context, clusterInfo *types.ClusterInfo) error {
-	logrus.Debug("DOKS.Driver.RemoveLegacyServiceAccount(...) called")
+	operationContext(ctx, "RemoveLegacyServiceAccount")
 	return nil
 }
 
-func (*Driver) ETCDSave(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) error {
-	return errors.New("etcd backup operations are not implemented")
+func (driver *Driver) ETCDSave(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) error {
+	ctx = operationContext(ctx, "ETCDSave")
+	return driver.saveSnapshot(ctx, clusterInfo, opts, snapshotName)
 }
 
-func (*Driver) ETCDRestore(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) (*types.ClusterInfo, error) {
-	return nil, errors.New("etcd backup operations are not implemented")
+func (driver *Driver) ETCDRestore(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) (*types.ClusterInfo, error) {
+	ctx = operationContext(ctx, "ETCDRestore")
+
+	err := driver.restoreSnapshot(ctx, clusterInfo, opts, snapshotName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterInfo, nil
 }
 
-func (*Driver) GetK8SCapabilities(ctx context.Context, opts *types.DriverOptions) (*types.K8SCapabilities, error) {
-	return &types.K8SCapabilities{}, nil
+func (driver *Driver) GetK8SCapabilities(ctx context.Context, opts *types.DriverOptions) (*types.K8SCapabilities, error) {
+	ctx = operationContext(ctx, "GetK8SCapabilities")
+
+	clusterState, _, err := driver.stateBuilder.BuildStatesFromOpts(opts)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildStatesFromOpts in GetK8SCapabilities")
+		return nil, err
+	}
+
+	return buildK8SCapabilities(clusterState.VersionSlug), nil
 }
 
-func (*Driver) ETCDRemoveSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) error {
-	return errors.New("etcd backup operations are not implemented")
+func (driver *Driver) ETCDRemoveSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) error {
+	ctx = operationContext(ctx, "ETCDRemoveSnapshot")
+	return driver.removeSnapshot(ctx, clusterInfo, opts, snapshotName)
 }
 
-func (driver Driver) checkClusterStateUpdates(clusterInfo *types.ClusterInfo,
+func (driver Driver) checkClusterStateUpdates(ctx context.Context, clusterInfo *types.ClusterInfo,
 	options *types.DriverOptions) (state.Cluster,bool,error){
 
 	clusterState, errClusterState := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
 	newClusterState, _, _ := driver.stateBuilder.BuildStatesFromOpts(options)
 
 	if errClusterState != nil {
-		logrus.Debugf("Error in BuildClusterStateFromClusterInfo %v",errClusterState)
+		logging.FromContext(ctx).WithError(errClusterState).Debug("Error in BuildClusterStateFromClusterInfo")
 		return state.Cluster{}, false, errClusterState
 	}
 
@@ -361,33 +534,44 @@ func (driver Driver) checkClusterStateUpdates(clusterInfo *types.ClusterInfo,
 		clusterState.AutoUpgrade = newClusterState.AutoUpgrade
 	}
 
-	if newClusterState.Token != ""{
-		clusterState.Token = newClusterState.Token
+	if newClusterState.ScalingPolicy != "" {
+		updateClusterState = true
+		clusterState.ScalingPolicy = newClusterState.ScalingPolicy
 	}
 
-	if updateClusterState{
-		return clusterState, true, nil
-	}else{
-		return state.Cluster{},false,nil
+	if newClusterState.AutoRepair != nil {
+		updateClusterState = true
+		clusterState.AutoRepair = newClusterState.AutoRepair
 	}
-}
 
-func (driver Driver) checkNodePoolStateUpdates(clusterInfo *types.ClusterInfo,
-	options *types.DriverOptions)(*state.NodePool, error){
-	_, newNodePoolState, err :=  driver.stateBuilder.BuildStatesFromOpts(options)
-	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
+	if newClusterState.API != (state.API{}) {
+		api := clusterState.API.Merge(newClusterState.API)
 
-	if err != nil {
-		logrus.Debugf("Error in get state %v",err)
-		return nil, err
+		if err := service.ValidateAPI(api); err != nil {
+			logging.FromContext(ctx).WithError(err).Debug("Error invalid DigitalOcean API settings")
+			return state.Cluster{}, false, err
+		}
+
+		updateClusterState = true
+		clusterState.API = api
 	}
 
-	digitalOceanService := driver.digitalOceanFactory(clusterState.Token)
+	return clusterState, updateClusterState, nil
+}
+
+// checkNodePoolStateUpdates reads the node pool with the cluster state being
+// updated, so new credentials and API settings are already used.
+func (driver Driver) checkNodePoolStateUpdates(ctx context.Context, clusterState state.Cluster,
+	options *types.DriverOptions)(*state.NodePool, error){
+	_, newNodePoolState, _ :=  driver.stateBuilder.BuildStatesFromOpts(options)
+
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
 
-	nodePool, err := digitalOceanService.GetNodePool(context.TODO(), clusterState.ClusterID, clusterState.NodePoolID)
+	nodePool, err := digitalOceanService.GetNodePool(ctx, clusterState.ClusterID, clusterState.NodePoolID)
 
 	if err != nil {
-		logrus.Debugf("Error in get node pool %v",err)
+		logging.FromContext(ctx).WithError(err).Debug("Error in get node pool")
 		return nil, err
 	}
 
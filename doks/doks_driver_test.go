@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 /*************** Defining Mocks *************/
@@ -33,6 +34,8 @@ type StateBuilderMock struct {
 	mock.Mock
 	buildStatesFromOptsMock func (driverOptions *types.DriverOptions) (state.Cluster, state.NodePool ,error)
 	buildStateFromClusterInfo func (clusterInfo *types.ClusterInfo)(state.Cluster,error)
+	buildNodeRecycleFromOptsMock func(driverOptions *types.DriverOptions) state.NodeRecycle
+	buildBackupFromOptsMock func(driverOptions *types.DriverOptions) state.Backup
 }
 
 func (m *StateBuilderMock) BuildStatesFromOpts(driverOptions *types.DriverOptions) (state.Cluster, state.NodePool , error){
@@ -45,6 +48,16 @@ func (m *StateBuilderMock) BuildClusterStateFromClusterInfo(clusterInfo *types.C
 	return m.buildStateFromClusterInfo(clusterInfo)
 }
 
+func (m *StateBuilderMock) BuildNodeRecycleFromOpts(driverOptions *types.DriverOptions) state.NodeRecycle {
+	m.Called(driverOptions)
+	return m.buildNodeRecycleFromOptsMock(driverOptions)
+}
+
+func (m *StateBuilderMock) BuildBackupFromOpts(driverOptions *types.DriverOptions) state.Backup {
+	m.Called(driverOptions)
+	return m.buildBackupFromOptsMock(driverOptions)
+}
+
 type DigitalOceanMock struct {
 	mock.Mock
 	createClusterMock func(ctx context.Context, state state.Cluster, pool state.NodePool ) (string, string, error)
@@ -55,8 +68,19 @@ type DigitalOceanMock struct {
 	getKubernetesClusterVersionMock func(ctx context.Context, clusterID string)(string, error)
 	upgradeKubernetesVersionMock func(ctx context.Context, clusterID, version string)error
 	updateNodePoolMock func (ctx context.Context, clusterID, nodePoolID string, nodePool state.NodePool) error
+	createNodePoolMock func(ctx context.Context, clusterID string, nodePool state.NodePool) (string, error)
+	deleteNodePoolMock func(ctx context.Context, clusterID, nodePoolID string) error
 	updateClusterMock func(ctx context.Context, clusterID string, cluster state.Cluster)error
 	getNodePoolMock func(ctx context.Context, clusterID, nodePoolID string) (*state.NodePool,error)
+	listNodePoolsMock func(ctx context.Context, clusterID string) ([]state.NodePool, error)
+	listNodesMock func(ctx context.Context, clusterID, nodePoolID string) ([]state.Node, error)
+	deleteNodeMock func(ctx context.Context, clusterID, nodePoolID, nodeID string, replace, skipDrain bool) error
+	waitNodeReplacedMock func(ctx context.Context, clusterID, nodePoolID, nodeID string) error
+	getAccountMock func(ctx context.Context) (*state.Account, error)
+	getClusterMock func(ctx context.Context, clusterID string) (*state.ClusterStatus, error)
+	findProjectMock func(ctx context.Context, projectID, projectName string) (*state.Project, error)
+	assignClusterToProjectMock func(ctx context.Context, projectID, clusterID string) error
+	findOrphansMock func(ctx context.Context, ownerTag string, knownClusterIDs []string) ([]service.Orphan, error)
 }
 
 func (m *DigitalOceanMock) CreateCluster(ctx context.Context, clusterState state.Cluster,
@@ -99,6 +123,16 @@ func (m *DigitalOceanMock) UpdateNodePool(ctx context.Context, clusterID, nodePo
 	return m.updateNodePoolMock(ctx, clusterID, nodePoolID, nodePool)
 }
 
+func (m *DigitalOceanMock) CreateNodePool(ctx context.Context, clusterID string, nodePool state.NodePool) (string, error) {
+	m.Called(ctx, clusterID, nodePool)
+	return m.createNodePoolMock(ctx, clusterID, nodePool)
+}
+
+func (m *DigitalOceanMock) DeleteNodePool(ctx context.Context, clusterID, nodePoolID string) error {
+	m.Called(ctx, clusterID, nodePoolID)
+	return m.deleteNodePoolMock(ctx, clusterID, nodePoolID)
+}
+
 func (m *DigitalOceanMock) GetNodePool(ctx context.Context, clusterID, nodePoolID string) (*state.NodePool,error){
 	m.Called(ctx, clusterID, nodePoolID)
 	return m.getNodePoolMock(ctx,clusterID,nodePoolID)
@@ -109,6 +143,56 @@ func (m *DigitalOceanMock) UpdateCluster(ctx context.Context, clusterID string,
 	return m.updateClusterMock(ctx, clusterID, cluster)
 }
 
+func (m *DigitalOceanMock) ListNodePools(ctx context.Context, clusterID string) ([]state.NodePool, error) {
+	m.Called(ctx, clusterID)
+	return m.listNodePoolsMock(ctx, clusterID)
+}
+
+func (m *DigitalOceanMock) ListNodes(ctx context.Context, clusterID, nodePoolID string) ([]state.Node, error) {
+	m.Called(ctx, clusterID, nodePoolID)
+	return m.listNodesMock(ctx, clusterID, nodePoolID)
+}
+
+func (m *DigitalOceanMock) DeleteNode(ctx context.Context, clusterID, nodePoolID, nodeID string,
+	replace, skipDrain bool) error {
+	m.Called(ctx, clusterID, nodePoolID, nodeID, replace, skipDrain)
+	return m.deleteNodeMock(ctx, clusterID, nodePoolID, nodeID, replace, skipDrain)
+}
+
+func (m *DigitalOceanMock) WaitNodeReplaced(ctx context.Context, clusterID, nodePoolID, nodeID string) error {
+	m.Called(ctx, clusterID, nodePoolID, nodeID)
+	return m.waitNodeReplacedMock(ctx, clusterID, nodePoolID, nodeID)
+}
+
+func (m *DigitalOceanMock) GetAccount(ctx context.Context) (*state.Account, error) {
+	m.Called(ctx)
+	return m.getAccountMock(ctx)
+}
+
+func (m *DigitalOceanMock) GetCluster(ctx context.Context, clusterID string) (*state.ClusterStatus, error) {
+	m.Called(ctx, clusterID)
+	return m.getClusterMock(ctx, clusterID)
+}
+
+func (m *DigitalOceanMock) FindProject(ctx context.Context, projectID, projectName string) (*state.Project, error) {
+	m.Called(ctx, projectID, projectName)
+	return m.findProjectMock(ctx, projectID, projectName)
+}
+
+func (m *DigitalOceanMock) AssignClusterToProject(ctx context.Context, projectID, clusterID string) error {
+	m.Called(ctx, projectID, clusterID)
+	return m.assignClusterToProjectMock(ctx, projectID, clusterID)
+}
+
+func (m *DigitalOceanMock) FindOrphans(ctx context.Context, ownerTag string, knownClusterIDs []string) ([]service.Orphan, error) {
+	m.Called(ctx, ownerTag, knownClusterIDs)
+	return m.findOrphansMock(ctx, ownerTag, knownClusterIDs)
+}
+
+func activeAccount(_ context.Context) (*state.Account, error) {
+	return &state.Account{Status: "active", DropletLimit: 25, DropletCount: 3, WriteScope: true}, nil
+}
+
 /*************** Defining Tests *************/
 
 func TestGetDriverCreateOptions(t *testing.T) {
@@ -159,6 +243,7 @@ func TestDriverCreate(t *testing.T) {
 	returnNodePoolID := "zzz"
 
 	digitalOceanMock := &DigitalOceanMock{
+		getAccountMock: activeAccount,
 		createClusterMock: func(_ context.Context, _ state.Cluster, _ state.NodePool) (string, string ,error) {
 			return returnClusterID, returnNodePoolID, nil
 		},
@@ -169,19 +254,21 @@ func TestDriverCreate(t *testing.T) {
 
 	driver := Driver{
 		stateBuilder: stateBuilderMock,
-		digitalOceanFactory: func(token string) service.DigitalOcean {return digitalOceanMock},
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return digitalOceanMock},
 	}
 
 	options := &types.DriverOptions{}
 	ctx := context.TODO()
 
+	digitalOceanMock.On("GetAccount", mock.Anything)
+
 	stateBuilderMock.On("BuildStatesFromOpts",options).Return(returnClusterState,
 		returnNodePoolState)
 
-	digitalOceanMock.On("CreateCluster", ctx, returnClusterState,
+	digitalOceanMock.On("CreateCluster", mock.Anything, returnClusterState,
 		returnNodePoolState).Return(returnClusterID,returnNodePoolID,nil)
 
-	digitalOceanMock.On("WaitClusterCreated",ctx,returnClusterID).Return(nil)
+	digitalOceanMock.On("WaitClusterCreated",mock.Anything,returnClusterID).Return(nil)
 
 	info, err := driver.Create(ctx, options , nil)
 
@@ -258,6 +345,35 @@ func TestDriverCreateWithoutToken(t *testing.T){
 
 }
 
+func TestDriverCreateWithInvalidAPISettings(t *testing.T) {
+	returnClusterState := state.Cluster{
+		Token: "token",
+		API:   state.API{Timeout: "-1s"},
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStatesFromOptsMock: func(_ *types.DriverOptions) (state.Cluster, state.NodePool, error) {
+			return returnClusterState, state.NodePool{Count: 1}, nil
+		},
+	}
+
+	driver := Driver{
+		stateBuilder: stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {
+			t.Fatal("no client is built with invalid API settings")
+			return nil
+		},
+	}
+
+	options := &types.DriverOptions{}
+
+	stateBuilderMock.On("BuildStatesFromOpts", options)
+
+	_, err := driver.Create(context.TODO(), options, nil)
+
+	assert.Error(t, err, "Invalid timeout refused")
+}
+
 func TestDriverCreateErrorInDigitalOceanServiceCreate(t *testing.T){
 
 	returnNodePoolState := state.NodePool{
@@ -280,6 +396,7 @@ func TestDriverCreateErrorInDigitalOceanServiceCreate(t *testing.T){
 	}
 
 	digitalOceanMock := &DigitalOceanMock{
+		getAccountMock: activeAccount,
 		createClusterMock: func(_ context.Context, _ state.Cluster, _ state.NodePool) (string, string, error) {
 			return "", "", errors.New("error in create cluster")
 		},
@@ -287,16 +404,18 @@ func TestDriverCreateErrorInDigitalOceanServiceCreate(t *testing.T){
 
 	driver := Driver{
 		stateBuilder: stateBuilderMock,
-		digitalOceanFactory: func(token string) service.DigitalOcean {return digitalOceanMock},
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return digitalOceanMock},
 	}
 
 	options := &types.DriverOptions{}
 	ctx := context.TODO()
 
+	digitalOceanMock.On("GetAccount", mock.Anything)
+
 	stateBuilderMock.On("BuildStatesFromOpts",
 		options).Return(returnClusterState, returnNodePoolState)
 
-	digitalOceanMock.On("CreateCluster", ctx, returnClusterState,
+	digitalOceanMock.On("CreateCluster", mock.Anything, returnClusterState,
 		returnNodePoolState).Return("",nil)
 
 	_, err := driver.Create(ctx, options , nil)
@@ -333,6 +452,7 @@ func TestDriverCreateErrorInWaitClusterCreated(t *testing.T){
 	returnNodePoolID := "zzz"
 
 	digitalOceanMock := &DigitalOceanMock{
+		getAccountMock: activeAccount,
 		createClusterMock: func(_ context.Context, _ state.Cluster, _ state.NodePool) (string, string, error) {
 			return returnClusterID, returnNodePoolID, nil
 		},
@@ -343,19 +463,21 @@ func TestDriverCreateErrorInWaitClusterCreated(t *testing.T){
 
 	driver := Driver{
 		stateBuilder: stateBuilderMock,
-		digitalOceanFactory: func(token string) service.DigitalOcean {return digitalOceanMock},
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return digitalOceanMock},
 	}
 
 	options := &types.DriverOptions{}
 	ctx := context.TODO()
 
+	digitalOceanMock.On("GetAccount", mock.Anything)
+
 	stateBuilderMock.On("BuildStatesFromOpts",
 		options).Return(returnClusterState, returnNodePoolState)
 
-	digitalOceanMock.On("CreateCluster", ctx,
+	digitalOceanMock.On("CreateCluster", mock.Anything,
 		returnClusterState, returnNodePoolState).Return(returnClusterID,returnNodePoolID,nil)
 
-	digitalOceanMock.On("WaitClusterCreated",ctx,returnClusterID).Return(nil)
+	digitalOceanMock.On("WaitClusterCreated",mock.Anything,returnClusterID).Return(nil)
 
 	_, err := driver.Create(ctx, options , nil)
 
@@ -382,6 +504,7 @@ func TestRemoveCluster(t *testing.T){
 	}
 
 	digitalOceanMock := DigitalOceanMock{
+		getAccountMock: activeAccount,
 		deleteClusterMock: func(_ context.Context, _ string) error {
 			return nil
 		},
@@ -390,7 +513,7 @@ func TestRemoveCluster(t *testing.T){
 		},
 	}
 
-	digitalOceanFactory := func(token string)service.DigitalOcean{
+	digitalOceanFactory := func(credential state.Credential, api state.API) service.DigitalOcean{
 		return &digitalOceanMock
 	}
 
@@ -403,8 +526,9 @@ func TestRemoveCluster(t *testing.T){
 	ctx := context.TODO()
 
 	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo).Return(returnState)
-	digitalOceanMock.On("DeleteCluster",ctx, returnState.ClusterID).Return(nil)
-	digitalOceanMock.On("WaitClusterDeleted",ctx, returnState.ClusterID).Return(nil)
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("DeleteCluster",mock.Anything, returnState.ClusterID).Return(nil)
+	digitalOceanMock.On("WaitClusterDeleted",mock.Anything, returnState.ClusterID).Return(nil)
 
 	err := driver.Remove(ctx, clusterInfo)
 
@@ -459,12 +583,13 @@ func TestRemoveClusterErrorInDigitalOceanDelete(t *testing.T){
 	}
 
 	digitalOceanMock := DigitalOceanMock{
+		getAccountMock: activeAccount,
 		deleteClusterMock: func(_ context.Context, _ string) error {
 			return returnError
 		},
 	}
 
-	digitalOceanFactory := func(token string)service.DigitalOcean{
+	digitalOceanFactory := func(credential state.Credential, api state.API) service.DigitalOcean{
 		return &digitalOceanMock
 	}
 
@@ -477,7 +602,8 @@ func TestRemoveClusterErrorInDigitalOceanDelete(t *testing.T){
 	ctx := context.TODO()
 
 	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo).Return(returnState)
-	digitalOceanMock.On("DeleteCluster",ctx, returnState.ClusterID).Return(returnError)
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("DeleteCluster",mock.Anything, returnState.ClusterID).Return(returnError)
 
 	err := driver.Remove(ctx, clusterInfo)
 
@@ -487,6 +613,82 @@ func TestRemoveClusterErrorInDigitalOceanDelete(t *testing.T){
 	assert.Error(t, err, "Error in remove cluster")
 }
 
+func TestRemoveClusterReadOnlyToken(t *testing.T){
+
+	returnState := state.Cluster{
+		ClusterID: "abcd",
+		Token:     "a405b7bd3e0d6193f605368102a2deafe4067ed542c92166c6d772fe7e2df019",
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return returnState, nil
+		},
+	}
+
+	digitalOceanMock := DigitalOceanMock{
+		getAccountMock: func(_ context.Context) (*state.Account, error) {
+			return &state.Account{Status: "active"}, nil
+		},
+	}
+
+	driver := Driver{
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return &digitalOceanMock},
+		stateBuilder: stateBuilderMock,
+	}
+
+	clusterInfo := &types.ClusterInfo{}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo).Return(returnState)
+	digitalOceanMock.On("GetAccount", mock.Anything)
+
+	err := driver.Remove(context.TODO(), clusterInfo)
+
+	digitalOceanMock.AssertNotCalled(t, "DeleteCluster", mock.Anything, returnState.ClusterID)
+
+	assert.True(t, errors.Is(err, ErrTokenReadOnly), "Read only token stops the removal")
+}
+
+func TestRemoveClusterAlreadyDeleted(t *testing.T){
+
+	returnState := state.Cluster{
+		ClusterID: "abcd",
+		Token:     "a405b7bd3e0d6193f605368102a2deafe4067ed542c92166c6d772fe7e2df019",
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return returnState, nil
+		},
+	}
+
+	digitalOceanMock := DigitalOceanMock{
+		getAccountMock: activeAccount,
+		deleteClusterMock: func(_ context.Context, _ string) error {
+			return &service.Error{Kind: service.ErrorKindNotFound, Operation: "delete cluster"}
+		},
+	}
+
+	driver := Driver{
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return &digitalOceanMock},
+		stateBuilder: stateBuilderMock,
+	}
+
+	clusterInfo := &types.ClusterInfo{}
+	ctx := context.TODO()
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo).Return(returnState)
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("DeleteCluster", mock.Anything, returnState.ClusterID)
+
+	err := driver.Remove(ctx, clusterInfo)
+
+	digitalOceanMock.AssertExpectations(t)
+	digitalOceanMock.AssertNotCalled(t, "WaitClusterDeleted", ctx, returnState.ClusterID)
+
+	assert.NoError(t, err, "Cluster not found is removed")
+}
+
 func TestRemoveClusterErrorInWaitDeleted(t *testing.T){
 
 	returnState := state.Cluster{
@@ -505,6 +707,7 @@ func TestRemoveClusterErrorInWaitDeleted(t *testing.T){
 	returnError := errors.New("error in waht cluster deleted")
 
 	digitalOceanMock := DigitalOceanMock{
+		getAccountMock: activeAccount,
 		deleteClusterMock: func(_ context.Context, _ string) error {
 			return nil
 		},
@@ -513,7 +716,7 @@ func TestRemoveClusterErrorInWaitDeleted(t *testing.T){
 		},
 	}
 
-	digitalOceanFactory := func(token string)service.DigitalOcean{
+	digitalOceanFactory := func(credential state.Credential, api state.API) service.DigitalOcean{
 		return &digitalOceanMock
 	}
 
@@ -526,8 +729,9 @@ func TestRemoveClusterErrorInWaitDeleted(t *testing.T){
 	ctx := context.TODO()
 
 	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo).Return(returnState)
-	digitalOceanMock.On("DeleteCluster",ctx, returnState.ClusterID).Return(nil)
-	digitalOceanMock.On("WaitClusterDeleted",ctx, returnState.ClusterID).Return(returnError)
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("DeleteCluster",mock.Anything, returnState.ClusterID).Return(nil)
+	digitalOceanMock.On("WaitClusterDeleted",mock.Anything, returnState.ClusterID).Return(returnError)
 
 	err := driver.Remove(ctx, clusterInfo)
 
@@ -543,10 +747,19 @@ func TestGetClusterSize(t *testing.T){
 	returnClusterID := "abcd"
 	returnNodePoolID := "aaas"
 
-	returnNodePool := &state.NodePool{
-		Name:  "node-pool-1",
-		Size:  "s-2vcpu-2gb",
-		Count: nodeCount,
+	returnNodePools := []state.NodePool{
+		{
+			ID:    returnNodePoolID,
+			Name:  "node-pool-1",
+			Size:  "s-2vcpu-2gb",
+			Count: nodeCount,
+		},
+		{
+			ID:    "bbbs",
+			Name:  "node-pool-2",
+			Size:  "s-2vcpu-2gb",
+			Count: 2,
+		},
 	}
 
 	returnState := state.Cluster{
@@ -565,14 +778,14 @@ func TestGetClusterSize(t *testing.T){
 	}
 
 	digitalOceanMock := &DigitalOceanMock{
-		getNodePoolMock: func(_ context.Context, _, _ string) (*state.NodePool, error) {
-			return returnNodePool, nil
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return returnNodePools, nil
 		},
 	}
 
 	driver := Driver{
 		stateBuilder: stateBuilderMock,
-		digitalOceanFactory: func(token string) service.DigitalOcean {return digitalOceanMock},
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return digitalOceanMock},
 	}
 
 	ctx := context.TODO()
@@ -580,8 +793,7 @@ func TestGetClusterSize(t *testing.T){
 
 	stateBuilderMock.On("BuildClusterStateFromClusterInfo",clusterInfo).Return(returnState)
 
-	digitalOceanMock.On("GetNodePool", ctx, returnClusterID,
-		returnNodePoolID).Return(returnNodePool,nil)
+	digitalOceanMock.On("ListNodePools", mock.Anything, returnClusterID).Return(returnNodePools,nil)
 
 	clusterSize, err := driver.GetClusterSize(ctx, clusterInfo)
 
@@ -589,7 +801,7 @@ func TestGetClusterSize(t *testing.T){
 	digitalOceanMock.AssertExpectations(t)
 
 	assert.NoError(t, err, "Not error in get cluster size")
-	assert.Equal(t, int64(nodeCount), clusterSize.Count, "NodeCount equals")
+	assert.Equal(t, int64(nodeCount+2), clusterSize.Count, "NodeCount sums all node pools")
 
 }
 
@@ -603,4 +815,172 @@ func TestSetVersion(t *testing.T){
 
 func TestSetClusterSize(t *testing.T){
 
+	returnClusterID := "abcd"
+	autoScale := true
+
+	returnNodePools := []state.NodePool{
+		{ID: "bbbs", Name: "node-pool-2", Count: 2},
+		{ID: "aaas", Name: "node-pool-1", Count: 3, AutoScale: &autoScale, MinNodes: 1, MaxNodes: 4},
+	}
+
+	returnState := state.Cluster{
+		Token:      "a405b7bd3e0d6193f605368102a2deafe4067ed542c92166c6d772fe7e2df019",
+		ClusterID:  returnClusterID,
+		NodePoolID: "aaas",
+		ScalingPolicy: ScalingPolicyFill,
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return returnState, nil
+		},
+	}
+
+	updatedCounts := map[string]int{}
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return returnNodePools, nil
+		},
+		updateNodePoolMock: func(_ context.Context, _, nodePoolID string, nodePool state.NodePool) error {
+			updatedCounts[nodePoolID] = nodePool.Count
+			return nil
+		},
+	}
+
+	driver := Driver{
+		stateBuilder: stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return digitalOceanMock},
+	}
+
+	ctx := context.TODO()
+	clusterInfo := &types.ClusterInfo{}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo",clusterInfo).Return(returnState)
+	digitalOceanMock.On("ListNodePools", mock.Anything, returnClusterID).Return(returnNodePools,nil)
+	digitalOceanMock.On("UpdateNodePool", mock.Anything, returnClusterID).Return(nil)
+
+	err := driver.SetClusterSize(ctx, clusterInfo, &types.NodeCount{Count: 8})
+
+	stateBuilderMock.AssertExpectations(t)
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err, "Not error in set cluster size")
+	assert.Equal(t, map[string]int{"aaas": 4, "bbbs": 4}, updatedCounts,
+		"Primary node pool capped at max nodes and the rest spilled over")
+}
+
+func TestSetClusterSizeOutOfBounds(t *testing.T){
+
+	returnClusterID := "abcd"
+	autoScale := true
+
+	returnNodePools := []state.NodePool{
+		{ID: "aaas", Name: "node-pool-1", Count: 3, AutoScale: &autoScale, MinNodes: 1, MaxNodes: 4},
+	}
+
+	returnState := state.Cluster{
+		ClusterID:  returnClusterID,
+		NodePoolID: "aaas",
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return returnState, nil
+		},
+	}
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return returnNodePools, nil
+		},
+	}
+
+	driver := Driver{
+		stateBuilder: stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return digitalOceanMock},
+	}
+
+	ctx := context.TODO()
+	clusterInfo := &types.ClusterInfo{}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo",clusterInfo).Return(returnState)
+	digitalOceanMock.On("ListNodePools", mock.Anything, returnClusterID).Return(returnNodePools,nil)
+
+	err := driver.SetClusterSize(ctx, clusterInfo, &types.NodeCount{Count: 8})
+
+	digitalOceanMock.AssertNotCalled(t, "UpdateNodePool", ctx, returnClusterID)
+
+	assert.Error(t, err, "Error in set cluster size beyond max nodes")
+}
+
+func TestPostCheck(t *testing.T){
+
+	returnState := state.Cluster{
+		ClusterID:   "abcd",
+		Token:       "a405b7bd3e0d6193f605368102a2deafe4067ed542c92166c6d772fe7e2df019",
+		VersionSlug: "",
+	}
+
+	createdAt := time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	returnStatus := &state.ClusterStatus{
+		ID:          "abcd",
+		State:       "running",
+		Message:     "Cluster is ready",
+		VersionSlug: "1.17.5-do.0",
+		Endpoint:    "https://abcd.k8s.ondigitalocean.com",
+		IPv4:        "10.0.0.1",
+		URN:         "do:kubernetes:abcd",
+		CreatedAt:   createdAt,
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return returnState, nil
+		},
+	}
+
+	kubeConfig := &store.KubeConfig{
+		Clusters: []store.ConfigCluster{{Cluster: store.DataCluster{Server: "https://abcd.k8s.ondigitalocean.com"}}},
+		Users:    []store.ConfigUser{{User: store.UserData{Token: "kube-token"}}},
+	}
+
+	digitalOceanMock := DigitalOceanMock{
+		getKubeConfigMock: func(_ string) (*store.KubeConfig, error) {
+			return kubeConfig, nil
+		},
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return []state.NodePool{{ID: "pool-1", Count: 3}, {ID: "pool-2", Count: 2}}, nil
+		},
+		getClusterMock: func(_ context.Context, _ string) (*state.ClusterStatus, error) {
+			return returnStatus, nil
+		},
+	}
+
+	driver := Driver{
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {return &digitalOceanMock},
+		stateBuilder: stateBuilderMock,
+	}
+
+	clusterInfo := &types.ClusterInfo{}
+	ctx := context.TODO()
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo)
+	digitalOceanMock.On("GetKubeConfig", returnState.ClusterID)
+	digitalOceanMock.On("ListNodePools", mock.Anything, returnState.ClusterID)
+	digitalOceanMock.On("GetCluster", mock.Anything, returnState.ClusterID)
+
+	info, err := driver.PostCheck(ctx, clusterInfo)
+
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err, "Not error in post check")
+	assert.Equal(t, "running", info.Status, "Status equals")
+	assert.Equal(t, "1.17.5-do.0", info.Version, "Version reported by DigitalOcean")
+	assert.Equal(t, int64(5), info.NodeCount, "Node count equals")
+	assert.Equal(t, "Cluster is ready", info.Metadata["status-message"], "Status message equals")
+	assert.Equal(t, "do:kubernetes:abcd", info.Metadata["urn"], "URN equals")
+	assert.Equal(t, "10.0.0.1", info.Metadata["ipv4"], "IPv4 equals")
+	assert.Equal(t, "2020-06-01T10:00:00Z", info.Metadata["created-at"], "Creation time equals")
 }
@@ -0,0 +1,296 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/rancher/kontainer-engine/types"
+)
+
+var ErrShuttingDown = errors.New("the DOKS driver is shutting down, try again later")
+
+// GracefulDriver rejects new calls once Shutdown starts and cancels the
+// context of the calls still running when the drain period ends.
+type GracefulDriver struct {
+	next types.Driver
+
+	mutex    sync.Mutex
+	stopping bool
+	inFlight sync.WaitGroup
+	cancel   chan struct{}
+}
+
+func NewGracefulDriver(next types.Driver) *GracefulDriver {
+	return &GracefulDriver{next: next, cancel: make(chan struct{})}
+}
+
+// begin registers a call. The returned context is cancelled when the drain
+// period ends, and done must be called when the call returns.
+func (driver *GracefulDriver) begin(ctx context.Context) (context.Context, func(), error) {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	if driver.stopping {
+		return nil, nil, ErrShuttingDown
+	}
+
+	driver.inFlight.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-driver.cancel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		cancel()
+		driver.inFlight.Done()
+	}, nil
+}
+
+// Shutdown stops accepting calls and waits for the running ones. When ctx is
+// done first, their contexts are cancelled and Shutdown waits for them to
+// return until abort is done.
+func (driver *GracefulDriver) Shutdown(ctx, abort context.Context) error {
+	driver.mutex.Lock()
+	alreadyStopping := driver.stopping
+	driver.stopping = true
+	driver.mutex.Unlock()
+
+	if alreadyStopping {
+		return errors.New("shutdown already started")
+	}
+
+	drained := make(chan struct{})
+
+	go func() {
+		driver.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	close(driver.cancel)
+
+	select {
+	case <-drained:
+		return ctx.Err()
+	case <-abort.Done():
+		return abort.Err()
+	}
+}
+
+func (driver *GracefulDriver) GetDriverCreateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.GetDriverCreateOptions(ctx)
+}
+
+func (driver *GracefulDriver) GetDriverUpdateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.GetDriverUpdateOptions(ctx)
+}
+
+func (driver *GracefulDriver) Create(ctx context.Context, opts *types.DriverOptions,
+	clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
+
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.Create(ctx, opts, clusterInfo)
+}
+
+func (driver *GracefulDriver) Update(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions) (*types.ClusterInfo, error) {
+
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.Update(ctx, clusterInfo, opts)
+}
+
+func (driver *GracefulDriver) PostCheck(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.PostCheck(ctx, clusterInfo)
+}
+
+func (driver *GracefulDriver) Remove(ctx context.Context, clusterInfo *types.ClusterInfo) error {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return driver.next.Remove(ctx, clusterInfo)
+}
+
+func (driver *GracefulDriver) GetVersion(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.KubernetesVersion, error) {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.GetVersion(ctx, clusterInfo)
+}
+
+func (driver *GracefulDriver) SetVersion(ctx context.Context, clusterInfo *types.ClusterInfo,
+	version *types.KubernetesVersion) error {
+
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return driver.next.SetVersion(ctx, clusterInfo, version)
+}
+
+func (driver *GracefulDriver) GetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.NodeCount, error) {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.GetClusterSize(ctx, clusterInfo)
+}
+
+func (driver *GracefulDriver) SetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo,
+	count *types.NodeCount) error {
+
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return driver.next.SetClusterSize(ctx, clusterInfo, count)
+}
+
+func (driver *GracefulDriver) GetCapabilities(ctx context.Context) (*types.Capabilities, error) {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.GetCapabilities(ctx)
+}
+
+func (driver *GracefulDriver) RemoveLegacyServiceAccount(ctx context.Context, clusterInfo *types.ClusterInfo) error {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return driver.next.RemoveLegacyServiceAccount(ctx, clusterInfo)
+}
+
+func (driver *GracefulDriver) ETCDSave(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) error {
+
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return driver.next.ETCDSave(ctx, clusterInfo, opts, snapshotName)
+}
+
+func (driver *GracefulDriver) ETCDRestore(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) (*types.ClusterInfo, error) {
+
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.ETCDRestore(ctx, clusterInfo, opts, snapshotName)
+}
+
+func (driver *GracefulDriver) ETCDRemoveSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) error {
+
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return driver.next.ETCDRemoveSnapshot(ctx, clusterInfo, opts, snapshotName)
+}
+
+func (driver *GracefulDriver) GetK8SCapabilities(ctx context.Context, opts *types.DriverOptions) (*types.K8SCapabilities, error) {
+	ctx, done, err := driver.begin(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer done()
+
+	return driver.next.GetK8SCapabilities(ctx, opts)
+}
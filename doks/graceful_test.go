@@ -0,0 +1,97 @@
+package doks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingDriver holds Remove until its context is cancelled or release is
+// closed.
+type blockingDriver struct {
+	types.Driver
+	started chan struct{}
+	release chan struct{}
+}
+
+func (driver *blockingDriver) Remove(ctx context.Context, _ *types.ClusterInfo) error {
+	close(driver.started)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-driver.release:
+		return nil
+	}
+}
+
+func newBlockingDriver() *blockingDriver {
+	return &blockingDriver{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func TestGracefulDriverDrains(t *testing.T) {
+	blocking := newBlockingDriver()
+	driver := NewGracefulDriver(blocking)
+
+	removeErr := make(chan error)
+
+	go func() {
+		removeErr <- driver.Remove(context.Background(), &types.ClusterInfo{})
+	}()
+
+	<-blocking.started
+
+	shutdownErr := make(chan error)
+
+	go func() {
+		shutdownErr <- driver.Shutdown(context.Background(), context.Background())
+	}()
+
+	// wait for the shutdown to start before checking new calls are rejected
+	for {
+		driver.mutex.Lock()
+		stopping := driver.stopping
+		driver.mutex.Unlock()
+
+		if stopping {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, ErrShuttingDown, driver.Remove(context.Background(), &types.ClusterInfo{}))
+
+	_, err := driver.GetCapabilities(context.Background())
+	assert.Equal(t, ErrShuttingDown, err)
+
+	close(blocking.release)
+
+	assert.NoError(t, <-removeErr, "running call finished normally")
+	assert.NoError(t, <-shutdownErr)
+}
+
+func TestGracefulDriverCancelsAfterDrainPeriod(t *testing.T) {
+	blocking := newBlockingDriver()
+	driver := NewGracefulDriver(blocking)
+
+	removeErr := make(chan error)
+
+	go func() {
+		removeErr <- driver.Remove(context.Background(), &types.ClusterInfo{})
+	}()
+
+	<-blocking.started
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := driver.Shutdown(drainCtx, context.Background())
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, context.Canceled, <-removeErr, "running call context cancelled")
+	assert.Error(t, driver.Shutdown(context.Background(), context.Background()), "shutdown only once")
+}
@@ -0,0 +1,217 @@
+package doks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rancher/kontainer-engine/drivers/options"
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/metrics"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentedDriver records the count, duration and outcome of every driver
+// operation, labelled with the region of the cluster, and traces it. The
+// region and the span are put in the context so the DigitalOcean calls made
+// by the operation carry them.
+type InstrumentedDriver struct {
+	next types.Driver
+}
+
+func NewInstrumentedDriver(next types.Driver) *InstrumentedDriver {
+	return &InstrumentedDriver{next: next}
+}
+
+// clusterLabels reads the region from the options, or from the saved state
+// without decrypting it, and the cluster ID from the saved state.
+func clusterLabels(clusterInfo *types.ClusterInfo, opts *types.DriverOptions) (string, string) {
+	saved := struct {
+		ClusterID  string `json:"cluster_id"`
+		RegionSlug string `json:"region_slug"`
+	}{}
+
+	if clusterInfo != nil {
+		json.Unmarshal([]byte(clusterInfo.Metadata["state"]), &saved)
+	}
+
+	if opts != nil {
+		region := options.GetValueFromDriverOptions(opts, types.StringType, "region-slug", "regionSlug").(string)
+
+		if region != "" {
+			saved.RegionSlug = region
+		}
+	}
+
+	return saved.RegionSlug, saved.ClusterID
+}
+
+// operation is one driver call, traced as the root span of the DigitalOcean
+// calls it makes.
+type operation struct {
+	ctx    context.Context
+	name   string
+	region string
+	start  time.Time
+	span   trace.Span
+}
+
+func (driver *InstrumentedDriver) start(ctx context.Context, name string, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions) *operation {
+
+	region, clusterID := clusterLabels(clusterInfo, opts)
+
+	ctx, span := tracing.StartSpan(metrics.WithRegion(ctx, region), "Driver."+name,
+		tracing.RegionKey.String(region), tracing.ClusterIDKey.String(clusterID))
+
+	return &operation{ctx: ctx, name: name, region: region, start: time.Now(), span: span}
+}
+
+func (o *operation) end(err error) {
+	metrics.ObserveOperation(o.name, o.region, service.Outcome(err), o.start)
+	tracing.End(o.span, err)
+}
+
+func (driver *InstrumentedDriver) GetDriverCreateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	operation := driver.start(ctx, "GetDriverCreateOptions", nil, nil)
+	flags, err := driver.next.GetDriverCreateOptions(operation.ctx)
+	operation.end(err)
+
+	return flags, err
+}
+
+func (driver *InstrumentedDriver) GetDriverUpdateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	operation := driver.start(ctx, "GetDriverUpdateOptions", nil, nil)
+	flags, err := driver.next.GetDriverUpdateOptions(operation.ctx)
+	operation.end(err)
+
+	return flags, err
+}
+
+func (driver *InstrumentedDriver) Create(ctx context.Context, opts *types.DriverOptions,
+	clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
+
+	operation := driver.start(ctx, "Create", clusterInfo, opts)
+	info, err := driver.next.Create(operation.ctx, opts, clusterInfo)
+	operation.end(err)
+
+	return info, err
+}
+
+func (driver *InstrumentedDriver) Update(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions) (*types.ClusterInfo, error) {
+
+	operation := driver.start(ctx, "Update", clusterInfo, nil)
+	info, err := driver.next.Update(operation.ctx, clusterInfo, opts)
+	operation.end(err)
+
+	return info, err
+}
+
+func (driver *InstrumentedDriver) PostCheck(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
+	operation := driver.start(ctx, "PostCheck", clusterInfo, nil)
+	info, err := driver.next.PostCheck(operation.ctx, clusterInfo)
+	operation.end(err)
+
+	return info, err
+}
+
+func (driver *InstrumentedDriver) Remove(ctx context.Context, clusterInfo *types.ClusterInfo) error {
+	operation := driver.start(ctx, "Remove", clusterInfo, nil)
+	err := driver.next.Remove(operation.ctx, clusterInfo)
+	operation.end(err)
+
+	return err
+}
+
+func (driver *InstrumentedDriver) GetVersion(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.KubernetesVersion, error) {
+	operation := driver.start(ctx, "GetVersion", clusterInfo, nil)
+	version, err := driver.next.GetVersion(operation.ctx, clusterInfo)
+	operation.end(err)
+
+	return version, err
+}
+
+func (driver *InstrumentedDriver) SetVersion(ctx context.Context, clusterInfo *types.ClusterInfo,
+	version *types.KubernetesVersion) error {
+
+	operation := driver.start(ctx, "SetVersion", clusterInfo, nil)
+	err := driver.next.SetVersion(operation.ctx, clusterInfo, version)
+	operation.end(err)
+
+	return err
+}
+
+func (driver *InstrumentedDriver) GetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.NodeCount, error) {
+	operation := driver.start(ctx, "GetClusterSize", clusterInfo, nil)
+	count, err := driver.next.GetClusterSize(operation.ctx, clusterInfo)
+	operation.end(err)
+
+	return count, err
+}
+
+func (driver *InstrumentedDriver) SetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo,
+	count *types.NodeCount) error {
+
+	operation := driver.start(ctx, "SetClusterSize", clusterInfo, nil)
+	err := driver.next.SetClusterSize(operation.ctx, clusterInfo, count)
+	operation.end(err)
+
+	return err
+}
+
+func (driver *InstrumentedDriver) GetCapabilities(ctx context.Context) (*types.Capabilities, error) {
+	operation := driver.start(ctx, "GetCapabilities", nil, nil)
+	capabilities, err := driver.next.GetCapabilities(operation.ctx)
+	operation.end(err)
+
+	return capabilities, err
+}
+
+func (driver *InstrumentedDriver) RemoveLegacyServiceAccount(ctx context.Context, clusterInfo *types.ClusterInfo) error {
+	operation := driver.start(ctx, "RemoveLegacyServiceAccount", clusterInfo, nil)
+	err := driver.next.RemoveLegacyServiceAccount(operation.ctx, clusterInfo)
+	operation.end(err)
+
+	return err
+}
+
+func (driver *InstrumentedDriver) ETCDSave(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) error {
+
+	operation := driver.start(ctx, "ETCDSave", clusterInfo, nil)
+	err := driver.next.ETCDSave(operation.ctx, clusterInfo, opts, snapshotName)
+	operation.end(err)
+
+	return err
+}
+
+func (driver *InstrumentedDriver) ETCDRestore(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) (*types.ClusterInfo, error) {
+
+	operation := driver.start(ctx, "ETCDRestore", clusterInfo, nil)
+	info, err := driver.next.ETCDRestore(operation.ctx, clusterInfo, opts, snapshotName)
+	operation.end(err)
+
+	return info, err
+}
+
+func (driver *InstrumentedDriver) ETCDRemoveSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) error {
+
+	operation := driver.start(ctx, "ETCDRemoveSnapshot", clusterInfo, nil)
+	err := driver.next.ETCDRemoveSnapshot(operation.ctx, clusterInfo, opts, snapshotName)
+	operation.end(err)
+
+	return err
+}
+
+func (driver *InstrumentedDriver) GetK8SCapabilities(ctx context.Context, opts *types.DriverOptions) (*types.K8SCapabilities, error) {
+	operation := driver.start(ctx, "GetK8SCapabilities", nil, opts)
+	capabilities, err := driver.next.GetK8SCapabilities(operation.ctx, opts)
+	operation.end(err)
+
+	return capabilities, err
+}
@@ -0,0 +1,33 @@
+package doks
+
+import (
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterLabels(t *testing.T) {
+	opts := &types.DriverOptions{StringOptions: map[string]string{"region-slug": "nyc1"}}
+	clusterInfo := &types.ClusterInfo{Metadata: map[string]string{
+		"state": `{"cluster_id":"abcd","region_slug":"sfo2","token":"enc:v1:x"}`}}
+
+	region, clusterID := clusterLabels(clusterInfo, opts)
+	assert.Equal(t, "nyc1", region)
+	assert.Equal(t, "abcd", clusterID)
+
+	region, clusterID = clusterLabels(clusterInfo, nil)
+	assert.Equal(t, "sfo2", region)
+	assert.Equal(t, "abcd", clusterID)
+
+	region, _ = clusterLabels(clusterInfo, &types.DriverOptions{})
+	assert.Equal(t, "sfo2", region)
+
+	region, clusterID = clusterLabels(&types.ClusterInfo{}, nil)
+	assert.Equal(t, "", region)
+	assert.Equal(t, "", clusterID)
+
+	region, clusterID = clusterLabels(nil, nil)
+	assert.Equal(t, "", region)
+	assert.Equal(t, "", clusterID)
+}
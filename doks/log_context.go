@@ -0,0 +1,22 @@
+package doks
+
+import (
+	"context"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// operationContext starts the logs of a driver operation, all of them
+// sharing one correlation ID.
+func operationContext(ctx context.Context, operation string) context.Context {
+	ctx = logging.WithOperation(ctx, operation)
+	logging.FromContext(ctx).Debugf("DOKS.Driver.%s(...) called", operation)
+
+	return ctx
+}
+
+// clusterContext adds the cluster identifiers once they are known.
+func clusterContext(ctx context.Context, clusterState state.Cluster) context.Context {
+	return logging.WithCluster(ctx, clusterState.ClusterID, clusterState.NodePoolID, clusterState.RegionSlug)
+}
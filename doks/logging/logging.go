@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	LevelEnv  = "DOKS_LOG_LEVEL"
+	FormatEnv = "DOKS_LOG_FORMAT"
+
+	FormatText = "text"
+	FormatJSON = "json"
+
+	FieldOperation     = "operation"
+	FieldClusterID     = "cluster_id"
+	FieldNodePoolID    = "node_pool_id"
+	FieldRegion        = "region"
+	FieldCorrelationID = "correlation_id"
+)
+
+// The standard logger always redacts, even when Configure is never called.
+func init() {
+	logrus.SetFormatter(NewRedactingFormatter(&logrus.TextFormatter{}))
+}
+
+// Configure sets the level and the format of the standard logger. Empty
+// values keep the current setting.
+func Configure(level, format string) error {
+	if level != "" {
+		parsedLevel, err := logrus.ParseLevel(level)
+
+		if err != nil {
+			return fmt.Errorf("invalid log level %q", level)
+		}
+
+		logrus.SetLevel(parsedLevel)
+	}
+
+	switch strings.ToLower(format) {
+	case "":
+	case FormatText:
+		logrus.SetFormatter(NewRedactingFormatter(&logrus.TextFormatter{}))
+	case FormatJSON:
+		logrus.SetFormatter(NewRedactingFormatter(&logrus.JSONFormatter{}))
+	default:
+		return fmt.Errorf("invalid log format %q, expected %s or %s", format, FormatText, FormatJSON)
+	}
+
+	return nil
+}
+
+func ConfigureFromEnv() error {
+	return Configure(os.Getenv(LevelEnv), os.Getenv(FormatEnv))
+}
+
+type entryKey struct{}
+
+// FromContext returns the logger carrying the fields added to ctx.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+			return entry
+		}
+	}
+
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return context.WithValue(ctx, entryKey{}, FromContext(ctx).WithFields(fields))
+}
+
+// WithOperation names the operation logged with ctx. A correlation ID is
+// generated unless the caller already set one.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	fields := logrus.Fields{FieldOperation: operation}
+
+	if _, ok := FromContext(ctx).Data[FieldCorrelationID]; !ok {
+		fields[FieldCorrelationID] = NewCorrelationID()
+	}
+
+	return WithFields(ctx, fields)
+}
+
+// WithCluster adds the identifiers of the cluster. Empty values are skipped.
+func WithCluster(ctx context.Context, clusterID, nodePoolID, region string) context.Context {
+	fields := logrus.Fields{}
+
+	for key, value := range map[string]string{
+		FieldClusterID:  clusterID,
+		FieldNodePoolID: nodePoolID,
+		FieldRegion:     region,
+	} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+
+	return WithFields(ctx, fields)
+}
+
+func NewCorrelationID() string {
+	id := make([]byte, 8)
+
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(id)
+}
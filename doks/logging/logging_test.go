@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigure(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+	defer logrus.SetFormatter(logrus.StandardLogger().Formatter)
+
+	assert.NoError(t, Configure("debug", "json"))
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	formatter, ok := logrus.StandardLogger().Formatter.(*RedactingFormatter)
+
+	if assert.True(t, ok) {
+		assert.IsType(t, &logrus.JSONFormatter{}, formatter.next)
+	}
+
+	assert.NoError(t, Configure("", ""))
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	assert.Error(t, Configure("verbose", ""))
+	assert.Error(t, Configure("", "xml"))
+}
+
+func TestWithOperation(t *testing.T) {
+	ctx := WithOperation(context.Background(), "Create")
+	ctx = WithCluster(ctx, "abcd", "", "nyc1")
+
+	fields := FromContext(ctx).Data
+
+	assert.Equal(t, "Create", fields[FieldOperation])
+	assert.Equal(t, "abcd", fields[FieldClusterID])
+	assert.Equal(t, "nyc1", fields[FieldRegion])
+	assert.NotContains(t, fields, FieldNodePoolID)
+	assert.Len(t, fields[FieldCorrelationID], 16)
+
+	nested := WithOperation(ctx, "preflight")
+
+	assert.Equal(t, fields[FieldCorrelationID], FromContext(nested).Data[FieldCorrelationID])
+	assert.Equal(t, "preflight", FromContext(nested).Data[FieldOperation])
+}
+
+func TestFromContextWithoutFields(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()).Data)
+	assert.Empty(t, FromContext(nil).Data)
+}
+
+func TestRedact(t *testing.T) {
+	AddSecret("short")
+	AddSecret("registered-secret-value")
+
+	tests := map[string]string{
+		"token registered-secret-value used":              "token [REDACTED] used",
+		"token short used":                                "token short used",
+		"token dop_v1_0123456789abcdef0123456789abcdef":   "token [REDACTED]",
+		"Authorization: Bearer abc.def-ghi":               "Authorization: Bearer [REDACTED]",
+		"users:\n- user:\n    token: abcdefgh\n":          "users:\n- user:\n    token: [REDACTED]\n",
+		"client-key-data: LS0tLS1CRUdJTg==":               "client-key-data: [REDACTED]",
+		`{"cluster_id":"abcd","token":"abcdefgh"}`:        `{"cluster_id":"abcd","token":"[REDACTED]"}`,
+		"error in get cluster: not found (request id 12)": "error in get cluster: not found (request id 12)",
+	}
+
+	for text, expected := range tests {
+		assert.Equal(t, expected, Redact(text))
+	}
+}
+
+func TestAddSecretKeepsMostRecent(t *testing.T) {
+	AddSecret("first-rotated-secret")
+
+	for i := 0; i < maxSecrets; i++ {
+		AddSecret(fmt.Sprintf("rotated-secret-%04d", i))
+	}
+
+	assert.Equal(t, maxSecrets, len(secrets.values))
+	assert.Equal(t, "first-rotated-secret used", Redact("first-rotated-secret used"))
+	assert.Equal(t, "[REDACTED] used", Redact(fmt.Sprintf("rotated-secret-%04d used", maxSecrets-1)))
+}
+
+func TestRedactingFormatter(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = output
+	logger.Formatter = NewRedactingFormatter(&logrus.JSONFormatter{})
+
+	logger.WithFields(logrus.Fields{
+		"token":      "plain-token",
+		"kubeconfig": "apiVersion: v1",
+		"cluster_id": "abcd",
+		"count":      3,
+	}).WithError(errors.New("bad token dop_v1_0123456789abcdef0123456789abcdef")).
+		Info("using token: abcdefgh")
+
+	line := map[string]interface{}{}
+
+	if !assert.NoError(t, json.Unmarshal(output.Bytes(), &line)) {
+		return
+	}
+
+	assert.Equal(t, Redacted, line["token"])
+	assert.Equal(t, Redacted, line["kubeconfig"])
+	assert.Equal(t, "abcd", line["cluster_id"])
+	assert.Equal(t, float64(3), line["count"])
+	assert.Equal(t, "bad token [REDACTED]", line["error"])
+	assert.Equal(t, "using token: [REDACTED]", line["msg"])
+}
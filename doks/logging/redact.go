@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const Redacted = "[REDACTED]"
+
+// minSecretLength keeps short values, which would match ordinary words, out
+// of the redaction list.
+const minSecretLength = 8
+
+// maxSecrets bounds the redaction list. Once full, the secret added or seen
+// longest ago is dropped, so a long running driver rotating tokens neither
+// grows without limit nor slows every log line down.
+const maxSecrets = 256
+
+// sensitiveFields are logged as Redacted whatever their value.
+var sensitiveFields = map[string]bool{
+	"token":                 true,
+	"access_token":          true,
+	"service_account_token": true,
+	"kubeconfig":            true,
+	"kube_config":           true,
+	"password":              true,
+	"secret":                true,
+}
+
+var redactPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// DigitalOcean personal access, OAuth and refresh tokens
+	{regexp.MustCompile(`\bdo[opr]_v1_[A-Za-z0-9]{16,}`), Redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`), "${1}" + Redacted},
+	// kubeconfig credentials
+	{regexp.MustCompile(`((?:client-key-data|client-certificate-data|certificate-authority-data|token|password)\s*:\s*)[^\s",}]+`),
+		"${1}" + Redacted},
+	// JSON encoded state and secrets
+	{regexp.MustCompile(`("(?:token|access_token|accessToken|service_account_token|password)"\s*:\s*")[^"]*(")`),
+		"${1}" + Redacted + "${2}"},
+}
+
+var secrets = struct {
+	sync.Mutex
+	order  *list.List
+	values map[string]*list.Element
+}{order: list.New(), values: map[string]*list.Element{}}
+
+// AddSecret makes every later log line containing value print Redacted
+// instead. Tokens are added as soon as they are read.
+func AddSecret(value string) {
+	value = strings.TrimSpace(value)
+
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	if element, known := secrets.values[value]; known {
+		secrets.order.MoveToFront(element)
+		return
+	}
+
+	secrets.values[value] = secrets.order.PushFront(value)
+
+	for secrets.order.Len() > maxSecrets {
+		oldest := secrets.order.Back()
+		secrets.order.Remove(oldest)
+		delete(secrets.values, oldest.Value.(string))
+	}
+}
+
+// Redact removes the known secrets and anything looking like a token or a
+// kubeconfig credential from text.
+func Redact(text string) string {
+	secrets.Lock()
+	for element := secrets.order.Front(); element != nil; element = element.Next() {
+		text = strings.Replace(text, element.Value.(string), Redacted, -1)
+	}
+	secrets.Unlock()
+
+	for _, redactPattern := range redactPatterns {
+		text = redactPattern.pattern.ReplaceAllString(text, redactPattern.replacement)
+	}
+
+	return text
+}
+
+// RedactingFormatter redacts the message and the fields of every entry
+// before handing it to the wrapped formatter.
+type RedactingFormatter struct {
+	next logrus.Formatter
+}
+
+func NewRedactingFormatter(next logrus.Formatter) *RedactingFormatter {
+	return &RedactingFormatter{next: next}
+}
+
+func (formatter *RedactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	redacted := *entry
+	redacted.Message = Redact(entry.Message)
+	redacted.Data = make(logrus.Fields, len(entry.Data))
+
+	for key, value := range entry.Data {
+		if sensitiveFields[strings.ToLower(key)] {
+			redacted.Data[key] = Redacted
+			continue
+		}
+
+		switch typed := value.(type) {
+		case string:
+			redacted.Data[key] = Redact(typed)
+		case error:
+			redacted.Data[key] = Redact(typed.Error())
+		case fmt.Stringer:
+			redacted.Data[key] = Redact(typed.String())
+		default:
+			redacted.Data[key] = value
+		}
+	}
+
+	return formatter.next.Format(&redacted)
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "doks_driver"
+
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+
+	unknownRegion = "unknown"
+)
+
+// Registry holds every collector of the driver. Metrics are always recorded
+// and only exposed when the metrics listener is started.
+var Registry = prometheus.NewRegistry()
+
+var (
+	driverOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Driver operations called by Rancher.",
+	}, []string{"operation", "region", "outcome"})
+
+	driverOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the driver operations called by Rancher.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600, 900, 1800, 3600},
+	}, []string{"operation", "region", "outcome"})
+
+	apiCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_calls_total",
+		Help:      "Calls to the DigitalOcean service.",
+	}, []string{"operation", "region", "outcome"})
+
+	apiCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_call_duration_seconds",
+		Help:      "Duration of the calls to the DigitalOcean service, waits included.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 15, 60, 300, 900, 1800},
+	}, []string{"operation", "region", "outcome"})
+
+	waitIterations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "wait_iterations",
+		Help:      "Polling iterations needed until a cluster or node pool reached the expected state.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 400},
+	}, []string{"operation", "region"})
+
+	rateLimitRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "api_rate_limit_remaining",
+		Help:      "Requests left in the current DigitalOcean API rate limit window.",
+	})
+
+	rateLimit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "api_rate_limit",
+		Help:      "Requests allowed per DigitalOcean API rate limit window.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(driverOperations, driverOperationDuration, apiCalls, apiCallDuration,
+		waitIterations, rateLimitRemaining, rateLimit)
+}
+
+type regionKey struct{}
+
+// WithRegion labels the metrics recorded with ctx with the cluster region.
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+func Region(ctx context.Context) string {
+	if ctx != nil {
+		if region, ok := ctx.Value(regionKey{}).(string); ok && region != "" {
+			return region
+		}
+	}
+
+	return unknownRegion
+}
+
+func ObserveOperation(operation, region, outcome string, start time.Time) {
+	if region == "" {
+		region = unknownRegion
+	}
+
+	driverOperations.WithLabelValues(operation, region, outcome).Inc()
+	driverOperationDuration.WithLabelValues(operation, region, outcome).Observe(time.Since(start).Seconds())
+}
+
+func ObserveAPICall(ctx context.Context, operation, outcome string, start time.Time) {
+	region := Region(ctx)
+
+	apiCalls.WithLabelValues(operation, region, outcome).Inc()
+	apiCallDuration.WithLabelValues(operation, region, outcome).Observe(time.Since(start).Seconds())
+}
+
+func ObserveWaitIterations(ctx context.Context, operation string, iterations int) {
+	waitIterations.WithLabelValues(operation, Region(ctx)).Observe(float64(iterations))
+}
+
+func SetRateLimit(limit, remaining int) {
+	rateLimit.Set(float64(limit))
+	rateLimitRemaining.Set(float64(remaining))
+}
+
+// Serve exposes the registry on /metrics until the listener is closed.
+func Serve(address string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not listen for metrics on %s", address)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: listener.Addr().String(), Handler: mux}
+
+	go server.Serve(listener)
+
+	return server, nil
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegion(t *testing.T) {
+	assert.Equal(t, "unknown", Region(context.Background()))
+	assert.Equal(t, "unknown", Region(WithRegion(context.Background(), "")))
+	assert.Equal(t, "nyc1", Region(WithRegion(context.Background(), "nyc1")))
+}
+
+func TestServe(t *testing.T) {
+	ctx := WithRegion(context.Background(), "sfo2")
+
+	ObserveOperation("Create", "", OutcomeSuccess, time.Now())
+	ObserveAPICall(ctx, "CreateCluster", OutcomeError, time.Now())
+	ObserveWaitIterations(ctx, "WaitNodeReplaced", 3)
+	SetRateLimit(5000, 4999)
+
+	server, err := Serve("127.0.0.1:0")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer server.Close()
+
+	response, err := http.Get("http://" + server.Addr + "/metrics")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer response.Body.Close()
+
+	body, _ := ioutil.ReadAll(response.Body)
+	exposed := string(body)
+
+	assert.True(t, strings.Contains(exposed,
+		`doks_driver_operations_total{operation="Create",outcome="success",region="unknown"} 1`))
+	assert.True(t, strings.Contains(exposed,
+		`doks_driver_api_calls_total{operation="CreateCluster",outcome="error",region="sfo2"} 1`))
+	assert.True(t, strings.Contains(exposed,
+		`doks_driver_wait_iterations_sum{operation="WaitNodeReplaced",region="sfo2"} 3`))
+	assert.True(t, strings.Contains(exposed, "doks_driver_api_rate_limit_remaining 4999"))
+}
+
+func TestServeInvalidAddress(t *testing.T) {
+	_, err := Serve("invalid-address")
+
+	assert.Error(t, err)
+}
@@ -25,6 +25,64 @@ func (builderImpl) BuildCreateOptions() *types.DriverFlags {
 			nil,
 		)
 
+	builder(
+		"credential-source",
+		types.StringType,
+		"Where the DigitalOcean token is read from: token, env, file, cloud-credential or vault",
+		nil,
+	)
+
+	builder(
+		"token-env",
+		types.StringType,
+		"Environment variable of the driver process holding the token, among the ones the driver allows. Defaults to DIGITALOCEAN_ACCESS_TOKEN",
+		nil,
+	)
+
+	builder(
+		"token-file",
+		types.StringType,
+		"File holding the token in a directory the driver allows, read again on every use",
+		nil,
+	)
+
+	builder(
+		"cloud-credential-id",
+		types.StringType,
+		"Rancher cloud credential holding the token, such as cattle-global-data:cc-xxxxx, in a namespace the driver allows",
+		nil,
+	)
+
+	builder(
+		"vault-path",
+		types.StringType,
+		"Path of the Vault KV secret holding the token, such as secret/data/doks",
+		nil,
+	)
+
+	builder(
+		"vault-field",
+		types.StringType,
+		"Field of the Vault KV secret holding the token",
+		&types.Default{
+			DefaultString: "token",
+		},
+	)
+
+	builder(
+		"api-timeout",
+		types.StringType,
+		"Timeout of every DigitalOcean API request, such as 30s. Defaults to DOKS_API_TIMEOUT",
+		nil,
+	)
+
+	builder(
+		"user-agent-suffix",
+		types.StringType,
+		"Appended to the user agent of the DigitalOcean API requests",
+		nil,
+	)
+
 	builder(
 		"display-name",
 		types.StringType,
@@ -48,6 +106,24 @@ func (builderImpl) BuildCreateOptions() *types.DriverFlags {
 		},
 	)
 
+	builder(
+		"auto-repair",
+		types.BoolPointerType,
+		"Automatically replaces worker nodes stuck in error or provisioning",
+		&types.Default{
+			DefaultBool: false,
+		},
+	)
+
+	builder(
+		"scaling-policy",
+		types.StringType,
+		"How a new cluster size is spread across node pools: primary, proportional or fill",
+		&types.Default{
+			DefaultString: "primary",
+		},
+	)
+
 	builder(
 		"region-slug",
 		types.StringType,
@@ -126,6 +202,81 @@ func (builderImpl) BuildCreateOptions() *types.DriverFlags {
 		nil,
 	)
 
+	builder(
+		"backup-bucket",
+		types.StringType,
+		"Spaces or S3 compatible bucket where resource backups are stored",
+		nil,
+	)
+
+	builder(
+		"backup-region",
+		types.StringType,
+		"Region of the backup bucket",
+		&types.Default{
+			DefaultString: "nyc3",
+		},
+	)
+
+	builder(
+		"backup-endpoint",
+		types.StringType,
+		"S3 compatible endpoint of the backup bucket. Defaults to the Spaces endpoint of the region",
+		nil,
+	)
+
+	builder(
+		"backup-folder",
+		types.StringType,
+		"Folder of the backup bucket where resource backups are stored",
+		nil,
+	)
+
+	builder(
+		"backup-access-key",
+		types.StringType,
+		"Access key of the backup bucket",
+		nil,
+	)
+
+	builder(
+		"backup-secret-key",
+		types.StringType,
+		"Secret key of the backup bucket",
+		nil,
+	)
+
+	builder(
+		"backup-path-style",
+		types.BoolPointerType,
+		"Uses path style requests, as required by MinIO",
+		&types.Default{
+			DefaultBool: false,
+		},
+	)
+
+	builder(
+		"project-id",
+		types.StringType,
+		"DigitalOcean project the cluster is assigned to, the default project when empty",
+		nil,
+	)
+
+	builder(
+		"project-name",
+		types.StringType,
+		"Name of the DigitalOcean project the cluster is assigned to, when no project ID is given",
+		nil,
+	)
+
+	builder(
+		"clone-from-cluster-id",
+		types.StringType,
+		"Existing cluster whose version, tags, auto upgrade, maintenance window and node pools are copied. "+
+			"Only the name, region and VPC of these options are kept",
+		nil,
+	)
+
 	return builder(
 		"vpc-id",
 		types.StringType,
@@ -145,6 +296,34 @@ func (builderImpl) BuildUpdateOptions() *types.DriverFlags{
 		nil,
 	)
 
+	builder(
+		"auto-repair",
+		types.BoolPointerType,
+		"Automatically replaces worker nodes stuck in error or provisioning",
+		nil,
+	)
+
+	builder(
+		"scaling-policy",
+		types.StringType,
+		"How a new cluster size is spread across node pools: primary, proportional or fill",
+		nil,
+	)
+
+	builder(
+		"project-id",
+		types.StringType,
+		"DigitalOcean project the cluster is moved to",
+		nil,
+	)
+
+	builder(
+		"project-name",
+		types.StringType,
+		"Name of the DigitalOcean project the cluster is moved to, when no project ID is given",
+		nil,
+	)
+
 	builder(
 		"token",
 		types.StringType,
@@ -152,6 +331,64 @@ func (builderImpl) BuildUpdateOptions() *types.DriverFlags{
 		nil,
 	)
 
+	builder(
+		"credential-source",
+		types.StringType,
+		"Where the DigitalOcean token is read from: token, env, file, cloud-credential or vault",
+		nil,
+	)
+
+	builder(
+		"token-env",
+		types.StringType,
+		"Environment variable of the driver process holding the token, among the ones the driver allows. Defaults to DIGITALOCEAN_ACCESS_TOKEN",
+		nil,
+	)
+
+	builder(
+		"token-file",
+		types.StringType,
+		"File holding the token in a directory the driver allows, read again on every use",
+		nil,
+	)
+
+	builder(
+		"cloud-credential-id",
+		types.StringType,
+		"Rancher cloud credential holding the token, such as cattle-global-data:cc-xxxxx, in a namespace the driver allows",
+		nil,
+	)
+
+	builder(
+		"vault-path",
+		types.StringType,
+		"Path of the Vault KV secret holding the token, such as secret/data/doks",
+		nil,
+	)
+
+	builder(
+		"vault-field",
+		types.StringType,
+		"Field of the Vault KV secret holding the token",
+		&types.Default{
+			DefaultString: "token",
+		},
+	)
+
+	builder(
+		"api-timeout",
+		types.StringType,
+		"Timeout of every DigitalOcean API request, such as 30s. Defaults to DOKS_API_TIMEOUT",
+		nil,
+	)
+
+	builder(
+		"user-agent-suffix",
+		types.StringType,
+		"Appended to the user agent of the DigitalOcean API requests",
+		nil,
+	)
+
 	builder(
 		"tags",
 		types.StringSliceType,
@@ -194,6 +431,76 @@ func (builderImpl) BuildUpdateOptions() *types.DriverFlags{
 		nil,
 	)
 
+	builder(
+		"recycle-nodes",
+		types.StringSliceType,
+		"Names of worker nodes to replace, one at a time. Each request runs once, clear it to give it again",
+		nil,
+	)
+
+	builder(
+		"recycle-all-nodes",
+		types.BoolPointerType,
+		"Replaces every worker node of the node pool, one at a time. Runs once, clear it to recycle again",
+		nil,
+	)
+
+	builder(
+		"recycle-skip-drain",
+		types.BoolPointerType,
+		"Skips draining worker nodes before replacing them",
+		nil,
+	)
+
+	builder(
+		"backup-bucket",
+		types.StringType,
+		"Spaces or S3 compatible bucket where resource backups are stored",
+		nil,
+	)
+
+	builder(
+		"backup-region",
+		types.StringType,
+		"Region of the backup bucket",
+		nil,
+	)
+
+	builder(
+		"backup-endpoint",
+		types.StringType,
+		"S3 compatible endpoint of the backup bucket. Defaults to the Spaces endpoint of the region",
+		nil,
+	)
+
+	builder(
+		"backup-folder",
+		types.StringType,
+		"Folder of the backup bucket where resource backups are stored",
+		nil,
+	)
+
+	builder(
+		"backup-access-key",
+		types.StringType,
+		"Access key of the backup bucket",
+		nil,
+	)
+
+	builder(
+		"backup-secret-key",
+		types.StringType,
+		"Secret key of the backup bucket",
+		nil,
+	)
+
+	builder(
+		"backup-path-style",
+		types.BoolPointerType,
+		"Uses path style requests, as required by MinIO",
+		nil,
+	)
+
 	return builder(
 		"node-pool-count",
 		types.IntType,
@@ -18,6 +18,18 @@ func TestGetCreateOptions(t *testing.T) {
 	assert.True(t, ok, "Token is present")
 	assert.Equal(t, types.StringType, tokenFlag.GetType(), "Token type is string")
 
+	for _, name := range []string{"credential-source", "token-env", "token-file", "cloud-credential-id",
+		"vault-path", "vault-field", "api-timeout", "user-agent-suffix", "project-id", "project-name"} {
+		credentialFlag, ok := options.Options[name]
+
+		assert.True(t, ok, name+" flag is present")
+		assert.Equal(t, types.StringType, credentialFlag.GetType(), name+" type is string")
+	}
+
+	for _, name := range []string{"api-url", "proxy-url", "ca-bundle"} {
+		assert.NotContains(t, options.Options, name, name+" is a setting of the driver process")
+	}
+
 	displayNameFlag, ok := options.Options["display-name"]
 
 	assert.True(t, ok, "DisplayName flag is present")
@@ -83,10 +95,32 @@ func TestGetCreateOptions(t *testing.T) {
 	assert.True(t, ok, "NodePoolLabels flag is present")
 	assert.Equal(t, types.StringSliceType, nodePoolLabelsFlag.GetType(), "NodePoolLabels type is []string")
 
+	autoRepairFlag, ok := options.Options["auto-repair"]
+
+	assert.True(t, ok, "AutoRepair flag is present")
+	assert.Equal(t, types.BoolPointerType, autoRepairFlag.GetType(), "AutoRepair type is bool")
+
+	scalingPolicyFlag, ok := options.Options["scaling-policy"]
+
+	assert.True(t, ok, "ScalingPolicy flag is present")
+	assert.Equal(t, types.StringType, scalingPolicyFlag.GetType(), "ScalingPolicy type is string")
+
 	VPCIDFlag, ok := options.Options["vpc-id"]
 
 	assert.True(t, ok, "VPCID flag is present")
 	assert.Equal(t, types.StringType, VPCIDFlag.GetType(), "VPCID type is string")
+
+	cloneFlag, ok := options.Options["clone-from-cluster-id"]
+
+	assert.True(t, ok, "CloneFromClusterID flag is present")
+	assert.Equal(t, types.StringType, cloneFlag.GetType(), "CloneFromClusterID type is string")
+
+	for _, name := range []string{"project-id", "project-name"} {
+		projectFlag, ok := options.Options[name]
+
+		assert.True(t, ok, "%s flag is present", name)
+		assert.Equal(t, types.StringType, projectFlag.GetType(), "%s type is string", name)
+	}
 }
 
 func TestGetUpdateOptions(t *testing.T) {
@@ -98,6 +132,14 @@ func TestGetUpdateOptions(t *testing.T) {
 	assert.True(t, ok, "Token is present")
 	assert.Equal(t, types.StringType, tokenFlag.GetType(), "Token type is string")
 
+	for _, name := range []string{"credential-source", "token-env", "token-file", "cloud-credential-id",
+		"vault-path", "vault-field", "api-timeout", "user-agent-suffix"} {
+		credentialFlag, ok := options.Options[name]
+
+		assert.True(t, ok, name+" flag is present")
+		assert.Equal(t, types.StringType, credentialFlag.GetType(), name+" type is string")
+	}
+
 	autoUpgradeFlag, ok := options.Options["auto-upgraded"]
 
 	assert.True(t, ok, "AutoUpgrade flag is present")
@@ -138,4 +180,19 @@ func TestGetUpdateOptions(t *testing.T) {
 	assert.True(t, ok, "NodePoolLabels flag is present")
 	assert.Equal(t, types.StringSliceType, nodePoolLabelsFlag.GetType(), "NodePoolLabels type is []string")
 
+	recycleNodesFlag, ok := options.Options["recycle-nodes"]
+
+	assert.True(t, ok, "RecycleNodes flag is present")
+	assert.Equal(t, types.StringSliceType, recycleNodesFlag.GetType(), "RecycleNodes type is []string")
+
+	recycleAllNodesFlag, ok := options.Options["recycle-all-nodes"]
+
+	assert.True(t, ok, "RecycleAllNodes flag is present")
+	assert.Equal(t, types.BoolPointerType, recycleAllNodesFlag.GetType(), "RecycleAllNodes type is bool")
+
+	recycleSkipDrainFlag, ok := options.Options["recycle-skip-drain"]
+
+	assert.True(t, ok, "RecycleSkipDrain flag is present")
+	assert.Equal(t, types.BoolPointerType, recycleSkipDrainFlag.GetType(), "RecycleSkipDrain type is bool")
+
 }
@@ -0,0 +1,181 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/rancher/kontainer-engine/types"
+	"gopkg.in/yaml.v2"
+)
+
+// Values maps option names to plain values, as written in JSON or YAML
+// files.
+type Values map[string]interface{}
+
+// ParseValues reads values written in JSON or YAML.
+func ParseValues(data []byte) (Values, error) {
+	values := Values{}
+
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("error reading options: %v", err)
+	}
+
+	return values, nil
+}
+
+// DriverOptions types the values after the flags declared by the driver.
+// Unknown options are refused, so typos do not go unnoticed. With defaults,
+// flags missing from the values get their default like Rancher sends them.
+func (values Values) DriverOptions(flags *types.DriverFlags, withDefaults bool) (*types.DriverOptions, error) {
+	driverOptions := &types.DriverOptions{
+		BoolOptions:        map[string]bool{},
+		StringOptions:      map[string]string{},
+		IntOptions:         map[string]int64{},
+		StringSliceOptions: map[string]*types.StringSlice{},
+	}
+
+	names := make([]string, 0, len(values))
+
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag, ok := flags.Options[name]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown option %q", name)
+		}
+
+		if err := setOption(driverOptions, name, flag.Type, values[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if withDefaults {
+		for name, flag := range flags.Options {
+			if _, ok := values[name]; !ok && flag.Default != nil {
+				setDefault(driverOptions, name, flag)
+			}
+		}
+	}
+
+	return driverOptions, nil
+}
+
+func setOption(driverOptions *types.DriverOptions, name, typ string, value interface{}) error {
+	var err error
+
+	switch typ {
+	case types.BoolType, types.BoolPointerType:
+		driverOptions.BoolOptions[name], err = toBool(value)
+	case types.IntType, types.IntPointerType:
+		driverOptions.IntOptions[name], err = toInt(value)
+	case types.StringType:
+		driverOptions.StringOptions[name], err = toString(value)
+	case types.StringSliceType:
+		var slice []string
+
+		slice, err = toStringSlice(value)
+		driverOptions.StringSliceOptions[name] = &types.StringSlice{Value: slice}
+	default:
+		err = fmt.Errorf("type %s is not supported", typ)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid option %q: %v", name, err)
+	}
+
+	return nil
+}
+
+func setDefault(driverOptions *types.DriverOptions, name string, flag *types.Flag) {
+	switch flag.Type {
+	case types.BoolType, types.BoolPointerType:
+		driverOptions.BoolOptions[name] = flag.Default.DefaultBool
+	case types.IntType, types.IntPointerType:
+		driverOptions.IntOptions[name] = flag.Default.DefaultInt
+	case types.StringType:
+		driverOptions.StringOptions[name] = flag.Default.DefaultString
+	case types.StringSliceType:
+		if flag.Default.DefaultStringSlice != nil {
+			driverOptions.StringSliceOptions[name] = flag.Default.DefaultStringSlice
+		}
+	}
+}
+
+func toBool(value interface{}) (bool, error) {
+	switch typed := value.(type) {
+	case bool:
+		return typed, nil
+	case string:
+		return strconv.ParseBool(typed)
+	}
+
+	return false, fmt.Errorf("%v is not a boolean", value)
+}
+
+func toInt(value interface{}) (int64, error) {
+	switch typed := value.(type) {
+	case int:
+		return int64(typed), nil
+	case int64:
+		return typed, nil
+	case uint64:
+		if typed <= math.MaxInt64 {
+			return int64(typed), nil
+		}
+	case float64:
+		if typed == math.Trunc(typed) {
+			return int64(typed), nil
+		}
+	case string:
+		return strconv.ParseInt(typed, 10, 64)
+	}
+
+	return 0, fmt.Errorf("%v is not an integer", value)
+}
+
+func toString(value interface{}) (string, error) {
+	switch typed := value.(type) {
+	case string:
+		return typed, nil
+	case bool, int, int64, uint64:
+		return fmt.Sprint(typed), nil
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), nil
+	}
+
+	return "", fmt.Errorf("%v is not a string", value)
+}
+
+// toStringSlice accepts a list or a single value.
+func toStringSlice(value interface{}) ([]string, error) {
+	if slice, ok := value.([]string); ok {
+		return slice, nil
+	}
+
+	list, ok := value.([]interface{})
+
+	if !ok {
+		list = []interface{}{value}
+	}
+
+	slice := make([]string, 0, len(list))
+
+	for _, item := range list {
+		text, err := toString(item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		slice = append(slice, text)
+	}
+
+	return slice, nil
+}
@@ -0,0 +1,81 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValuesDriverOptions(t *testing.T) {
+	values, err := ParseValues([]byte(`
+name: rancher-cluster
+version-slug: 1.18
+node-pool-count: 3
+node-pool-autoscale: "true"
+tags: [rancher, 42]
+node-pool-labels: role=worker
+`))
+
+	assert.NoError(t, err)
+
+	driverOptions, err := values.DriverOptions(optionsBuilder.BuildCreateOptions(), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "rancher-cluster", driverOptions.StringOptions["name"], "String kept")
+	assert.Equal(t, "1.18", driverOptions.StringOptions["version-slug"], "Number read as string")
+	assert.Equal(t, int64(3), driverOptions.IntOptions["node-pool-count"], "Int converted")
+	assert.True(t, driverOptions.BoolOptions["node-pool-autoscale"], "Bool parsed")
+	assert.Equal(t, []string{"rancher", "42"}, driverOptions.StringSliceOptions["tags"].Value, "List converted")
+	assert.Equal(t, []string{"role=worker"}, driverOptions.StringSliceOptions["node-pool-labels"].Value,
+		"Single value as list")
+	assert.Len(t, driverOptions.StringOptions, 2, "No defaults")
+}
+
+func TestValuesDriverOptionsJSON(t *testing.T) {
+	values, err := ParseValues([]byte(`{"node-pool-count": 2, "auto-upgraded": false}`))
+
+	assert.NoError(t, err)
+
+	driverOptions, err := values.DriverOptions(optionsBuilder.BuildUpdateOptions(), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), driverOptions.IntOptions["node-pool-count"], "Int converted")
+	assert.Contains(t, driverOptions.BoolOptions, "auto-upgraded", "False bool kept")
+}
+
+func TestValuesDriverOptionsDefaults(t *testing.T) {
+	flags := &types.DriverFlags{Options: map[string]*types.Flag{
+		"name":  {Type: types.StringType},
+		"count": {Type: types.IntType, Default: &types.Default{DefaultInt: 1}},
+	}}
+
+	driverOptions, err := Values{"name": "cluster"}.DriverOptions(flags, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), driverOptions.IntOptions["count"], "Default applied")
+
+	driverOptions, err = Values{"count": 5}.DriverOptions(flags, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), driverOptions.IntOptions["count"], "Value wins over default")
+}
+
+func TestValuesDriverOptionsInvalid(t *testing.T) {
+	flags := optionsBuilder.BuildCreateOptions()
+
+	for _, values := range []Values{
+		{"unknown-option": "value"},
+		{"node-pool-count": "three"},
+		{"node-pool-count": 1.5},
+		{"auto-upgraded": "maybe"},
+		{"name": []interface{}{"a", "b"}},
+	} {
+		_, err := values.DriverOptions(flags, false)
+
+		assert.Error(t, err, "values %v", values)
+	}
+
+	_, err := ParseValues([]byte("name: [unclosed"))
+	assert.Error(t, err, "Malformed file reported")
+}
@@ -0,0 +1,98 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+const accountStatusActive = "active"
+
+var (
+	ErrTokenReadOnly    = errors.New("the token has no write scope")
+	ErrAccountNotActive = errors.New("the DigitalOcean account is not active")
+	ErrDropletLimit     = errors.New("the droplet limit of the DigitalOcean account would be exceeded")
+)
+
+// preflightCheck confirms the token can change the account, the account is
+// active and it has room for the droplets before any resource is touched, so
+// a revoked or read only token or a full account fails fast instead of midway.
+// Droplets autoscaling may add later are only warned about.
+func preflightCheck(ctx context.Context, digitalOceanService service.DigitalOcean,
+	additionalDroplets, autoscaleDroplets int) (*state.Account, error) {
+
+	account, err := digitalOceanService.GetAccount(ctx)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error GetAccount in preflightCheck")
+		return nil, err
+	}
+
+	if account.Status != "" && account.Status != accountStatusActive {
+		return nil, fmt.Errorf("%w: %s %s", ErrAccountNotActive, account.Status, account.StatusMessage)
+	}
+
+	if !account.WriteScope {
+		return nil, ErrTokenReadOnly
+	}
+
+	available := account.DropletLimit - account.DropletCount
+
+	if additionalDroplets > 0 && account.DropletLimit > 0 && additionalDroplets > available {
+		return nil, fmt.Errorf("%w: %d droplets are needed but only %d of %d are available",
+			ErrDropletLimit, additionalDroplets, available, account.DropletLimit)
+	}
+
+	if autoscaleDroplets > 0 && account.DropletLimit > 0 && additionalDroplets+autoscaleDroplets > available {
+		logging.FromContext(ctx).Warnf("Autoscaling may need %d more droplets than the %d available",
+			additionalDroplets+autoscaleDroplets-available, available)
+	}
+
+	return account, nil
+}
+
+// requiredDroplets is the number of droplets a node pool is created or
+// resized with.
+func requiredDroplets(nodePool state.NodePool) int {
+	return nodePool.Count
+}
+
+// autoscaleDroplets is how many droplets autoscaling may add to a node pool
+// on top of its count.
+func autoscaleDroplets(nodePool state.NodePool) int {
+	if nodePool.AutoScale != nil && *nodePool.AutoScale && nodePool.MaxNodes > nodePool.Count {
+		return nodePool.MaxNodes - nodePool.Count
+	}
+
+	return 0
+}
+
+// additionalDroplets is how many droplets an update of the primary node pool
+// adds to the cluster, and how many more autoscaling may add on top.
+func additionalDroplets(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster, nodePoolState *state.NodePool) (int, int, error) {
+
+	if nodePoolState == nil {
+		return 0, 0, nil
+	}
+
+	nodePools, err := digitalOceanService.ListNodePools(ctx, clusterState.ClusterID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error ListNodePools in additionalDroplets")
+		return 0, 0, err
+	}
+
+	for _, nodePool := range nodePools {
+		if nodePool.ID == clusterState.NodePoolID {
+			return requiredDroplets(*nodePoolState) - requiredDroplets(nodePool),
+				autoscaleDroplets(*nodePoolState), nil
+		}
+	}
+
+	return requiredDroplets(*nodePoolState), autoscaleDroplets(*nodePoolState), nil
+}
@@ -0,0 +1,172 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func accountMock(account *state.Account, err error) *DigitalOceanMock {
+	digitalOceanMock := &DigitalOceanMock{
+		getAccountMock: func(_ context.Context) (*state.Account, error) {
+			return account, err
+		},
+	}
+
+	digitalOceanMock.On("GetAccount", mock.Anything)
+
+	return digitalOceanMock
+}
+
+func TestPreflightCheck(t *testing.T) {
+	digitalOceanMock := accountMock(&state.Account{Status: "active", DropletLimit: 10, DropletCount: 5, WriteScope: true}, nil)
+
+	_, err := preflightCheck(context.TODO(), digitalOceanMock, 5, 0)
+
+	assert.NoError(t, err, "Not error with enough droplets")
+}
+
+func TestPreflightCheckUnauthorized(t *testing.T) {
+	unauthorized := &service.Error{Kind: service.ErrorKindUnauthorized, Operation: "get account"}
+	digitalOceanMock := accountMock(nil, unauthorized)
+
+	_, err := preflightCheck(context.TODO(), digitalOceanMock, 0, 0)
+
+	assert.True(t, service.IsUnauthorized(err), "Unauthorized token")
+}
+
+func TestPreflightCheckAccountLocked(t *testing.T) {
+	digitalOceanMock := accountMock(&state.Account{Status: "locked", StatusMessage: "billing"}, nil)
+
+	_, err := preflightCheck(context.TODO(), digitalOceanMock, 0, 0)
+
+	assert.True(t, errors.Is(err, ErrAccountNotActive), "Locked account")
+}
+
+func TestPreflightCheckReadOnlyToken(t *testing.T) {
+	digitalOceanMock := accountMock(&state.Account{Status: "active", DropletLimit: 10}, nil)
+
+	_, err := preflightCheck(context.TODO(), digitalOceanMock, 0, 0)
+
+	assert.True(t, errors.Is(err, ErrTokenReadOnly), "Read only token")
+}
+
+func TestPreflightCheckAutoscaleOnlyWarns(t *testing.T) {
+	digitalOceanMock := accountMock(&state.Account{Status: "active", DropletLimit: 10, DropletCount: 5,
+		WriteScope: true}, nil)
+
+	_, err := preflightCheck(context.TODO(), digitalOceanMock, 3, 5)
+
+	assert.NoError(t, err, "Autoscale beyond the droplet limit only warned about")
+}
+
+func TestPreflightCheckDropletLimit(t *testing.T) {
+	digitalOceanMock := accountMock(&state.Account{Status: "active", DropletLimit: 10, DropletCount: 8, WriteScope: true}, nil)
+
+	_, err := preflightCheck(context.TODO(), digitalOceanMock, 3, 0)
+
+	assert.True(t, errors.Is(err, ErrDropletLimit), "Droplet limit exceeded")
+	assert.Contains(t, err.Error(), "3 droplets are needed but only 2 of 10 are available")
+}
+
+func TestRequiredDroplets(t *testing.T) {
+	autoScale := true
+
+	assert.Equal(t, 3, requiredDroplets(state.NodePool{Count: 3}), "Fixed pool")
+	assert.Equal(t, 3, requiredDroplets(state.NodePool{Count: 3, AutoScale: &autoScale, MaxNodes: 8}),
+		"Autoscale pool counted at its count")
+	assert.Equal(t, 0, autoscaleDroplets(state.NodePool{Count: 3, MaxNodes: 8}), "Fixed pool does not autoscale")
+	assert.Equal(t, 5, autoscaleDroplets(state.NodePool{Count: 3, AutoScale: &autoScale, MaxNodes: 8}),
+		"Autoscale pool may grow")
+}
+
+func TestAdditionalDroplets(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd", NodePoolID: "pool-1"}
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return []state.NodePool{{ID: "pool-2", Count: 4}, {ID: "pool-1", Count: 3}}, nil
+		},
+	}
+
+	digitalOceanMock.On("ListNodePools", mock.Anything, "abcd")
+
+	additional, _, err := additionalDroplets(context.TODO(), digitalOceanMock, clusterState, &state.NodePool{Count: 5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, additional, "Two droplets added")
+
+	additional, _, err = additionalDroplets(context.TODO(), digitalOceanMock, clusterState, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, additional, "No node pool update")
+}
+
+func newScaleUpdateDriver(reportedCount int) (Driver, *DigitalOceanMock) {
+	clusterState := state.Cluster{Token: "token", ClusterID: "abcd", NodePoolID: "pool-1"}
+	existingPool := state.NodePool{ID: "pool-1", Name: "workers", Count: 3}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return clusterState, nil
+		},
+		buildStatesFromOptsMock: func(_ *types.DriverOptions) (state.Cluster, state.NodePool, error) {
+			return state.Cluster{}, state.NodePool{Count: reportedCount}, nil
+		},
+		buildNodeRecycleFromOptsMock: func(_ *types.DriverOptions) state.NodeRecycle {
+			return state.NodeRecycle{}
+		},
+	}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", mock.Anything)
+	stateBuilderMock.On("BuildStatesFromOpts", mock.Anything)
+	stateBuilderMock.On("BuildNodeRecycleFromOpts", mock.Anything)
+
+	digitalOceanMock := &DigitalOceanMock{
+		getAccountMock: activeAccount,
+		getNodePoolMock: func(_ context.Context, _, _ string) (*state.NodePool, error) {
+			nodePool := existingPool
+			return &nodePool, nil
+		},
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return []state.NodePool{existingPool}, nil
+		},
+		updateNodePoolMock: func(_ context.Context, _, _ string, _ state.NodePool) error {
+			return nil
+		},
+	}
+
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("GetNodePool", mock.Anything, "abcd", "pool-1")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "abcd")
+	digitalOceanMock.On("UpdateNodePool", mock.Anything, "abcd")
+
+	driver := Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(_ state.Credential, _ state.API) service.DigitalOcean { return digitalOceanMock },
+	}
+
+	return driver, digitalOceanMock
+}
+
+func TestDriverUpdateChecksAccount(t *testing.T) {
+	driver, digitalOceanMock := newScaleUpdateDriver(2)
+
+	_, err := driver.Update(context.TODO(), &types.ClusterInfo{}, &types.DriverOptions{})
+
+	assert.NoError(t, err, "Not error shrinking the node pool")
+	digitalOceanMock.AssertCalled(t, "GetAccount", mock.Anything)
+
+	driver, digitalOceanMock = newScaleUpdateDriver(5)
+
+	_, err = driver.Update(context.TODO(), &types.ClusterInfo{}, &types.DriverOptions{})
+
+	assert.NoError(t, err, "Not error growing the node pool")
+	digitalOceanMock.AssertCalled(t, "GetAccount", mock.Anything)
+}
@@ -0,0 +1,84 @@
+package doks
+
+import (
+	"context"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// resolveProject checks the project given by ID or name exists before any
+// resource is created, and keeps its ID in the state. Without a project the
+// cluster stays in the default project of the account.
+func resolveProject(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster) (state.Cluster, error) {
+
+	if clusterState.ProjectID == "" && clusterState.ProjectName == "" {
+		return clusterState, nil
+	}
+
+	project, err := digitalOceanService.FindProject(ctx, clusterState.ProjectID, clusterState.ProjectName)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error FindProject in resolveProject")
+		return clusterState, err
+	}
+
+	clusterState.ProjectID = project.ID
+
+	return clusterState, nil
+}
+
+// moveToProject assigns the cluster to the project of the update options
+// when it differs from the one in the state, and saves the state. Options
+// naming the stored project are not looked up again.
+func (driver Driver) moveToProject(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterInfo *types.ClusterInfo, clusterState state.Cluster, opts *types.DriverOptions) (state.Cluster, error) {
+
+	reportedState, _, err := driver.stateBuilder.BuildStatesFromOpts(opts)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildStatesFromOpts in moveToProject")
+		return clusterState, err
+	}
+
+	if (reportedState.ProjectName == "" || reportedState.ProjectName == clusterState.ProjectName) &&
+		(reportedState.ProjectID == "" || reportedState.ProjectID == clusterState.ProjectID) {
+		return clusterState, nil
+	}
+
+	reportedState, err = resolveProject(ctx, digitalOceanService, reportedState)
+
+	if err != nil {
+		return clusterState, err
+	}
+
+	moved := reportedState.ProjectID != clusterState.ProjectID
+
+	if moved {
+		err = digitalOceanService.AssignClusterToProject(ctx, reportedState.ProjectID, clusterState.ClusterID)
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Debug("Error AssignClusterToProject in moveToProject")
+			return clusterState, err
+		}
+	}
+
+	clusterState.ProjectID = reportedState.ProjectID
+	clusterState.ProjectName = reportedState.ProjectName
+
+	err = clusterState.Save(clusterInfo)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error save cluster state in moveToProject")
+		return clusterState, err
+	}
+
+	if moved {
+		logging.FromContext(ctx).WithField("project_id", clusterState.ProjectID).Info("DOKS cluster moved to project")
+	}
+
+	return clusterState, nil
+}
@@ -0,0 +1,181 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newProjectMock() *DigitalOceanMock {
+	return &DigitalOceanMock{
+		findProjectMock: func(_ context.Context, projectID, projectName string) (*state.Project, error) {
+			if projectID == "team-a-id" || projectName == "team-a" {
+				return &state.Project{ID: "team-a-id", Name: "team-a"}, nil
+			}
+
+			return nil, &service.Error{Kind: service.ErrorKindValidation, Message: "project not found"}
+		},
+		assignClusterToProjectMock: func(_ context.Context, _, _ string) error {
+			return nil
+		},
+	}
+}
+
+func newProjectDriver(reportedState state.Cluster, digitalOceanMock *DigitalOceanMock) Driver {
+	stateBuilderMock := &StateBuilderMock{
+		buildStatesFromOptsMock: func(_ *types.DriverOptions) (state.Cluster, state.NodePool, error) {
+			return reportedState, state.NodePool{Name: "workers", Size: "s-1vcpu-2gb", Count: 1}, nil
+		},
+	}
+
+	stateBuilderMock.On("BuildStatesFromOpts", mock.Anything)
+
+	return Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean { return digitalOceanMock },
+	}
+}
+
+func TestDriverCreateInProject(t *testing.T) {
+	digitalOceanMock := newProjectMock()
+	digitalOceanMock.getAccountMock = activeAccount
+	digitalOceanMock.createClusterMock = func(_ context.Context, _ state.Cluster, _ state.NodePool) (string, string, error) {
+		return "abcd", "pool", nil
+	}
+	digitalOceanMock.waitClusterCreated = func(_ context.Context, _ string) error {
+		return nil
+	}
+
+	driver := newProjectDriver(state.Cluster{Token: "token", Name: "cluster", ProjectName: "team-a"}, digitalOceanMock)
+
+	digitalOceanMock.On("FindProject", mock.Anything, "", "team-a")
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("CreateCluster", mock.Anything, mock.Anything, mock.Anything)
+	digitalOceanMock.On("AssignClusterToProject", mock.Anything, "team-a-id", "abcd")
+	digitalOceanMock.On("WaitClusterCreated", mock.Anything, "abcd")
+
+	info, err := driver.Create(context.TODO(), &types.DriverOptions{}, nil)
+
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err)
+
+	savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(info)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a-id", savedState.ProjectID, "Project ID saved")
+}
+
+func TestDriverCreateInUnknownProject(t *testing.T) {
+	digitalOceanMock := newProjectMock()
+	driver := newProjectDriver(state.Cluster{Token: "token", Name: "cluster", ProjectID: "unknown"}, digitalOceanMock)
+
+	digitalOceanMock.On("FindProject", mock.Anything, "unknown", "")
+
+	_, err := driver.Create(context.TODO(), &types.DriverOptions{}, nil)
+
+	digitalOceanMock.AssertExpectations(t)
+	digitalOceanMock.AssertNotCalled(t, "CreateCluster", mock.Anything, mock.Anything, mock.Anything)
+
+	assert.True(t, service.IsValidation(err), "Unknown project rejected before the cluster is created")
+}
+
+func TestDriverCreateKeepsStateWhenProjectAssignmentFails(t *testing.T) {
+	digitalOceanMock := newProjectMock()
+	digitalOceanMock.getAccountMock = activeAccount
+	digitalOceanMock.createClusterMock = func(_ context.Context, _ state.Cluster, _ state.NodePool) (string, string, error) {
+		return "abcd", "pool", nil
+	}
+	digitalOceanMock.assignClusterToProjectMock = func(_ context.Context, _, _ string) error {
+		return errors.New("project is full")
+	}
+
+	driver := newProjectDriver(state.Cluster{Token: "token", Name: "cluster", ProjectID: "team-a-id"}, digitalOceanMock)
+
+	digitalOceanMock.On("FindProject", mock.Anything, "team-a-id", "")
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("CreateCluster", mock.Anything, mock.Anything, mock.Anything)
+	digitalOceanMock.On("AssignClusterToProject", mock.Anything, "team-a-id", "abcd")
+
+	info, err := driver.Create(context.TODO(), &types.DriverOptions{}, nil)
+
+	assert.Error(t, err)
+	digitalOceanMock.AssertNotCalled(t, "WaitClusterCreated", mock.Anything, mock.Anything)
+
+	savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(info)
+
+	assert.NoError(t, err, "State returned with the error")
+	assert.Equal(t, "abcd", savedState.ClusterID, "Created cluster kept for Remove")
+}
+
+func TestMoveToProject(t *testing.T) {
+	digitalOceanMock := newProjectMock()
+	driver := newProjectDriver(state.Cluster{ProjectName: "team-a"}, digitalOceanMock)
+	clusterInfo := &types.ClusterInfo{}
+
+	digitalOceanMock.On("FindProject", mock.Anything, "", "team-a")
+	digitalOceanMock.On("AssignClusterToProject", mock.Anything, "team-a-id", "abcd")
+
+	clusterState, err := driver.moveToProject(context.TODO(), digitalOceanMock, clusterInfo,
+		state.Cluster{ClusterID: "abcd", Token: "token"}, &types.DriverOptions{})
+
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a-id", clusterState.ProjectID, "Project ID updated")
+
+	savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a-id", savedState.ProjectID, "Project ID saved")
+}
+
+func TestMoveToSameProject(t *testing.T) {
+	digitalOceanMock := newProjectMock()
+	driver := newProjectDriver(state.Cluster{ProjectID: "team-a-id"}, digitalOceanMock)
+
+	clusterState, err := driver.moveToProject(context.TODO(), digitalOceanMock, &types.ClusterInfo{},
+		state.Cluster{ClusterID: "abcd", ProjectID: "team-a-id"}, &types.DriverOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a-id", clusterState.ProjectID)
+	digitalOceanMock.AssertNotCalled(t, "AssignClusterToProject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMoveToStoredProjectName(t *testing.T) {
+	digitalOceanMock := newProjectMock()
+	driver := newProjectDriver(state.Cluster{ProjectName: "team-a"}, digitalOceanMock)
+
+	clusterState, err := driver.moveToProject(context.TODO(), digitalOceanMock, &types.ClusterInfo{},
+		state.Cluster{ClusterID: "abcd", ProjectID: "team-a-id", ProjectName: "team-a"}, &types.DriverOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a-id", clusterState.ProjectID)
+	digitalOceanMock.AssertNotCalled(t, "FindProject", mock.Anything, mock.Anything, mock.Anything)
+	digitalOceanMock.AssertNotCalled(t, "AssignClusterToProject", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMoveToProjectSavesName(t *testing.T) {
+	digitalOceanMock := newProjectMock()
+	driver := newProjectDriver(state.Cluster{ProjectName: "team-a"}, digitalOceanMock)
+	clusterInfo := &types.ClusterInfo{}
+
+	digitalOceanMock.On("FindProject", mock.Anything, "", "team-a")
+
+	_, err := driver.moveToProject(context.TODO(), digitalOceanMock, clusterInfo,
+		state.Cluster{ClusterID: "abcd", Token: "token", ProjectID: "team-a-id"}, &types.DriverOptions{})
+
+	assert.NoError(t, err)
+	digitalOceanMock.AssertNotCalled(t, "AssignClusterToProject", mock.Anything, mock.Anything, mock.Anything)
+
+	savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a", savedState.ProjectName, "Project name saved")
+}
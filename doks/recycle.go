@@ -0,0 +1,105 @@
+package doks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// recycleRequestedNodes runs each recycle request once. Rancher sends the
+// options, and the request they keep, on every update, so the request is
+// recorded in the state before any node is replaced. A failed recycle is not
+// run again on its own, and a request removed from the options is forgotten
+// so it can be given again later.
+func (driver *Driver) recycleRequestedNodes(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterInfo *types.ClusterInfo, clusterState state.Cluster, recycle state.NodeRecycle) (state.Cluster, error) {
+
+	key := recycle.Key()
+
+	if key == clusterState.NodeRecycle {
+		return clusterState, nil
+	}
+
+	clusterState.NodeRecycle = key
+
+	if err := clusterState.Save(clusterInfo); err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error save cluster state in recycleRequestedNodes")
+		return clusterState, err
+	}
+
+	if !recycle.IsRequested() {
+		return clusterState, nil
+	}
+
+	return clusterState, driver.recycleNodes(ctx, digitalOceanService, clusterState, recycle)
+}
+
+// recycleNodes replaces the requested worker nodes one at a time, waiting for
+// each replacement to be running before moving on to the next node.
+func (driver *Driver) recycleNodes(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster, recycle state.NodeRecycle) error {
+
+	nodes, err := digitalOceanService.ListNodes(ctx, clusterState.ClusterID, clusterState.NodePoolID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error list nodes in recycleNodes")
+		return err
+	}
+
+	nodesToRecycle, err := selectNodesToRecycle(nodes, recycle)
+
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodesToRecycle {
+		logging.FromContext(ctx).WithField("node", node.Name).Debug("Recycling node")
+
+		err = digitalOceanService.DeleteNode(ctx, clusterState.ClusterID, clusterState.NodePoolID,
+			node.ID, true, recycle.SkipDrain)
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("node", node.Name).Debug("Error delete node in recycleNodes")
+			return err
+		}
+
+		err = digitalOceanService.WaitNodeReplaced(ctx, clusterState.ClusterID, clusterState.NodePoolID, node.ID)
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("node", node.Name).Debug("Error wait node replaced in recycleNodes")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func selectNodesToRecycle(nodes []state.Node, recycle state.NodeRecycle) ([]state.Node, error) {
+	if recycle.AllNodes {
+		return nodes, nil
+	}
+
+	nodesByName := make(map[string]state.Node, len(nodes))
+
+	for _, node := range nodes {
+		nodesByName[node.Name] = node
+	}
+
+	selected := make([]state.Node, 0, len(recycle.NodeNames))
+
+	for _, name := range recycle.NodeNames {
+		node, ok := nodesByName[name]
+
+		if !ok {
+			return nil, fmt.Errorf("node %s not found in node pool", name)
+		}
+
+		selected = append(selected, node)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,169 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+var recycleNodes = []state.Node{
+	{ID: "node-1", Name: "pool-1-aaaa", State: "running"},
+	{ID: "node-2", Name: "pool-1-bbbb", State: "running"},
+	{ID: "node-3", Name: "pool-1-cccc", State: "error"},
+}
+
+func TestSelectNodesToRecycleAllNodes(t *testing.T) {
+	selected, err := selectNodesToRecycle(recycleNodes, state.NodeRecycle{AllNodes: true})
+
+	assert.NoError(t, err, "Not error in select all nodes")
+	assert.Equal(t, recycleNodes, selected, "All nodes selected")
+}
+
+func TestSelectNodesToRecycleByName(t *testing.T) {
+	recycle := state.NodeRecycle{NodeNames: []string{"pool-1-cccc", "pool-1-aaaa"}}
+
+	selected, err := selectNodesToRecycle(recycleNodes, recycle)
+
+	assert.NoError(t, err, "Not error in select nodes by name")
+	assert.Equal(t, []state.Node{recycleNodes[2], recycleNodes[0]}, selected, "Nodes selected in requested order")
+}
+
+func TestSelectNodesToRecycleUnknownName(t *testing.T) {
+	recycle := state.NodeRecycle{NodeNames: []string{"pool-1-zzzz"}}
+
+	_, err := selectNodesToRecycle(recycleNodes, recycle)
+
+	assert.Error(t, err, "Error in select unknown node")
+}
+
+func TestRecycleNodesOneAtATime(t *testing.T) {
+
+	clusterState := state.Cluster{
+		Token:      "a405b7bd3e0d6193f605368102a2deafe4067ed542c92166c6d772fe7e2df019",
+		ClusterID:  "abcd",
+		NodePoolID: "zzz",
+	}
+
+	var calls []string
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodesMock: func(_ context.Context, _, _ string) ([]state.Node, error) {
+			return recycleNodes, nil
+		},
+		deleteNodeMock: func(_ context.Context, _, _, nodeID string, _, _ bool) error {
+			calls = append(calls, "delete "+nodeID)
+			return nil
+		},
+		waitNodeReplacedMock: func(_ context.Context, _, _, nodeID string) error {
+			calls = append(calls, "wait "+nodeID)
+			return nil
+		},
+	}
+
+	ctx := context.TODO()
+	recycle := state.NodeRecycle{NodeNames: []string{"pool-1-aaaa", "pool-1-cccc"}, SkipDrain: true}
+
+	digitalOceanMock.On("ListNodes", ctx, "abcd", "zzz").Return(recycleNodes, nil)
+	digitalOceanMock.On("DeleteNode", ctx, "abcd", "zzz", "node-1", true, true).Return(nil)
+	digitalOceanMock.On("DeleteNode", ctx, "abcd", "zzz", "node-3", true, true).Return(nil)
+	digitalOceanMock.On("WaitNodeReplaced", ctx, "abcd", "zzz", "node-1").Return(nil)
+	digitalOceanMock.On("WaitNodeReplaced", ctx, "abcd", "zzz", "node-3").Return(nil)
+
+	driver := Driver{}
+
+	err := driver.recycleNodes(ctx, digitalOceanMock, clusterState, recycle)
+
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err, "Not error in recycle nodes")
+	assert.Equal(t, []string{"delete node-1", "wait node-1", "delete node-3", "wait node-3"}, calls,
+		"Nodes recycled one at a time")
+}
+
+func TestRecycleNodesStopsOnWaitError(t *testing.T) {
+
+	clusterState := state.Cluster{ClusterID: "abcd", NodePoolID: "zzz"}
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodesMock: func(_ context.Context, _, _ string) ([]state.Node, error) {
+			return recycleNodes, nil
+		},
+		deleteNodeMock: func(_ context.Context, _, _, _ string, _, _ bool) error {
+			return nil
+		},
+		waitNodeReplacedMock: func(_ context.Context, _, _, _ string) error {
+			return errors.New("error in wait node replaced")
+		},
+	}
+
+	ctx := context.TODO()
+
+	digitalOceanMock.On("ListNodes", ctx, "abcd", "zzz").Return(recycleNodes, nil)
+	digitalOceanMock.On("DeleteNode", ctx, "abcd", "zzz", "node-1", true, false).Return(nil)
+	digitalOceanMock.On("WaitNodeReplaced", ctx, "abcd", "zzz", "node-1").Return(nil)
+
+	driver := Driver{}
+
+	err := driver.recycleNodes(ctx, digitalOceanMock, clusterState, state.NodeRecycle{AllNodes: true})
+
+	digitalOceanMock.AssertExpectations(t)
+	digitalOceanMock.AssertNumberOfCalls(t, "DeleteNode", 1)
+
+	assert.Error(t, err, "Error in recycle nodes")
+}
+
+func TestRecycleRequestedNodesOnce(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd", NodePoolID: "zzz"}
+	clusterInfo := &types.ClusterInfo{}
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodesMock: func(_ context.Context, _, _ string) ([]state.Node, error) {
+			return recycleNodes[:1], nil
+		},
+		deleteNodeMock: func(_ context.Context, _, _, _ string, _, _ bool) error {
+			return nil
+		},
+		waitNodeReplacedMock: func(_ context.Context, _, _, _ string) error {
+			return nil
+		},
+	}
+
+	ctx := context.TODO()
+	recycle := state.NodeRecycle{AllNodes: true}
+
+	digitalOceanMock.On("ListNodes", ctx, "abcd", "zzz")
+	digitalOceanMock.On("DeleteNode", ctx, "abcd", "zzz", "node-1", true, false)
+	digitalOceanMock.On("WaitNodeReplaced", ctx, "abcd", "zzz", "node-1")
+
+	driver := Driver{}
+
+	clusterState, err := driver.recycleRequestedNodes(ctx, digitalOceanMock, clusterInfo, clusterState, recycle)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "all", clusterState.NodeRecycle, "Request recorded")
+
+	savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "all", savedState.NodeRecycle, "Request saved")
+
+	_, err = driver.recycleRequestedNodes(ctx, digitalOceanMock, clusterInfo, savedState, recycle)
+
+	assert.NoError(t, err)
+	digitalOceanMock.AssertNumberOfCalls(t, "DeleteNode", 1)
+
+	clusterState, err = driver.recycleRequestedNodes(ctx, digitalOceanMock, clusterInfo, savedState,
+		state.NodeRecycle{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, clusterState.NodeRecycle, "Request forgotten once removed from the options")
+
+	_, err = driver.recycleRequestedNodes(ctx, digitalOceanMock, clusterInfo, clusterState, recycle)
+
+	assert.NoError(t, err)
+	digitalOceanMock.AssertNumberOfCalls(t, "DeleteNode", 2)
+}
@@ -0,0 +1,200 @@
+package doks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+const (
+	nodeStateProvisioning = "provisioning"
+	nodeStateError        = "error"
+	nodeStateDraining     = "draining"
+	nodeStateDeleting     = "deleting"
+
+	// nodeProvisioningTimeout is how long a node may stay provisioning before
+	// auto-repair considers it stuck.
+	nodeProvisioningTimeout = 20 * time.Minute
+
+	maxRepairHistory = 50
+
+	// backgroundRepairTimeout bounds a repair started by GetClusterSize,
+	// which does not wait for it.
+	backgroundRepairTimeout = 5 * time.Minute
+)
+
+// backgroundRepairs holds the IDs of the clusters being repaired in the
+// background, so GetClusterSize never starts a second repair of a cluster.
+var backgroundRepairs sync.Map
+
+// autoRepairNodes runs repairNodes when auto-repair is enabled for the cluster.
+// PostCheck and Update call it, and Rancher saves the repair history with
+// their cluster info. Repair is best effort, so failures are logged instead
+// of failing the caller.
+func (driver *Driver) autoRepairNodes(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster, clusterInfo *types.ClusterInfo) {
+
+	if clusterState.AutoRepair == nil || !*clusterState.AutoRepair {
+		return
+	}
+
+	err := driver.repairNodes(ctx, digitalOceanService, clusterState, clusterInfo)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("Error in auto repair of cluster")
+	}
+}
+
+// autoRepairNodesInBackground runs autoRepairNodes without holding up
+// GetClusterSize, which Rancher polls. It is skipped while an earlier one runs
+// for the cluster. Rancher does not save the cluster info of GetClusterSize, so
+// the repair works on a copy of the metadata and its history is only logged;
+// the nodes being replaced keep the pool from being repaired twice at once.
+// The returned channel is closed once the repair ends, nil when none started.
+func (driver *Driver) autoRepairNodesInBackground(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster, clusterInfo *types.ClusterInfo) chan struct{} {
+
+	if clusterState.AutoRepair == nil || !*clusterState.AutoRepair {
+		return nil
+	}
+
+	if _, running := backgroundRepairs.LoadOrStore(clusterState.ClusterID, true); running {
+		logging.FromContext(ctx).Debug("Auto repair of cluster already running")
+		return nil
+	}
+
+	metadata := make(map[string]string, len(clusterInfo.Metadata))
+
+	for key, value := range clusterInfo.Metadata {
+		metadata[key] = value
+	}
+
+	repairCtx, cancel := context.WithTimeout(logging.WithFields(context.Background(), logging.FromContext(ctx).Data),
+		backgroundRepairTimeout)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer backgroundRepairs.Delete(clusterState.ClusterID)
+		defer cancel()
+
+		driver.autoRepairNodes(repairCtx, digitalOceanService, clusterState, &types.ClusterInfo{Metadata: metadata})
+	}()
+
+	return done
+}
+
+// repairNodes replaces at most one unhealthy node per node pool and records
+// every replacement in the repair history of the cluster metadata. Nodes are
+// drained before they are deleted, so workloads still running on them move.
+func (driver *Driver) repairNodes(ctx context.Context, digitalOceanService service.DigitalOcean,
+	clusterState state.Cluster, clusterInfo *types.ClusterInfo) error {
+
+	history, err := state.LoadRepairHistory(clusterInfo)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error load repair history in repairNodes")
+		return err
+	}
+
+	nodePools, err := digitalOceanService.ListNodePools(ctx, clusterState.ClusterID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error list node pools in repairNodes")
+		return err
+	}
+
+	now := time.Now()
+	repaired := false
+
+	for _, nodePool := range nodePools {
+		node, reason := selectNodeToRepair(nodePool, history.LastRepair(nodePool.ID), now)
+
+		if node == nil {
+			continue
+		}
+
+		logging.FromContext(ctx).WithField("node", node.Name).WithField(logging.FieldNodePoolID, nodePool.ID).Infof("Repairing node: %s", reason)
+
+		err = digitalOceanService.DeleteNode(ctx, clusterState.ClusterID, nodePool.ID, node.ID, true, false)
+
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("node", node.Name).Debug("Error delete node in repairNodes")
+			return err
+		}
+
+		history = append(history, state.RepairRecord{
+			NodePoolID: nodePool.ID,
+			NodeID:     node.ID,
+			NodeName:   node.Name,
+			Reason:     reason,
+			RepairedAt: now,
+		})
+		repaired = true
+	}
+
+	if !repaired {
+		return nil
+	}
+
+	if len(history) > maxRepairHistory {
+		history = history[len(history)-maxRepairHistory:]
+	}
+
+	return history.Save(clusterInfo)
+}
+
+// selectNodeToRepair returns the first unhealthy node of the pool together
+// with the reason it needs repair. No node is returned while the pool is still
+// recovering from a previous repair, so a pool is never repaired twice at once.
+func selectNodeToRepair(nodePool state.NodePool, lastRepair *state.RepairRecord,
+	now time.Time) (*state.Node, string) {
+
+	if isNodePoolRepairing(nodePool, lastRepair, now) {
+		return nil, ""
+	}
+
+	for i, node := range nodePool.Nodes {
+		switch {
+		case node.State == nodeStateError:
+			return &nodePool.Nodes[i], fmt.Sprintf("node in error state: %s", node.Message)
+		case node.State == nodeStateProvisioning && now.Sub(node.CreatedAt) > nodeProvisioningTimeout:
+			return &nodePool.Nodes[i], fmt.Sprintf("node provisioning since %s", node.CreatedAt.Format(time.RFC3339))
+		}
+	}
+
+	return nil, ""
+}
+
+func isNodePoolRepairing(nodePool state.NodePool, lastRepair *state.RepairRecord, now time.Time) bool {
+	if lastRepair == nil {
+		return false
+	}
+
+	if len(nodePool.Nodes) < nodePool.Count {
+		return true
+	}
+
+	for _, node := range nodePool.Nodes {
+		if node.ID == lastRepair.NodeID {
+			return true
+		}
+
+		switch node.State {
+		case nodeStateDraining, nodeStateDeleting:
+			return true
+		case nodeStateProvisioning:
+			if now.Sub(node.CreatedAt) <= nodeProvisioningTimeout {
+				return true
+			}
+		}
+	}
+
+	return false
+}
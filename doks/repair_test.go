@@ -0,0 +1,211 @@
+package doks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var repairNow = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSelectNodeToRepairErrorNode(t *testing.T) {
+	nodePool := state.NodePool{
+		ID:    "pool-1",
+		Count: 2,
+		Nodes: []state.Node{
+			{ID: "node-1", Name: "pool-1-aaaa", State: "running"},
+			{ID: "node-2", Name: "pool-1-bbbb", State: "error", Message: "droplet unreachable"},
+		},
+	}
+
+	node, reason := selectNodeToRepair(nodePool, nil, repairNow)
+
+	assert.Equal(t, "node-2", node.ID, "Error node selected")
+	assert.Contains(t, reason, "droplet unreachable", "Reason carries the status message")
+}
+
+func TestSelectNodeToRepairStuckProvisioning(t *testing.T) {
+	nodePool := state.NodePool{
+		ID:    "pool-1",
+		Count: 2,
+		Nodes: []state.Node{
+			{ID: "node-1", State: "provisioning", CreatedAt: repairNow.Add(-5 * time.Minute)},
+			{ID: "node-2", State: "provisioning", CreatedAt: repairNow.Add(-time.Hour)},
+		},
+	}
+
+	node, _ := selectNodeToRepair(nodePool, nil, repairNow)
+
+	assert.Equal(t, "node-2", node.ID, "Node provisioning for too long selected")
+}
+
+func TestSelectNodeToRepairHealthyPool(t *testing.T) {
+	nodePool := state.NodePool{
+		ID:    "pool-1",
+		Count: 1,
+		Nodes: []state.Node{{ID: "node-1", State: "running"}},
+	}
+
+	node, _ := selectNodeToRepair(nodePool, nil, repairNow)
+
+	assert.Nil(t, node, "No node selected in healthy pool")
+}
+
+func TestSelectNodeToRepairWaitsForPreviousRepair(t *testing.T) {
+	nodePool := state.NodePool{
+		ID:    "pool-1",
+		Count: 2,
+		Nodes: []state.Node{
+			{ID: "node-1", State: "error"},
+			{ID: "node-3", State: "provisioning", CreatedAt: repairNow.Add(-time.Minute)},
+		},
+	}
+
+	lastRepair := &state.RepairRecord{NodePoolID: "pool-1", NodeID: "node-2", RepairedAt: repairNow.Add(-time.Minute)}
+
+	node, _ := selectNodeToRepair(nodePool, lastRepair, repairNow)
+
+	assert.Nil(t, node, "No repair while the replacement is provisioning")
+
+	nodePool.Nodes[1].State = "running"
+
+	node, _ = selectNodeToRepair(nodePool, lastRepair, repairNow)
+
+	assert.Equal(t, "node-1", node.ID, "Repair resumes once the pool recovered")
+}
+
+func TestRepairNodesRecordsHistory(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd"}
+
+	nodePools := []state.NodePool{
+		{
+			ID:    "pool-1",
+			Count: 2,
+			Nodes: []state.Node{
+				{ID: "node-1", Name: "pool-1-aaaa", State: "error"},
+				{ID: "node-2", Name: "pool-1-bbbb", State: "error"},
+			},
+		},
+		{
+			ID:    "pool-2",
+			Count: 1,
+			Nodes: []state.Node{{ID: "node-3", Name: "pool-2-cccc", State: "running"}},
+		},
+	}
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return nodePools, nil
+		},
+		deleteNodeMock: func(_ context.Context, _, _, _ string, _, _ bool) error {
+			return nil
+		},
+	}
+
+	ctx := context.TODO()
+
+	digitalOceanMock.On("ListNodePools", ctx, "abcd").Return(nodePools, nil)
+	digitalOceanMock.On("DeleteNode", ctx, "abcd", "pool-1", "node-1", true, false).Return(nil)
+
+	clusterInfo := &types.ClusterInfo{}
+	driver := Driver{}
+
+	err := driver.repairNodes(ctx, digitalOceanMock, clusterState, clusterInfo)
+
+	digitalOceanMock.AssertExpectations(t)
+	digitalOceanMock.AssertNumberOfCalls(t, "DeleteNode", 1)
+
+	assert.NoError(t, err, "Not error in repair nodes")
+
+	history, err := state.LoadRepairHistory(clusterInfo)
+
+	assert.NoError(t, err, "Not error in load repair history")
+	assert.Len(t, history, 1, "One repair recorded")
+	assert.Equal(t, "pool-1-aaaa", history[0].NodeName, "Repaired node recorded")
+}
+
+func TestAutoRepairNodesDisabled(t *testing.T) {
+	digitalOceanMock := &DigitalOceanMock{}
+
+	driver := Driver{}
+
+	driver.autoRepairNodes(context.TODO(), digitalOceanMock, state.Cluster{}, &types.ClusterInfo{})
+
+	digitalOceanMock.AssertNotCalled(t, "ListNodePools")
+}
+
+func TestGetClusterSizeRepairsInBackground(t *testing.T) {
+	autoRepair := true
+	clusterState := state.Cluster{ClusterID: "size-repair", AutoRepair: &autoRepair}
+
+	nodePools := []state.NodePool{
+		{ID: "pool-1", Count: 1, Nodes: []state.Node{{ID: "node-1", State: "error"}}},
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return clusterState, nil
+		},
+	}
+
+	release := make(chan struct{})
+	deleted := make(chan string, 1)
+
+	digitalOceanMock := &DigitalOceanMock{
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return nodePools, nil
+		},
+		deleteNodeMock: func(_ context.Context, _, _, nodeID string, _, _ bool) error {
+			<-release
+			deleted <- nodeID
+			return nil
+		},
+	}
+
+	driver := Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(_ state.Credential, _ state.API) service.DigitalOcean { return digitalOceanMock },
+	}
+
+	clusterInfo := &types.ClusterInfo{}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo)
+	digitalOceanMock.On("ListNodePools", mock.Anything, "size-repair")
+	digitalOceanMock.On("DeleteNode", mock.Anything, "size-repair", "pool-1", "node-1", true, false)
+
+	count, err := driver.GetClusterSize(context.TODO(), clusterInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count.Count, "Size returned while the repair runs")
+
+	assert.Nil(t, driver.autoRepairNodesInBackground(context.TODO(), digitalOceanMock, clusterState, clusterInfo),
+		"Second repair of the cluster skipped")
+
+	close(release)
+
+	select {
+	case nodeID := <-deleted:
+		assert.Equal(t, "node-1", nodeID, "Node in error replaced")
+	case <-time.After(5 * time.Second):
+		t.Fatal("node not repaired in the background")
+	}
+
+	assert.Eventually(t, func() bool {
+		_, running := backgroundRepairs.Load("size-repair")
+		return !running
+	}, 5*time.Second, 10*time.Millisecond, "Guard released")
+	assert.Empty(t, clusterInfo.Metadata["repair-history"], "Cluster info of the caller left alone")
+}
+
+func TestAutoRepairNodesInBackgroundDisabled(t *testing.T) {
+	driver := Driver{}
+
+	assert.Nil(t, driver.autoRepairNodesInBackground(context.TODO(), &DigitalOceanMock{}, state.Cluster{},
+		&types.ClusterInfo{}), "Nothing started without auto-repair")
+}
@@ -0,0 +1,120 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+var ErrTokenOtherTeam = errors.New("the new token belongs to a different DigitalOcean team")
+
+// rotatedCredential applies the token or credential source reported in the
+// update options. It reports false when they are absent or unchanged.
+func rotatedCredential(clusterState, reportedState state.Cluster) (state.Cluster, bool) {
+	if reportedState.Token == "" && reportedState.Credential.Source == "" {
+		return clusterState, false
+	}
+
+	rotatedState := clusterState
+	rotatedState.Token = reportedState.Token
+	rotatedState.Credential = reportedState.Credential
+
+	if reflect.DeepEqual(rotatedState.DigitalOceanCredential(), clusterState.DigitalOceanCredential()) {
+		return clusterState, false
+	}
+
+	return rotatedState, true
+}
+
+// rotateCredential switches the cluster to a new token once it is proven to
+// reach the cluster, and saves it even when nothing else is updated.
+func (driver *Driver) rotateCredential(ctx context.Context, clusterInfo *types.ClusterInfo,
+	clusterState state.Cluster, opts *types.DriverOptions) (state.Cluster, error) {
+
+	reportedState, _, err := driver.stateBuilder.BuildStatesFromOpts(opts)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildStatesFromOpts in rotateCredential")
+		return clusterState, err
+	}
+
+	rotatedState, rotated := rotatedCredential(clusterState, reportedState)
+
+	if !rotated {
+		return clusterState, nil
+	}
+
+	err = rotatedState.DigitalOceanCredential().Validate()
+
+	if err != nil {
+		return clusterState, err
+	}
+
+	err = driver.verifyCredential(ctx, clusterState, &rotatedState)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error verifyCredential in rotateCredential")
+		return clusterState, err
+	}
+
+	err = rotatedState.Save(clusterInfo)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error save cluster state in rotateCredential")
+		return clusterState, err
+	}
+
+	logging.FromContext(ctx).Info("DOKS token rotated")
+
+	return rotatedState, nil
+}
+
+func (driver *Driver) verifyCredential(ctx context.Context, clusterState state.Cluster,
+	rotatedState *state.Cluster) error {
+
+	digitalOceanService := driver.digitalOceanFactory(rotatedState.DigitalOceanCredential(), rotatedState.API)
+
+	account, err := digitalOceanService.GetAccount(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	teamUUID := clusterState.TeamUUID
+
+	// states saved before the team was recorded are compared through the
+	// current token, when it still works
+	if teamUUID == "" {
+		currentService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
+		currentAccount, err := currentService.GetAccount(ctx)
+
+		if err == nil {
+			teamUUID = currentAccount.TeamUUID
+		}
+	}
+
+	if teamUUID != "" && account.TeamUUID != teamUUID {
+		return ErrTokenOtherTeam
+	}
+
+	_, err = digitalOceanService.GetKubernetesClusterVersion(ctx, clusterState.ClusterID)
+
+	if err != nil {
+		return fmt.Errorf("the new token cannot read cluster %s: %v", clusterState.ClusterID, err)
+	}
+
+	_, err = digitalOceanService.ListNodePools(ctx, clusterState.ClusterID)
+
+	if err != nil {
+		return fmt.Errorf("the new token cannot read the node pools of cluster %s: %v", clusterState.ClusterID, err)
+	}
+
+	rotatedState.TeamUUID = account.TeamUUID
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRotationMock(teamUUID string, clusterErr error) *DigitalOceanMock {
+	digitalOceanMock := &DigitalOceanMock{
+		getAccountMock: func(_ context.Context) (*state.Account, error) {
+			return &state.Account{Status: "active", TeamUUID: teamUUID, WriteScope: true}, nil
+		},
+		getKubernetesClusterVersionMock: func(_ context.Context, _ string) (string, error) {
+			return "1.17.5-do.0", clusterErr
+		},
+		listNodePoolsMock: func(_ context.Context, _ string) ([]state.NodePool, error) {
+			return []state.NodePool{{ID: "pool-1", Count: 3}}, nil
+		},
+	}
+
+	digitalOceanMock.On("GetAccount", mock.Anything)
+	digitalOceanMock.On("GetKubernetesClusterVersion", mock.Anything, "abcd")
+	digitalOceanMock.On("ListNodePools", mock.Anything, "abcd")
+
+	return digitalOceanMock
+}
+
+func newRotationDriver(reportedState state.Cluster, mocks map[string]*DigitalOceanMock) *Driver {
+	stateBuilderMock := &StateBuilderMock{
+		buildStatesFromOptsMock: func(_ *types.DriverOptions) (state.Cluster, state.NodePool, error) {
+			return reportedState, state.NodePool{}, nil
+		},
+	}
+
+	stateBuilderMock.On("BuildStatesFromOpts", mock.Anything)
+
+	return &Driver{
+		stateBuilder: stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean {
+			return mocks[credential.Token]
+		},
+	}
+}
+
+func TestRotatedCredential(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd", Token: "old-token"}
+
+	_, rotated := rotatedCredential(clusterState, state.Cluster{})
+	assert.False(t, rotated, "Nothing reported")
+
+	_, rotated = rotatedCredential(clusterState, state.Cluster{Token: "old-token"})
+	assert.False(t, rotated, "Same token reported")
+
+	rotatedState, rotated := rotatedCredential(clusterState, state.Cluster{Token: "new-token"})
+	assert.True(t, rotated, "New token reported")
+	assert.Equal(t, "new-token", rotatedState.Token, "Token rotated")
+	assert.Equal(t, "abcd", rotatedState.ClusterID, "Cluster kept")
+
+	rotatedState, rotated = rotatedCredential(clusterState, state.Cluster{
+		Credential: state.Credential{Source: state.CredentialSourceFile, File: "/run/secrets/doks"},
+	})
+	assert.True(t, rotated, "New credential source reported")
+	assert.Empty(t, rotatedState.Token, "Literal token dropped")
+}
+
+func TestRotateCredentialSavesWithoutOtherChanges(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd", Token: "old-token", TeamUUID: "team-1"}
+	clusterInfo := &types.ClusterInfo{}
+
+	driver := newRotationDriver(state.Cluster{Token: "new-token"}, map[string]*DigitalOceanMock{
+		"new-token": newRotationMock("team-1", nil),
+	})
+
+	rotatedState, err := driver.rotateCredential(context.TODO(), clusterInfo, clusterState, &types.DriverOptions{})
+
+	assert.NoError(t, err, "Not error rotating token")
+	assert.Equal(t, "new-token", rotatedState.Token, "Token rotated")
+
+	savedState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new-token", savedState.Token, "Rotated token saved")
+	assert.Equal(t, "team-1", savedState.TeamUUID, "Team kept")
+}
+
+func TestRotateCredentialOtherTeam(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd", Token: "old-token", TeamUUID: "team-1"}
+	clusterInfo := &types.ClusterInfo{}
+
+	driver := newRotationDriver(state.Cluster{Token: "new-token"}, map[string]*DigitalOceanMock{
+		"new-token": newRotationMock("team-2", nil),
+	})
+
+	_, err := driver.rotateCredential(context.TODO(), clusterInfo, clusterState, &types.DriverOptions{})
+
+	assert.Equal(t, ErrTokenOtherTeam, err, "Token of another team refused")
+	assert.Empty(t, clusterInfo.Metadata, "State not saved")
+}
+
+func TestRotateCredentialLegacyStateComparesCurrentTeam(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd", Token: "old-token"}
+
+	driver := newRotationDriver(state.Cluster{Token: "new-token"}, map[string]*DigitalOceanMock{
+		"old-token": newRotationMock("team-1", nil),
+		"new-token": newRotationMock("team-2", nil),
+	})
+
+	_, err := driver.rotateCredential(context.TODO(), &types.ClusterInfo{}, clusterState, &types.DriverOptions{})
+
+	assert.Equal(t, ErrTokenOtherTeam, err, "Token of another team refused")
+}
+
+func TestRotateCredentialCannotReadCluster(t *testing.T) {
+	clusterState := state.Cluster{ClusterID: "abcd", Token: "old-token", TeamUUID: "team-1"}
+	clusterInfo := &types.ClusterInfo{}
+
+	driver := newRotationDriver(state.Cluster{Token: "new-token"}, map[string]*DigitalOceanMock{
+		"new-token": newRotationMock("team-1", errors.New("not found")),
+	})
+
+	_, err := driver.rotateCredential(context.TODO(), clusterInfo, clusterState, &types.DriverOptions{})
+
+	assert.Error(t, err, "Token that cannot read the cluster refused")
+	assert.Empty(t, clusterInfo.Metadata, "State not saved")
+}
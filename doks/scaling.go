@@ -0,0 +1,209 @@
+package doks
+
+import (
+	"fmt"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+const (
+	// ScalingPolicyPrimary resizes the primary node pool, the others only take
+	// what falls outside its bounds.
+	ScalingPolicyPrimary = "primary"
+	// ScalingPolicyProportional keeps the current ratio between node pools.
+	ScalingPolicyProportional = "proportional"
+	// ScalingPolicyFill fills node pools up to their maximum, primary pool first.
+	ScalingPolicyFill = "fill"
+
+	// minFixedNodePoolCount is the smallest count accepted for a pool without autoscale.
+	minFixedNodePoolCount = 1
+)
+
+type nodePoolBounds struct {
+	min int
+	max int
+}
+
+func totalNodeCount(nodePools []state.NodePool) int {
+	total := 0
+
+	for _, nodePool := range nodePools {
+		total += nodePool.Count
+	}
+
+	return total
+}
+
+// distributeNodeCount spreads count nodes across the node pools following the
+// scaling policy. The returned counts are aligned with the pools ordered by
+// orderNodePools and always respect each pool's autoscale bounds.
+func distributeNodeCount(nodePools []state.NodePool, policy string, count int) ([]int, error) {
+
+	if len(nodePools) == 0 {
+		return nil, fmt.Errorf("cluster has no node pools")
+	}
+
+	bounds := make([]nodePoolBounds, len(nodePools))
+	minTotal, maxTotal := 0, 0
+
+	for i, nodePool := range nodePools {
+		bounds[i] = getNodePoolBounds(nodePool, count)
+		minTotal += bounds[i].min
+		maxTotal += bounds[i].max
+	}
+
+	if count < minTotal || count > maxTotal {
+		return nil, fmt.Errorf("count %d does not fit the node pools bounds, it must be between %d and %d",
+			count, minTotal, maxTotal)
+	}
+
+	var counts []int
+
+	switch policy {
+	case "", ScalingPolicyPrimary:
+		counts = primaryNodeCounts(nodePools, count)
+	case ScalingPolicyProportional:
+		counts = proportionalNodeCounts(nodePools, count)
+	case ScalingPolicyFill:
+		counts = fillNodeCounts(bounds, count)
+	default:
+		return nil, fmt.Errorf("unknown scaling policy %s", policy)
+	}
+
+	return rebalanceNodeCounts(counts, bounds, count), nil
+}
+
+// orderNodePools returns the node pools with the primary pool first. A
+// primary pool missing from the cluster is reported, so another pool is never
+// resized in its place.
+func orderNodePools(nodePools []state.NodePool, primaryNodePoolID string) ([]state.NodePool, error) {
+	ordered := make([]state.NodePool, 0, len(nodePools))
+
+	for _, nodePool := range nodePools {
+		if nodePool.ID == primaryNodePoolID {
+			ordered = append(ordered, nodePool)
+		}
+	}
+
+	if len(ordered) == 0 {
+		return nil, service.NewValidationError("order node pools",
+			fmt.Sprintf("primary node pool %s not found in the cluster", primaryNodePoolID))
+	}
+
+	for _, nodePool := range nodePools {
+		if nodePool.ID != primaryNodePoolID {
+			ordered = append(ordered, nodePool)
+		}
+	}
+
+	return ordered, nil
+}
+
+func getNodePoolBounds(nodePool state.NodePool, count int) nodePoolBounds {
+	if nodePool.AutoScale != nil && *nodePool.AutoScale {
+		return nodePoolBounds{min: nodePool.MinNodes, max: nodePool.MaxNodes}
+	}
+
+	return nodePoolBounds{min: minFixedNodePoolCount, max: count}
+}
+
+// primaryNodeCounts resizes the primary node pool and keeps the others. The
+// rebalance spills what the primary pool cannot take within its bounds over
+// to the other pools.
+func primaryNodeCounts(nodePools []state.NodePool, count int) []int {
+	counts := make([]int, len(nodePools))
+	counts[0] = count
+
+	for i := 1; i < len(nodePools); i++ {
+		counts[i] = nodePools[i].Count
+		counts[0] -= nodePools[i].Count
+	}
+
+	return counts
+}
+
+func proportionalNodeCounts(nodePools []state.NodePool, count int) []int {
+	counts := make([]int, len(nodePools))
+	total := totalNodeCount(nodePools)
+	assigned := 0
+
+	for i, nodePool := range nodePools {
+		if total == 0 {
+			counts[i] = count / len(nodePools)
+		} else {
+			counts[i] = count * nodePool.Count / total
+		}
+		assigned += counts[i]
+	}
+
+	for i := 0; assigned < count; i = (i + 1) % len(counts) {
+		counts[i]++
+		assigned++
+	}
+
+	return counts
+}
+
+func fillNodeCounts(bounds []nodePoolBounds, count int) []int {
+	counts := make([]int, len(bounds))
+	remaining := count
+
+	for i, bound := range bounds {
+		counts[i] = bound.min
+		remaining -= bound.min
+	}
+
+	for i, bound := range bounds {
+		increase := bound.max - counts[i]
+
+		if increase > remaining {
+			increase = remaining
+		}
+
+		counts[i] += increase
+		remaining -= increase
+	}
+
+	return counts
+}
+
+// rebalanceNodeCounts clamps every count to its pool bounds and moves the
+// difference to the pools that still have room, primary pool first.
+func rebalanceNodeCounts(counts []int, bounds []nodePoolBounds, count int) []int {
+	diff := count
+
+	for i, bound := range bounds {
+		if counts[i] < bound.min {
+			counts[i] = bound.min
+		}
+
+		if counts[i] > bound.max {
+			counts[i] = bound.max
+		}
+
+		diff -= counts[i]
+	}
+
+	for i := range counts {
+		if diff > 0 {
+			increase := bounds[i].max - counts[i]
+			if increase > diff {
+				increase = diff
+			}
+			counts[i] += increase
+			diff -= increase
+		}
+
+		if diff < 0 {
+			decrease := counts[i] - bounds[i].min
+			if decrease > -diff {
+				decrease = -diff
+			}
+			counts[i] -= decrease
+			diff += decrease
+		}
+	}
+
+	return counts
+}
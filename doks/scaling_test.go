@@ -0,0 +1,82 @@
+package doks
+
+import (
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func scalingNodePools() []state.NodePool {
+	autoScale := true
+
+	return []state.NodePool{
+		{ID: "primary", Count: 2},
+		{ID: "autoscaled", Count: 2, AutoScale: &autoScale, MinNodes: 1, MaxNodes: 3},
+		{ID: "secondary", Count: 4},
+	}
+}
+
+func TestOrderNodePoolsPrimaryFirst(t *testing.T) {
+	nodePools, err := orderNodePools(scalingNodePools(), "secondary")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "secondary", nodePools[0].ID, "Primary node pool first")
+	assert.Equal(t, "primary", nodePools[1].ID, "Other node pools keep their order")
+	assert.Equal(t, "autoscaled", nodePools[2].ID, "Other node pools keep their order")
+}
+
+func TestDistributeNodeCountPrimary(t *testing.T) {
+	counts, err := distributeNodeCount(scalingNodePools(), ScalingPolicyPrimary, 10)
+
+	assert.NoError(t, err, "Not error in distribute node count")
+	assert.Equal(t, []int{4, 2, 4}, counts, "Only the primary node pool changes")
+}
+
+func TestOrderNodePoolsMissingPrimary(t *testing.T) {
+	nodePools, err := orderNodePools(scalingNodePools(), "deleted")
+
+	assert.Nil(t, nodePools)
+	assert.True(t, service.IsValidation(err), "Missing primary node pool reported")
+}
+
+func TestDistributeNodeCountPrimaryBelowMinimum(t *testing.T) {
+	counts, err := distributeNodeCount(scalingNodePools(), ScalingPolicyPrimary, 4)
+
+	assert.NoError(t, err, "Not error in distribute node count")
+	assert.Equal(t, []int{1, 1, 2}, counts, "Remainder spilled over to the other node pools")
+}
+
+func TestDistributeNodeCountProportional(t *testing.T) {
+	counts, err := distributeNodeCount(scalingNodePools(), ScalingPolicyProportional, 12)
+
+	assert.NoError(t, err, "Not error in distribute node count")
+	assert.Equal(t, []int{3, 3, 6}, counts, "Ratio between node pools kept")
+}
+
+func TestDistributeNodeCountProportionalRespectsMax(t *testing.T) {
+	counts, err := distributeNodeCount(scalingNodePools(), ScalingPolicyProportional, 16)
+
+	assert.NoError(t, err, "Not error in distribute node count")
+	assert.Equal(t, []int{5, 3, 8}, counts, "Autoscale node pool capped at max nodes")
+}
+
+func TestDistributeNodeCountFill(t *testing.T) {
+	counts, err := distributeNodeCount(scalingNodePools(), ScalingPolicyFill, 6)
+
+	assert.NoError(t, err, "Not error in distribute node count")
+	assert.Equal(t, []int{4, 1, 1}, counts, "Node pools filled in order")
+}
+
+func TestDistributeNodeCountOutOfBounds(t *testing.T) {
+	_, err := distributeNodeCount(scalingNodePools(), ScalingPolicyPrimary, 2)
+
+	assert.Error(t, err, "Error when count is below the node pools minimum")
+}
+
+func TestDistributeNodeCountUnknownPolicy(t *testing.T) {
+	_, err := distributeNodeCount(scalingNodePools(), "random", 6)
+
+	assert.Error(t, err, "Error with unknown scaling policy")
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// scopeProbeTag is not a valid tag name, DigitalOcean only accepts letters,
+// numbers, colons, dashes and underscores, so no tag by that name can exist.
+const scopeProbeTag = "doks.scope-probe"
+
+// account mirrors the account endpoint, including the team that is not
+// exposed by godo.
+type account struct {
+	godo.Account
+	Team *struct {
+		UUID string `json:"uuid"`
+		Name string `json:"name"`
+	} `json:"team,omitempty"`
+}
+
+func (do *digitalOceanImpl) GetAccount(ctx context.Context) (*state.Account, error) {
+	request, err := do.client.NewRequest(ctx, http.MethodGet, "v2/account", nil)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the account")
+	}
+
+	root := struct {
+		Account *account `json:"account"`
+	}{}
+
+	_, err = do.client.Do(ctx, request, &root)
+
+	if err != nil {
+		return nil, newError("get account", err)
+	}
+
+	accountState := &state.Account{
+		UUID:          root.Account.UUID,
+		Email:         root.Account.Email,
+		Status:        root.Account.Status,
+		StatusMessage: root.Account.StatusMessage,
+		DropletLimit:  root.Account.DropletLimit,
+	}
+
+	if root.Account.Team != nil {
+		accountState.TeamUUID = root.Account.Team.UUID
+		accountState.TeamName = root.Account.Team.Name
+	}
+
+	_, response, err := do.client.Droplets.List(ctx, &godo.ListOptions{Page: 1, PerPage: 1})
+
+	if err != nil {
+		return nil, newError("count droplets", err)
+	}
+
+	if response.Meta != nil {
+		accountState.DropletCount = response.Meta.Total
+	}
+
+	accountState.WriteScope, err = do.hasWriteScope(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return accountState, nil
+}
+
+// hasWriteScope probes the token by deleting a tag that cannot exist.
+// DigitalOcean answers 403 to read only tokens and 404 or 422 to the others,
+// so the account is never changed.
+func (do *digitalOceanImpl) hasWriteScope(ctx context.Context) (bool, error) {
+	_, err := do.client.Tags.Delete(ctx, scopeProbeTag)
+
+	if err == nil {
+		return true, nil
+	}
+
+	err = newError("check token scope", err)
+
+	switch {
+	case IsForbidden(err):
+		return false, nil
+	case IsNotFound(err), IsValidation(err):
+		return true, nil
+	}
+
+	return false, err
+}
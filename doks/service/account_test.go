@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/helper"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAccountServer(t *testing.T, tagStatus int) (*httptest.Server, DigitalOcean) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v2/account", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer valid-token" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			writer.Write([]byte(`{"id":"unauthorized","message":"Unable to authenticate you"}`))
+			return
+		}
+
+		writer.Write([]byte(`{"account":{"droplet_limit":25,"email":"ops@example.com","uuid":"account-1",` +
+			`"status":"active","team":{"uuid":"team-1","name":"Platform"}}}`))
+	})
+
+	mux.HandleFunc("/v2/droplets", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(`{"droplets":[],"meta":{"total":7}}`))
+	})
+
+	mux.HandleFunc("/v2/tags/"+scopeProbeTag, func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, http.MethodDelete, request.Method, "Scope probed with a delete")
+		writer.WriteHeader(tagStatus)
+		writer.Write([]byte(`{"id":"error","message":"tag probe"}`))
+	})
+
+	server := httptest.NewServer(mux)
+
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "valid-token"}), helper.NewTimerSleeper(), ClientConfig{})
+	digitalOcean.(*digitalOceanImpl).client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return server, digitalOcean
+}
+
+func TestGetAccount(t *testing.T) {
+	server, digitalOcean := newAccountServer(t, http.StatusNotFound)
+	defer server.Close()
+
+	account, err := digitalOcean.GetAccount(context.TODO())
+
+	expectedAccount := &state.Account{
+		UUID:         "account-1",
+		Email:        "ops@example.com",
+		Status:       "active",
+		TeamUUID:     "team-1",
+		TeamName:     "Platform",
+		DropletLimit: 25,
+		DropletCount: 7,
+		WriteScope:   true,
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedAccount, account, "Account equals")
+}
+
+func TestGetAccountReadOnlyToken(t *testing.T) {
+	server, digitalOcean := newAccountServer(t, http.StatusForbidden)
+	defer server.Close()
+
+	account, err := digitalOcean.GetAccount(context.TODO())
+
+	assert.NoError(t, err)
+	assert.False(t, account.WriteScope, "Read only token")
+}
+
+func TestGetAccountUnauthorized(t *testing.T) {
+	server, _ := newAccountServer(t, http.StatusForbidden)
+	defer server.Close()
+
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "revoked"}), helper.NewTimerSleeper(), ClientConfig{})
+	digitalOcean.(*digitalOceanImpl).client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	_, err := digitalOcean.GetAccount(context.TODO())
+
+	assert.True(t, IsUnauthorized(err), "Revoked token")
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClusterServer(body string) (*httptest.Server, DigitalOcean) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(body))
+	}))
+
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "token"}), &sleeperStub{}, ClientConfig{})
+	digitalOcean.(*digitalOceanImpl).client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return server, digitalOcean
+}
+
+func TestGetCluster(t *testing.T) {
+	server, digitalOcean := newClusterServer(`{"kubernetes_cluster":{"id":"abcd","version":"1.17.5-do.0",` +
+		`"endpoint":"https://abcd.k8s.ondigitalocean.com","ipv4":"10.0.0.1",` +
+		`"status":{"state":"running","message":"Cluster is ready"},` +
+		`"created_at":"2020-06-01T10:00:00Z","updated_at":"2020-06-01T10:05:00Z"}}`)
+	defer server.Close()
+
+	clusterStatus, err := digitalOcean.GetCluster(context.TODO(), "abcd")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "running", clusterStatus.State, "State equals")
+	assert.Equal(t, "Cluster is ready", clusterStatus.Message, "Message equals")
+	assert.Equal(t, "do:kubernetes:abcd", clusterStatus.URN, "URN built from the id")
+	assert.Equal(t, "10.0.0.1", clusterStatus.IPv4, "IPv4 equals")
+	assert.Equal(t, "https://abcd.k8s.ondigitalocean.com", clusterStatus.Endpoint, "Endpoint equals")
+	assert.False(t, clusterStatus.CreatedAt.IsZero(), "Creation time read")
+}
+
+func TestWaitClusterCreatedReportsStatusMessage(t *testing.T) {
+	server, digitalOcean := newClusterServer(`{"kubernetes_cluster":{"id":"abcd",` +
+		`"status":{"state":"error","message":"droplet limit exceeded"}}}`)
+	defer server.Close()
+
+	err := digitalOcean.WaitClusterCreated(context.TODO(), "abcd")
+
+	assert.EqualError(t, err, "cluster abcd is in error state: droplet limit exceeded")
+}
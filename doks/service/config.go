@@ -0,0 +1,200 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// Environment of the driver process holding the client settings. Cluster
+// options override the timeout and the user agent suffix only.
+const (
+	// APIURLEnv points the driver at another DigitalOcean API endpoint, such
+	// as a fake server in integration tests.
+	APIURLEnv          = "DOKS_API_URL"
+	ProxyURLEnv        = "DOKS_PROXY_URL"
+	CABundleFileEnv    = "DOKS_CA_BUNDLE_FILE"
+	TimeoutEnv         = "DOKS_API_TIMEOUT"
+	UserAgentSuffixEnv = "DOKS_USER_AGENT_SUFFIX"
+)
+
+// ClientConfig tunes the godo clients built by the factory.
+type ClientConfig struct {
+	// BaseURL of the DigitalOcean API, godo's default when empty.
+	BaseURL string
+	// ProxyURL of the HTTP(S) proxy, HTTPS_PROXY and NO_PROXY are honoured
+	// when empty.
+	ProxyURL string
+	// CABundle holds PEM certificates trusted on top of the system ones.
+	CABundle string
+	// Timeout of every request, none when zero.
+	Timeout         time.Duration
+	UserAgentSuffix string
+}
+
+// ClientConfigFromEnv reads the client configuration from the environment.
+func ClientConfigFromEnv() (ClientConfig, error) {
+	config := ClientConfig{
+		BaseURL:         os.Getenv(APIURLEnv),
+		ProxyURL:        os.Getenv(ProxyURLEnv),
+		UserAgentSuffix: os.Getenv(UserAgentSuffixEnv),
+	}
+
+	if file := os.Getenv(CABundleFileEnv); file != "" {
+		bundle, err := ioutil.ReadFile(file)
+
+		if err != nil {
+			return config, fmt.Errorf("error reading the CA bundle in %s: %v", CABundleFileEnv, err)
+		}
+
+		config.CABundle = string(bundle)
+	}
+
+	if timeout := os.Getenv(TimeoutEnv); timeout != "" {
+		var err error
+
+		config.Timeout, err = parseTimeout(timeout)
+
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %v", TimeoutEnv, err)
+		}
+	}
+
+	return config, nil
+}
+
+// WithAPI overrides the configuration with the settings of a cluster.
+func (config ClientConfig) WithAPI(api state.API) (ClientConfig, error) {
+	if api.UserAgentSuffix != "" {
+		config.UserAgentSuffix = api.UserAgentSuffix
+	}
+
+	if api.Timeout != "" {
+		timeout, err := parseTimeout(api.Timeout)
+
+		if err != nil {
+			return config, fmt.Errorf("invalid API timeout: %v", err)
+		}
+
+		config.Timeout = timeout
+	}
+
+	return config, nil
+}
+
+// ValidateAPI checks the API settings of a cluster before they are used.
+func ValidateAPI(api state.API) error {
+	config, err := ClientConfig{}.WithAPI(api)
+
+	if err != nil {
+		return err
+	}
+
+	return config.Validate()
+}
+
+// Validate checks the configuration so mistakes are reported when the driver
+// starts or the cluster options are read, instead of on the first call.
+func (config ClientConfig) Validate() error {
+	if config.BaseURL != "" {
+		if _, err := config.baseURL(); err != nil {
+			return err
+		}
+	}
+
+	_, err := config.transport()
+
+	return err
+}
+
+// baseURL parses the base URL. godo resolves relative paths against it, so
+// it always ends with a slash.
+func (config ClientConfig) baseURL() (*url.URL, error) {
+	baseURL, err := parseURL("DigitalOcean API", config.BaseURL, "http", "https")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(baseURL.Path, "/") {
+		baseURL.Path += "/"
+	}
+
+	return baseURL, nil
+}
+
+// transport builds the transport of the requests, with the proxy and the
+// certificates of the configuration.
+func (config ClientConfig) transport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.ProxyURL != "" {
+		proxyURL, err := parseURL("proxy", config.ProxyURL, "http", "https", "socks5")
+
+		if err != nil {
+			return nil, err
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.CABundle != "" {
+		certPool, err := x509.SystemCertPool()
+
+		if err != nil {
+			certPool = x509.NewCertPool()
+		}
+
+		if !certPool.AppendCertsFromPEM([]byte(config.CABundle)) {
+			return nil, errors.New("no PEM certificate found in the CA bundle")
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: certPool}
+	}
+
+	return transport, nil
+}
+
+func parseURL(name, rawURL string, schemes ...string) (*url.URL, error) {
+	parsedURL, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s URL %q: %v", name, rawURL, err)
+	}
+
+	if parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid %s URL %q: an absolute URL is required", name, rawURL)
+	}
+
+	for _, scheme := range schemes {
+		if parsedURL.Scheme == scheme {
+			return parsedURL, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid %s URL %q: the scheme must be one of %s", name, rawURL,
+		strings.Join(schemes, ", "))
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout %s must be positive", value)
+	}
+
+	return timeout, nil
+}
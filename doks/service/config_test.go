@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTLSClusterServer() (*httptest.Server, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(`{"kubernetes_cluster":{"id":"abcd","status":{"state":"running"}}}`))
+	}))
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	return server, string(certificate)
+}
+
+func newConfiguredDigitalOcean(config ClientConfig) DigitalOcean {
+	return newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "token"}), &sleeperStub{}, config)
+}
+
+func TestClientConfigBaseURL(t *testing.T) {
+	baseURL, err := ClientConfig{BaseURL: "http://127.0.0.1:8080/api"}.baseURL()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8080/api/", baseURL.String(), "Trailing slash added")
+	assert.NoError(t, ClientConfig{}.Validate(), "Default URL is valid")
+	assert.Error(t, ClientConfig{BaseURL: "127.0.0.1:8080"}.Validate(), "Scheme required")
+	assert.Error(t, ClientConfig{BaseURL: "ftp://example.com"}.Validate(), "Only http and https")
+}
+
+func TestClientConfigFromEnv(t *testing.T) {
+	server, certificate := newTLSClusterServer()
+	server.Close()
+
+	bundle, err := ioutil.TempFile("", "ca-bundle")
+	assert.NoError(t, err)
+	defer os.Remove(bundle.Name())
+
+	bundle.WriteString(certificate)
+	bundle.Close()
+
+	environment := map[string]string{
+		APIURLEnv:          "http://127.0.0.1:8080",
+		ProxyURLEnv:        "http://proxy:3128",
+		CABundleFileEnv:    bundle.Name(),
+		TimeoutEnv:         "45s",
+		UserAgentSuffixEnv: "rancher/2.4",
+	}
+
+	for name, value := range environment {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	config, err := ClientConfigFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, ClientConfig{
+		BaseURL:         "http://127.0.0.1:8080",
+		ProxyURL:        "http://proxy:3128",
+		CABundle:        certificate,
+		Timeout:         45 * time.Second,
+		UserAgentSuffix: "rancher/2.4",
+	}, config, "Config read from the environment")
+	assert.NoError(t, config.Validate())
+
+	os.Setenv(TimeoutEnv, "soon")
+
+	_, err = ClientConfigFromEnv()
+	assert.Error(t, err, "Invalid timeout reported")
+}
+
+func TestClientConfigWithAPI(t *testing.T) {
+	config := ClientConfig{BaseURL: "http://127.0.0.1:8080", ProxyURL: "http://proxy:3128", Timeout: time.Minute,
+		UserAgentSuffix: "rancher"}
+
+	clusterConfig, err := config.WithAPI(state.API{Timeout: "10s"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ClientConfig{
+		BaseURL:         "http://127.0.0.1:8080",
+		ProxyURL:        "http://proxy:3128",
+		Timeout:         10 * time.Second,
+		UserAgentSuffix: "rancher",
+	}, clusterConfig, "Cluster settings override")
+}
+
+func TestValidateAPI(t *testing.T) {
+	assert.NoError(t, ValidateAPI(state.API{}), "Nothing set")
+	assert.NoError(t, ValidateAPI(state.API{Timeout: "30s", UserAgentSuffix: "rancher/2.4"}), "Valid settings")
+	assert.Error(t, ValidateAPI(state.API{Timeout: "-1s"}), "Negative timeout rejected")
+	assert.Error(t, ValidateAPI(state.API{Timeout: "30"}), "Timeout unit required")
+}
+
+func TestFactoryUsesBaseURL(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	factory := NewDigitalOceanFactoryWithConfig(ClientConfig{BaseURL: server.URL})
+
+	_, err := factory(state.Credential{Token: "token"}, state.API{}).GetAccount(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Len(t, server.RequestsTo(http.MethodGet, "/v2/account"), 1, "Account read from the fake")
+}
+
+func TestFactoryAppliesClusterAPI(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	factory := NewDigitalOceanFactoryWithConfig(ClientConfig{BaseURL: server.URL, UserAgentSuffix: "env"})
+	api := state.API{UserAgentSuffix: "rancher/2.4"}
+
+	_, err := factory(state.Credential{Token: "token"}, api).GetAccount(context.TODO())
+
+	assert.NoError(t, err)
+
+	userAgent := server.RequestsTo(http.MethodGet, "/v2/account")[0].Header.Get("User-Agent")
+
+	assert.True(t, strings.HasPrefix(userAgent, "godo/"), "godo user agent kept")
+	assert.True(t, strings.HasSuffix(userAgent, " rancher/2.4"), "Suffix appended")
+}
+
+func TestClientThroughProxy(t *testing.T) {
+	proxy := fake.NewServer()
+	defer proxy.Close()
+
+	digitalOcean := newConfiguredDigitalOcean(ClientConfig{BaseURL: "http://api.digitalocean.invalid",
+		ProxyURL: proxy.URL})
+
+	_, err := digitalOcean.GetAccount(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Len(t, proxy.RequestsTo(http.MethodGet, "/v2/account"), 1, "Request sent through the proxy")
+}
+
+func TestClientCABundle(t *testing.T) {
+	server, certificate := newTLSClusterServer()
+	defer server.Close()
+
+	_, err := newConfiguredDigitalOcean(ClientConfig{BaseURL: server.URL}).GetCluster(context.TODO(), "abcd")
+	assert.Error(t, err, "Unknown certificate authority rejected")
+
+	digitalOcean := newConfiguredDigitalOcean(ClientConfig{BaseURL: server.URL, CABundle: certificate})
+
+	clusterStatus, err := digitalOcean.GetCluster(context.TODO(), "abcd")
+
+	assert.NoError(t, err, "Certificate trusted through the bundle")
+	assert.Equal(t, "running", clusterStatus.State, "State equals")
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	digitalOcean := newConfiguredDigitalOcean(ClientConfig{BaseURL: server.URL, Timeout: 20 * time.Millisecond})
+
+	_, err := digitalOcean.GetCluster(context.TODO(), "abcd")
+
+	assert.True(t, IsTransient(err), "Timed out request is transient")
+}
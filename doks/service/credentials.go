@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Environment of the driver process limiting what the credential sources of a
+// cluster may read, so a cluster creator cannot reach other secrets of the
+// host.
+const (
+	// TokenEnvAllowlistEnv lists, separated by commas, the environment
+	// variables the env source may read on top of DIGITALOCEAN_ACCESS_TOKEN.
+	TokenEnvAllowlistEnv = "DOKS_TOKEN_ENV_ALLOWLIST"
+	// TokenFileDirsEnv lists, separated by commas, the directories the file
+	// source may read from. Without it the file source is refused.
+	TokenFileDirsEnv = "DOKS_TOKEN_FILE_DIRS"
+	// CloudCredentialNamespacesEnv lists, separated by commas, the namespaces
+	// the cloud-credential source may read on top of cattle-global-data.
+	CloudCredentialNamespacesEnv = "DOKS_CLOUD_CREDENTIAL_NAMESPACES"
+)
+
+const (
+	cloudCredentialNamespace = "cattle-global-data"
+	cloudCredentialTokenKey  = "digitaloceancredentialConfig-accessToken"
+
+	vaultAddressEnv     = "VAULT_ADDR"
+	vaultTokenEnv       = "VAULT_TOKEN"
+	vaultRequestTimeout = 10 * time.Second
+)
+
+// CredentialProvider resolves the DigitalOcean token every time it is used,
+// so rotated secrets are picked up without touching the cluster state.
+type CredentialProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// SecretGetter reads the data of a Kubernetes secret.
+type SecretGetter func(namespace, name string) (map[string][]byte, error)
+
+func NewCredentialProvider(credential state.Credential) (CredentialProvider, error) {
+	return newCredentialProvider(credential, inClusterSecretGetter, http.DefaultClient)
+}
+
+func newCredentialProvider(credential state.Credential, secretGetter SecretGetter,
+	httpClient *http.Client) (CredentialProvider, error) {
+
+	if err := credential.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch credential.Source {
+	case state.CredentialSourceEnv:
+		return &envProvider{name: credential.Env}, nil
+	case state.CredentialSourceFile:
+		return &fileProvider{path: credential.File}, nil
+	case state.CredentialSourceCloudCredential:
+		return &cloudCredentialProvider{id: credential.CloudCredentialID, secretGetter: secretGetter}, nil
+	case state.CredentialSourceVault:
+		return &vaultProvider{
+			path:       credential.VaultPath,
+			field:      credential.VaultField,
+			httpClient: httpClient,
+		}, nil
+	default:
+		return &literalProvider{token: credential.Token}, nil
+	}
+}
+
+type literalProvider struct {
+	token string
+}
+
+func (provider *literalProvider) Token(_ context.Context) (string, error) {
+	return provider.token, nil
+}
+
+type envProvider struct {
+	name string
+}
+
+func (provider *envProvider) Token(_ context.Context) (string, error) {
+	name := provider.name
+
+	if name == "" {
+		name = state.DefaultTokenEnv
+	}
+
+	if name != state.DefaultTokenEnv && !splitEnvList(os.Getenv(TokenEnvAllowlistEnv))[name] {
+		return "", fmt.Errorf("environment variable %s is not listed in %s", name, TokenEnvAllowlistEnv)
+	}
+
+	token := strings.TrimSpace(os.Getenv(name))
+
+	if token == "" {
+		return "", fmt.Errorf("environment variable %s is empty", name)
+	}
+
+	return token, nil
+}
+
+type fileProvider struct {
+	path string
+}
+
+func (provider *fileProvider) Token(_ context.Context) (string, error) {
+	path, err := allowedTokenFile(provider.path)
+
+	if err != nil {
+		return "", err
+	}
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read token file %s", provider.path)
+	}
+
+	token := strings.TrimSpace(string(content))
+
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", provider.path)
+	}
+
+	return token, nil
+}
+
+// allowedTokenFile resolves the links of the token file and checks it lies in
+// one of the directories of TokenFileDirsEnv.
+func allowedTokenFile(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read token file %s", path)
+	}
+
+	resolved, err = filepath.Abs(resolved)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read token file %s", path)
+	}
+
+	for dir := range splitEnvList(os.Getenv(TokenFileDirsEnv)) {
+		dir, err := filepath.EvalSymlinks(dir)
+
+		if err != nil {
+			continue
+		}
+
+		dir, err = filepath.Abs(dir)
+
+		if err != nil {
+			continue
+		}
+
+		relative, err := filepath.Rel(dir, resolved)
+
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("token file %s is not in a directory listed in %s", path, TokenFileDirsEnv)
+}
+
+// cloudCredentialProvider reads a DigitalOcean cloud credential created in
+// Rancher. Its id has the form cattle-global-data:cc-xxxxx. Only Rancher's own
+// cloud credential namespace and the ones of CloudCredentialNamespacesEnv are
+// read, and only secrets holding a DigitalOcean access token are used.
+type cloudCredentialProvider struct {
+	id           string
+	secretGetter SecretGetter
+}
+
+func (provider *cloudCredentialProvider) Token(_ context.Context) (string, error) {
+	namespace, name := cloudCredentialNamespace, provider.id
+
+	if parts := strings.SplitN(provider.id, ":", 2); len(parts) == 2 {
+		namespace, name = parts[0], parts[1]
+	}
+
+	if namespace != cloudCredentialNamespace && !splitEnvList(os.Getenv(CloudCredentialNamespacesEnv))[namespace] {
+		return "", fmt.Errorf("cloud credential namespace %s is not listed in %s", namespace,
+			CloudCredentialNamespacesEnv)
+	}
+
+	data, err := provider.secretGetter(namespace, name)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read cloud credential %s", provider.id)
+	}
+
+	token := strings.TrimSpace(string(data[cloudCredentialTokenKey]))
+
+	if token == "" {
+		return "", fmt.Errorf("cloud credential %s has no DigitalOcean access token", provider.id)
+	}
+
+	return token, nil
+}
+
+func inClusterSecretGetter(namespace, name string) (map[string][]byte, error) {
+	restConfig, err := rest.InClusterConfig()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "cloud credentials are only available inside the Rancher cluster")
+	}
+
+	clientSet, err := kubernetes.NewForConfig(restConfig)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	secret, err := clientSet.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.Data, nil
+}
+
+func splitEnvList(value string) map[string]bool {
+	list := map[string]bool{}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list[item] = true
+		}
+	}
+
+	return list
+}
+
+// vaultProvider reads the token from a HashiCorp Vault KV secret. Both the
+// version 1 and version 2 engines are supported. The Vault server and token
+// are taken from VAULT_ADDR and VAULT_TOKEN, so the Vault token is only ever
+// sent to the server the operator configured.
+type vaultProvider struct {
+	path       string
+	field      string
+	httpClient *http.Client
+}
+
+type vaultSecret struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+func (provider *vaultProvider) Token(ctx context.Context) (string, error) {
+	address := os.Getenv(vaultAddressEnv)
+
+	if address == "" {
+		return "", fmt.Errorf("vault address was not reported in %s", vaultAddressEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, vaultRequestTimeout)
+	defer cancel()
+
+	url := strings.TrimSuffix(address, "/") + "/v1/" + strings.TrimPrefix(provider.path, "/")
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+
+	if err != nil {
+		return "", errors.Wrap(err, "error creating vault request")
+	}
+
+	request = request.WithContext(ctx)
+	request.Header.Set("X-Vault-Token", os.Getenv(vaultTokenEnv))
+
+	response, err := provider.httpClient.Do(request)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading vault secret %s", provider.path)
+	}
+
+	defer response.Body.Close()
+
+	secret := vaultSecret{}
+
+	if err := json.NewDecoder(response.Body).Decode(&secret); err != nil && response.StatusCode == http.StatusOK {
+		return "", errors.Wrapf(err, "error decoding vault secret %s", provider.path)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error reading vault secret %s: status %d %s",
+			provider.path, response.StatusCode, strings.Join(secret.Errors, ", "))
+	}
+
+	data := secret.Data
+
+	// KV version 2 nests the secret under data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nested
+		}
+	}
+
+	field := provider.field
+
+	if field == "" {
+		field = state.DefaultVaultField
+	}
+
+	token, _ := data[field].(string)
+
+	if token == "" {
+		return "", fmt.Errorf("vault secret %s has no field %s", provider.path, field)
+	}
+
+	return token, nil
+}
+
+// credentialTokenSource asks the provider for the token on every request.
+type credentialTokenSource struct {
+	provider CredentialProvider
+	err      error
+}
+
+func newCredentialTokenSource(credential state.Credential) *credentialTokenSource {
+	provider, err := NewCredentialProvider(credential)
+
+	return &credentialTokenSource{provider: provider, err: err}
+}
+
+func (source *credentialTokenSource) Token(ctx context.Context) (string, error) {
+	if source.err != nil {
+		return "", source.err
+	}
+
+	token, err := source.provider.Token(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	logging.AddSecret(token)
+
+	return token, nil
+}
+
+// credentialTransport authorizes every request with the token of the source,
+// read with the context of the request so a cancelled call stops waiting for
+// the credential provider.
+type credentialTransport struct {
+	source *credentialTokenSource
+	base   http.RoundTripper
+}
+
+func (transport *credentialTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	token, err := transport.source.Token(request.Context())
+
+	if err != nil {
+		if request.Body != nil {
+			request.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	authorized := request.Clone(request.Context())
+	authorized.Header.Set("Authorization", "Bearer "+token)
+
+	return transport.base.RoundTrip(authorized)
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func noSecretGetter(namespace, name string) (map[string][]byte, error) {
+	return nil, errors.New("not found")
+}
+
+func TestLiteralProvider(t *testing.T) {
+	provider, err := NewCredentialProvider(state.Credential{Token: "literal-token"})
+
+	assert.NoError(t, err)
+
+	token, err := provider.Token(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "literal-token", token, "Token equals")
+}
+
+func TestLiteralProviderWithoutToken(t *testing.T) {
+	_, err := NewCredentialProvider(state.Credential{Source: state.CredentialSourceToken})
+
+	assert.Error(t, err, "Error without token")
+}
+
+func TestEnvProvider(t *testing.T) {
+	os.Setenv("DOKS_TEST_TOKEN", "env-token")
+	defer os.Unsetenv("DOKS_TEST_TOKEN")
+
+	os.Setenv(TokenEnvAllowlistEnv, "OTHER_TOKEN, DOKS_TEST_TOKEN")
+	defer os.Unsetenv(TokenEnvAllowlistEnv)
+
+	provider, _ := NewCredentialProvider(state.Credential{Source: state.CredentialSourceEnv, Env: "DOKS_TEST_TOKEN"})
+
+	token, err := provider.Token(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "env-token", token, "Token equals")
+}
+
+func TestEnvProviderEmpty(t *testing.T) {
+	os.Setenv(TokenEnvAllowlistEnv, "DOKS_TEST_MISSING")
+	defer os.Unsetenv(TokenEnvAllowlistEnv)
+
+	provider, _ := NewCredentialProvider(state.Credential{Source: state.CredentialSourceEnv, Env: "DOKS_TEST_MISSING"})
+
+	_, err := provider.Token(context.TODO())
+
+	assert.Error(t, err, "Error with empty variable")
+}
+
+func TestEnvProviderNotAllowed(t *testing.T) {
+	os.Setenv("DOKS_TEST_SECRET", "host-secret")
+	defer os.Unsetenv("DOKS_TEST_SECRET")
+
+	provider, _ := NewCredentialProvider(state.Credential{Source: state.CredentialSourceEnv, Env: "DOKS_TEST_SECRET"})
+
+	_, err := provider.Token(context.TODO())
+
+	assert.Error(t, err, "Variable outside the allowlist refused")
+	assert.NotContains(t, err.Error(), "host-secret")
+}
+
+func TestFileProviderRereadsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doks-token")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("first-token\n"), 0600))
+
+	os.Setenv(TokenFileDirsEnv, dir)
+	defer os.Unsetenv(TokenFileDirsEnv)
+
+	provider, _ := NewCredentialProvider(state.Credential{Source: state.CredentialSourceFile, File: tokenFile})
+
+	token, err := provider.Token(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "first-token", token, "Token read from file")
+
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("rotated-token"), 0600))
+
+	token, err = provider.Token(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated-token", token, "Rotated token read from file")
+}
+
+func TestFileProviderOutsideAllowedDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doks-token")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	allowedDir := filepath.Join(dir, "tokens")
+	assert.NoError(t, os.Mkdir(allowedDir, 0700))
+
+	secretFile := filepath.Join(dir, "secret")
+	assert.NoError(t, ioutil.WriteFile(secretFile, []byte("host-secret"), 0600))
+
+	link := filepath.Join(allowedDir, "token")
+	assert.NoError(t, os.Symlink(secretFile, link))
+
+	for _, path := range []string{secretFile, filepath.Join(allowedDir, "..", "secret"), link} {
+		provider, _ := NewCredentialProvider(state.Credential{Source: state.CredentialSourceFile, File: path})
+
+		_, err = provider.Token(context.TODO())
+
+		assert.Error(t, err, path+" refused without allowed directories")
+
+		os.Setenv(TokenFileDirsEnv, allowedDir)
+
+		_, err = provider.Token(context.TODO())
+
+		assert.Error(t, err, path+" refused outside the allowed directories")
+
+		os.Unsetenv(TokenFileDirsEnv)
+	}
+}
+
+func TestCloudCredentialProvider(t *testing.T) {
+	secretGetter := func(namespace, name string) (map[string][]byte, error) {
+		assert.Equal(t, "cattle-global-data", namespace, "Namespace equals")
+		assert.Equal(t, "cc-abcde", name, "Name equals")
+		return map[string][]byte{cloudCredentialTokenKey: []byte("cloud-token")}, nil
+	}
+
+	for _, id := range []string{"cattle-global-data:cc-abcde", "cc-abcde"} {
+		credential := state.Credential{Source: state.CredentialSourceCloudCredential, CloudCredentialID: id}
+
+		provider, _ := newCredentialProvider(credential, secretGetter, http.DefaultClient)
+
+		token, err := provider.Token(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "cloud-token", token, "Token equals")
+	}
+}
+
+func TestCloudCredentialProviderNotFound(t *testing.T) {
+	credential := state.Credential{Source: state.CredentialSourceCloudCredential, CloudCredentialID: "cc-abcde"}
+
+	provider, _ := newCredentialProvider(credential, noSecretGetter, http.DefaultClient)
+
+	_, err := provider.Token(context.TODO())
+
+	assert.Error(t, err, "Error reading cloud credential")
+}
+
+func TestCloudCredentialProviderNamespaces(t *testing.T) {
+	secretGetter := func(namespace, name string) (map[string][]byte, error) {
+		return map[string][]byte{cloudCredentialTokenKey: []byte("cloud-token")}, nil
+	}
+
+	credential := state.Credential{Source: state.CredentialSourceCloudCredential, CloudCredentialID: "team-a:cc-abcde"}
+
+	provider, _ := newCredentialProvider(credential, secretGetter, http.DefaultClient)
+
+	_, err := provider.Token(context.TODO())
+
+	assert.Error(t, err, "Namespace outside the allowed ones refused")
+
+	os.Setenv(CloudCredentialNamespacesEnv, "team-b, team-a")
+	defer os.Unsetenv(CloudCredentialNamespacesEnv)
+
+	token, err := provider.Token(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cloud-token", token, "Token read from an allowed namespace")
+}
+
+func TestCloudCredentialProviderOtherSecret(t *testing.T) {
+	secretGetter := func(namespace, name string) (map[string][]byte, error) {
+		return map[string][]byte{"password": []byte("host-secret")}, nil
+	}
+
+	credential := state.Credential{Source: state.CredentialSourceCloudCredential, CloudCredentialID: "cc-abcde"}
+
+	provider, _ := newCredentialProvider(credential, secretGetter, http.DefaultClient)
+
+	_, err := provider.Token(context.TODO())
+
+	assert.Error(t, err, "Secret without a DigitalOcean token refused")
+	assert.NotContains(t, err.Error(), "host-secret")
+}
+
+func newVaultServer(t *testing.T, path, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != path || request.Header.Get("X-Vault-Token") != "vault-root" {
+			writer.WriteHeader(http.StatusForbidden)
+			writer.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		writer.Write([]byte(body))
+	}))
+}
+
+func vaultToken(t *testing.T, server *httptest.Server, vaultPath string) (string, error) {
+	os.Setenv(vaultTokenEnv, "vault-root")
+	defer os.Unsetenv(vaultTokenEnv)
+
+	os.Setenv(vaultAddressEnv, server.URL)
+	defer os.Unsetenv(vaultAddressEnv)
+
+	credential := state.Credential{
+		Source:     state.CredentialSourceVault,
+		VaultPath:  vaultPath,
+		VaultField: state.DefaultVaultField,
+	}
+
+	provider, err := newCredentialProvider(credential, noSecretGetter, server.Client())
+
+	assert.NoError(t, err)
+
+	return provider.Token(context.TODO())
+}
+
+func TestVaultProviderKVVersion2(t *testing.T) {
+	server := newVaultServer(t, "/v1/secret/data/doks",
+		`{"data":{"data":{"token":"vault-token"},"metadata":{"version":3}}}`)
+	defer server.Close()
+
+	token, err := vaultToken(t, server, "secret/data/doks")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-token", token, "Token equals")
+}
+
+func TestVaultProviderKVVersion1(t *testing.T) {
+	server := newVaultServer(t, "/v1/kv/doks", `{"data":{"token":"vault-token"}}`)
+	defer server.Close()
+
+	token, err := vaultToken(t, server, "kv/doks")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-token", token, "Token equals")
+}
+
+func TestVaultProviderDenied(t *testing.T) {
+	server := newVaultServer(t, "/v1/secret/data/doks", `{}`)
+	defer server.Close()
+
+	_, err := vaultToken(t, server, "secret/data/other")
+
+	assert.Error(t, err, "Error reading denied path")
+	assert.Contains(t, err.Error(), "permission denied", "Vault error reported")
+}
+
+func TestVaultProviderMissingField(t *testing.T) {
+	server := newVaultServer(t, "/v1/kv/doks", `{"data":{"password":"x"}}`)
+	defer server.Close()
+
+	_, err := vaultToken(t, server, "kv/doks")
+
+	assert.Error(t, err, "Error without token field")
+}
+
+func TestVaultProviderWithoutAddress(t *testing.T) {
+	os.Setenv(vaultTokenEnv, "vault-root")
+	defer os.Unsetenv(vaultTokenEnv)
+
+	credential := state.Credential{Source: state.CredentialSourceVault, VaultPath: "kv/doks"}
+
+	provider, _ := newCredentialProvider(credential, noSecretGetter, http.DefaultClient)
+
+	_, err := provider.Token(context.TODO())
+
+	assert.Error(t, err, "Error without VAULT_ADDR")
+}
+
+func TestCredentialTokenSource(t *testing.T) {
+	tokenSource := newCredentialTokenSource(state.Credential{Token: "literal-token"})
+
+	token, err := tokenSource.Token(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "literal-token", token, "Token equals")
+
+	_, err = newCredentialTokenSource(state.Credential{Source: "ssm"}).Token(context.TODO())
+
+	assert.Error(t, err, "Error with invalid credential")
+}
+
+type contextProvider struct {
+	ctx context.Context
+}
+
+func (provider *contextProvider) Token(ctx context.Context) (string, error) {
+	provider.ctx = ctx
+	return "context-token", ctx.Err()
+}
+
+func TestCredentialTransportUsesRequestContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "Bearer context-token", request.Header.Get("Authorization"), "Token sent")
+	}))
+	defer server.Close()
+
+	provider := &contextProvider{}
+	transport := &credentialTransport{source: &credentialTokenSource{provider: provider}, base: http.DefaultTransport}
+
+	type key struct{}
+	ctx := context.WithValue(context.TODO(), key{}, "caller")
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+
+	response, err := transport.RoundTrip(request.WithContext(ctx))
+
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, "caller", provider.ctx.Value(key{}), "Provider called with the request context")
+	assert.Empty(t, request.Header.Get("Authorization"), "Caller request left untouched")
+
+	cancelled, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	_, err = transport.RoundTrip(request.WithContext(cancelled))
+
+	assert.Equal(t, context.Canceled, err, "Cancelled request stops at the provider")
+}
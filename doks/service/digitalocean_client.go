@@ -4,19 +4,56 @@ import (
 	"context"
 	"fmt"
 	"github.com/digitalocean/godo"
-	"github.com/ghodss/yaml"
+	"gopkg.in/yaml.v2"
 	"github.com/pkg/errors"
 	"github.com/rancher/kontainer-engine/store"
 	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/helper"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/metrics"
 	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/tracing"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
 	"time"
 )
 
-type DigitalOceanFactory func(token string)DigitalOcean
+const (
+	nodeStatusRunning = "running"
+	maxRetries        = 5
+	retryBaseDelay    = 5 * time.Second
+	maxRetryDelay     = time.Minute
+)
+
+type DigitalOceanFactory func(credential state.Credential, api state.API)DigitalOcean
 
+// NewDigitalOceanFactory builds clients with the settings of the environment,
+// which main validates when the driver starts.
 func NewDigitalOceanFactory()DigitalOceanFactory{
-	return func(token string)DigitalOcean{
-		return newDigitalOcean(token, helper.NewTimerSleeper())
+	config, err := ClientConfigFromEnv()
+
+	if err != nil {
+		logrus.WithError(err).Warn("Ignoring the DigitalOcean client settings of the environment")
+		config = ClientConfig{}
+	}
+
+	return NewDigitalOceanFactoryWithConfig(config)
+}
+
+// NewDigitalOceanFactoryWithConfig builds clients with config, overridden by
+// the API settings of each cluster.
+func NewDigitalOceanFactoryWithConfig(config ClientConfig) DigitalOceanFactory {
+	return func(credential state.Credential, api state.API)DigitalOcean{
+		clusterConfig, err := config.WithAPI(api)
+
+		if err != nil {
+			// the cluster options are validated before they are saved
+			logrus.WithError(err).Warn("Ignoring the DigitalOcean API settings of the cluster")
+			clusterConfig = config
+		}
+
+		return newInstrumentedDigitalOcean(newDigitalOcean(newCredentialTokenSource(credential),
+			helper.NewTimerSleeper(), clusterConfig))
 	}
 }
 
@@ -27,10 +64,21 @@ type DigitalOcean interface {
 	UpgradeKubernetesVersion(ctx context.Context, clusterID, version string)error
 	DeleteCluster(ctx context.Context, clusterID string)error
 	UpdateNodePool(ctx context.Context, clusterID, nodePoolID string, nodePool state.NodePool ) error
+	CreateNodePool(ctx context.Context, clusterID string, nodePool state.NodePool) (string, error)
+	DeleteNodePool(ctx context.Context, clusterID, nodePoolID string) error
 	GetNodePool(ctx context.Context, clusterID, nodePoolID string) (*state.NodePool,error)
+	ListNodePools(ctx context.Context, clusterID string) ([]state.NodePool, error)
+	ListNodes(ctx context.Context, clusterID, nodePoolID string) ([]state.Node, error)
+	DeleteNode(ctx context.Context, clusterID, nodePoolID, nodeID string, replace, skipDrain bool) error
 	GetKubeConfig(clusterID string)(*store.KubeConfig,error)
 	WaitClusterCreated(ctx context.Context, clusterID string)error
 	WaitClusterDeleted(ctx context.Context, clusterID string)error
+	WaitNodeReplaced(ctx context.Context, clusterID, nodePoolID, nodeID string) error
+	GetAccount(ctx context.Context) (*state.Account, error)
+	GetCluster(ctx context.Context, clusterID string) (*state.ClusterStatus, error)
+	FindProject(ctx context.Context, projectID, projectName string) (*state.Project, error)
+	AssignClusterToProject(ctx context.Context, projectID, clusterID string) error
+	FindOrphans(ctx context.Context, ownerTag string, knownClusterIDs []string) ([]Orphan, error)
 }
 
 type digitalOceanImpl struct {
@@ -38,28 +86,66 @@ type digitalOceanImpl struct {
 	sleeper helper.Sleeper
 }
 
-func newDigitalOcean(token string, sleeper helper.Sleeper) DigitalOcean {
+func newDigitalOcean(tokenSource *credentialTokenSource, sleeper helper.Sleeper, config ClientConfig) DigitalOcean {
+	// the configuration is validated when the driver starts and when the
+	// cluster options are read, an invalid one here only comes from a caller
+	// skipping Validate
+	transport, err := config.transport()
+
+	if err != nil {
+		logrus.WithError(err).Warn("Ignoring the DigitalOcean proxy and CA bundle settings")
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	// the transport is used without a token cache so the credential provider
+	// is asked for the token on every request. Each request is traced as a
+	// child of the service call span.
+	httpClient := &http.Client{
+		Transport: tracing.NewTransport(&credentialTransport{source: tokenSource, base: transport}),
+		Timeout:   config.Timeout,
+	}
+
+	client := godo.NewClient(httpClient)
+	client.OnRequestCompleted(observeRateLimit)
+
+	if config.BaseURL != "" {
+		if baseURL, err := config.baseURL(); err == nil {
+			client.BaseURL = baseURL
+		} else {
+			logrus.WithError(err).Warn("Ignoring the DigitalOcean API URL")
+		}
+	}
+
+	if config.UserAgentSuffix != "" {
+		client.UserAgent = client.UserAgent + " " + config.UserAgentSuffix
+	}
+
 	return &digitalOceanImpl{
-		client: godo.NewFromToken(token),
+		client: client,
 		sleeper: sleeper,
 	}
 }
 
-func (do *digitalOceanImpl) CreateCluster(ctx context.Context, state state.Cluster,
+func (do *digitalOceanImpl) CreateCluster(ctx context.Context, clusterState state.Cluster,
 	poolState state.NodePool) (string, string, error){
+	managedTags := state.ManagedTags(clusterState)
+	poolState.Tags = state.WithManagedTags(poolState.Tags, managedTags)
+
 	createClusterRequest := &godo.KubernetesClusterCreateRequest{
-		Name: state.Name,
-		Tags: state.Tags,
-		AutoUpgrade: *state.AutoUpgrade,
-		RegionSlug: state.RegionSlug,
-		VersionSlug: state.VersionSlug,
+		Name: clusterState.Name,
+		Tags: state.WithManagedTags(clusterState.Tags, managedTags),
+		AutoUpgrade: *clusterState.AutoUpgrade,
+		RegionSlug: clusterState.RegionSlug,
+		VersionSlug: clusterState.VersionSlug,
+		VPCUUID: clusterState.VPCID,
 		NodePools: do.buildNodePoolCreateRequest(poolState),
+		MaintenancePolicy: buildMaintenancePolicy(clusterState.MaintenancePolicy),
 	}
 
 	cluster, _, err := do.client.Kubernetes.Create(ctx,createClusterRequest)
 
 	if err != nil {
-		return "","",errors.Wrap(err,"error creating the cluster")
+		return "","",newError("create cluster", err)
 	}
 
 	return cluster.ID, cluster.NodePools[0].ID, nil
@@ -68,14 +154,15 @@ func (do *digitalOceanImpl) CreateCluster(ctx context.Context, state state.Clust
 func (do *digitalOceanImpl) UpdateCluster(ctx context.Context, clusterID string, cluster state.Cluster)error{
 
 	updateRequest := &godo.KubernetesClusterUpdateRequest{
-		Tags: cluster.Tags,
+		Name: cluster.Name,
+		Tags: state.WithManagedTags(cluster.Tags, state.ManagedTags(cluster)),
 		AutoUpgrade: cluster.AutoUpgrade,
 	}
 
 	_, _, err := do.client.Kubernetes.Update(ctx, clusterID, updateRequest)
 
 	if err != nil {
-		return errors.Wrap(err,"error in update cluster")
+		return newError("update cluster", err)
 	}
 
 	return nil
@@ -85,7 +172,7 @@ func (do *digitalOceanImpl) DeleteCluster(ctx context.Context, clusterID string)
 	_, err := do.client.Kubernetes.Delete(ctx, clusterID)
 
 	if err != nil {
-		return errors.Wrap(err,"error in delete cluster")
+		return newError("delete cluster", err)
 	}
 
 	return nil
@@ -96,7 +183,7 @@ func (do *digitalOceanImpl) GetKubeConfig(clusterID string)(*store.KubeConfig,er
 	clusterKubeConfig, _, err := do.client.Kubernetes.GetKubeConfig(context.TODO(), clusterID)
 
 	if err != nil {
-		return nil, errors.Wrapf(err,"error get kubeConfig for cluster %s",clusterID)
+		return nil, newError(fmt.Sprintf("get kubeConfig for cluster %s",clusterID), err)
 	}
 
 	kubeConfig := &store.KubeConfig{}
@@ -107,6 +194,10 @@ func (do *digitalOceanImpl) GetKubeConfig(clusterID string)(*store.KubeConfig,er
 		return nil, errors.Wrapf(err,"error marshal kubeConfig from clusterID %s",clusterID)
 	}
 
+	for _, user := range kubeConfig.Users {
+		logging.AddSecret(user.User.Token)
+	}
+
 	return kubeConfig, nil
 }
 
@@ -116,9 +207,9 @@ func (do digitalOceanImpl) WaitClusterCreated(ctx context.Context, clusterID str
 }
 
 func (do digitalOceanImpl) WaitClusterDeleted(ctx context.Context, clusterID string)error{
-	response, err := do.waitCluster(ctx, clusterID, godo.KubernetesClusterStatusDeleted)
+	_, err := do.waitCluster(ctx, clusterID, godo.KubernetesClusterStatusDeleted)
 
-	if response != nil && response.StatusCode == 404 {
+	if IsNotFound(err) {
 		return nil
 	}
 
@@ -130,10 +221,11 @@ func (do digitalOceanImpl) GetNodePool(ctx context.Context, clusterID, nodePoolI
 	kubernetesNodePool, _, err := do.client.Kubernetes.GetNodePool(ctx, clusterID, nodePoolID)
 
 	if err != nil {
-		return nil, errors.Wrap(err,fmt.Sprintf("error in get node pool: %v",err))
+		return nil, newError("get node pool", err)
 	}
 
 	nodePool := &state.NodePool{
+		ID: kubernetesNodePool.ID,
 		Count: kubernetesNodePool.Count,
 		MaxNodes: kubernetesNodePool.MaxNodes,
 		MinNodes: kubernetesNodePool.MinNodes,
@@ -147,6 +239,109 @@ func (do digitalOceanImpl) GetNodePool(ctx context.Context, clusterID, nodePoolI
 	return nodePool, nil
 }
 
+func (do digitalOceanImpl) ListNodes(ctx context.Context, clusterID, nodePoolID string) ([]state.Node, error) {
+
+	kubernetesNodePool, _, err := do.client.Kubernetes.GetNodePool(ctx, clusterID, nodePoolID)
+
+	if err != nil {
+		return nil, newError(fmt.Sprintf("list nodes of node pool %s", nodePoolID), err)
+	}
+
+	return buildNodesState(kubernetesNodePool.Nodes), nil
+}
+
+// kubernetesNodePool adds the taints, which godo does not decode, to a node
+// pool.
+type kubernetesNodePool struct {
+	godo.KubernetesNodePool
+	Taints []struct {
+		Key    string `json:"key"`
+		Value  string `json:"value"`
+		Effect string `json:"effect"`
+	} `json:"taints,omitempty"`
+}
+
+func (do digitalOceanImpl) ListNodePools(ctx context.Context, clusterID string) ([]state.NodePool, error) {
+
+	nodePools := []state.NodePool{}
+	page := 1
+
+	for {
+		path := fmt.Sprintf("v2/kubernetes/clusters/%s/node_pools?page=%d", clusterID, page)
+		request, err := do.client.NewRequest(ctx, http.MethodGet, path, nil)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error in list node pools")
+		}
+
+		root := struct {
+			NodePools []kubernetesNodePool `json:"node_pools"`
+			Links     *godo.Links          `json:"links"`
+		}{}
+
+		_, err = do.client.Do(ctx, request, &root)
+
+		if err != nil {
+			return nil, newError(fmt.Sprintf("list node pools of cluster %s", clusterID), err)
+		}
+
+		for _, kubernetesNodePool := range root.NodePools {
+			var taints []state.Taint
+
+			for _, taint := range kubernetesNodePool.Taints {
+				taints = append(taints, state.Taint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+			}
+
+			autoScale := kubernetesNodePool.AutoScale
+
+			nodePools = append(nodePools, state.NodePool{
+				ID:        kubernetesNodePool.ID,
+				Name:      kubernetesNodePool.Name,
+				Size:      kubernetesNodePool.Size,
+				Count:     kubernetesNodePool.Count,
+				Tags:      kubernetesNodePool.Tags,
+				Labels:    kubernetesNodePool.Labels,
+				AutoScale: &autoScale,
+				MinNodes:  kubernetesNodePool.MinNodes,
+				MaxNodes:  kubernetesNodePool.MaxNodes,
+				Nodes:     buildNodesState(kubernetesNodePool.Nodes),
+				Taints:    taints,
+			})
+		}
+
+		if root.Links == nil || root.Links.IsLastPage() {
+			return nodePools, nil
+		}
+
+		currentPage, err := root.Links.CurrentPage()
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error in list node pools pagination")
+		}
+
+		page = currentPage + 1
+	}
+}
+
+func (do digitalOceanImpl) DeleteNode(ctx context.Context, clusterID, nodePoolID, nodeID string,
+	replace, skipDrain bool) error {
+
+	deleteRequest := &godo.KubernetesNodeDeleteRequest{
+		Replace:   replace,
+		SkipDrain: skipDrain,
+	}
+
+	_, err := do.client.Kubernetes.DeleteNode(ctx, clusterID, nodePoolID, nodeID, deleteRequest)
+
+	if err != nil {
+		return newError(fmt.Sprintf("delete node %s", nodeID), err)
+	}
+
+	return nil
+}
+
+// UpdateNodePool sends the tags of the node pool as given, callers add the
+// managed tags of the cluster with state.TagNodePool.
 func (do digitalOceanImpl) UpdateNodePool(ctx context.Context, clusterID, poolID string,
 	nodePool state.NodePool) error{
 
@@ -166,7 +361,33 @@ func (do digitalOceanImpl) UpdateNodePool(ctx context.Context, clusterID, poolID
 	_, _, err :=  do.client.Kubernetes.UpdateNodePool(ctx,clusterID,poolID,updateRequest)
 
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("error in getNodePool: %v",err))
+		return newError("update node pool", err)
+	}
+
+	return nil
+}
+
+// CreateNodePool sends the tags of the node pool as given, like
+// UpdateNodePool.
+func (do digitalOceanImpl) CreateNodePool(ctx context.Context, clusterID string,
+	nodePool state.NodePool) (string, error) {
+
+	createRequest := do.buildNodePoolCreateRequest(nodePool)[0]
+
+	kubernetesNodePool, _, err := do.client.Kubernetes.CreateNodePool(ctx, clusterID, createRequest)
+
+	if err != nil {
+		return "", newError(fmt.Sprintf("create node pool %s", nodePool.Name), err)
+	}
+
+	return kubernetesNodePool.ID, nil
+}
+
+func (do digitalOceanImpl) DeleteNodePool(ctx context.Context, clusterID, nodePoolID string) error {
+	_, err := do.client.Kubernetes.DeleteNodePool(ctx, clusterID, nodePoolID)
+
+	if err != nil {
+		return newError(fmt.Sprintf("delete node pool %s", nodePoolID), err)
 	}
 
 	return nil
@@ -176,7 +397,7 @@ func (do digitalOceanImpl) GetKubernetesClusterVersion(ctx context.Context, clus
 	cluster, _, err := do.client.Kubernetes.Get(ctx,clusterID)
 
 	if err != nil {
-		return "", errors.Wrap(err,fmt.Sprintf("error in get cluster %v",err))
+		return "", newError("get cluster", err)
 	}
 
 	return cluster.VersionSlug, nil
@@ -187,23 +408,46 @@ func (do digitalOceanImpl) UpgradeKubernetesVersion(ctx context.Context, cluster
 
 	_, err := do.client.Kubernetes.Upgrade(ctx, clusterID, upgradeRequest)
 
-	return err
+	return newError("upgrade cluster", err)
 }
 
 func (do digitalOceanImpl) waitCluster(ctx context.Context, clusterID string,
 	statusState godo.KubernetesClusterStatusState)(*godo.Response, error){
 
-	for {
+	iterations := 0
+
+	defer func() {
+		metrics.ObserveWaitIterations(ctx, "waitCluster_"+string(statusState), iterations)
+	}()
+
+	for attempt := 0; ; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		iterations++
+
 		cluster, response, err := do.client.Kubernetes.Get(ctx, clusterID)
 
 		if err != nil {
-			err = errors.Wrap(err, "error get cluster in waitCluster")
+			err = newError("get cluster in waitCluster", err)
+
+			if delay, retry := retryDelay(err, attempt); retry {
+				attempt++
+				do.sleeper.Sleep(delay)
+				continue
+			}
+
 			return response, err
 		}
 
+		attempt = 0
+
+		logging.FromContext(ctx).WithField("state", cluster.Status.State).
+			Debugf("Waiting for cluster %s to be %s", clusterID, statusState)
+
 		if cluster.Status.State == godo.KubernetesClusterStatusError {
-			err = errors.New("cluster status error")
-			return response, err
+			return response, clusterStatusError(cluster)
 		}
 
 		if cluster.Status.State != statusState{
@@ -215,6 +459,215 @@ func (do digitalOceanImpl) waitCluster(ctx context.Context, clusterID string,
 	}
 }
 
+// nodeReplaceTimeout bounds the wait for a replacement node, so a node that
+// is never replaced does not hang the update.
+var nodeReplaceTimeout = 30 * time.Minute
+
+// WaitNodeReplaced polls the node pool until the node is replaced. It gives
+// up when the context is done or after nodeReplaceTimeout.
+func (do digitalOceanImpl) WaitNodeReplaced(ctx context.Context, clusterID, nodePoolID, nodeID string) error {
+
+	iterations := 0
+	deadline := time.Now().Add(nodeReplaceTimeout)
+
+	defer func() {
+		metrics.ObserveWaitIterations(ctx, "WaitNodeReplaced", iterations)
+	}()
+
+	for attempt := 0; ; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if iterations > 0 && time.Now().After(deadline) {
+			return errors.Errorf("node %s of node pool %s was not replaced within %s",
+				nodeID, nodePoolID, nodeReplaceTimeout)
+		}
+
+		iterations++
+
+		kubernetesNodePool, _, err := do.client.Kubernetes.GetNodePool(ctx, clusterID, nodePoolID)
+
+		if err != nil {
+			err = newError("get node pool in WaitNodeReplaced", err)
+
+			if delay, retry := retryDelay(err, attempt); retry {
+				attempt++
+				do.sleeper.Sleep(delay)
+				continue
+			}
+
+			return err
+		}
+
+		attempt = 0
+
+		if !isNodePoolReplaced(kubernetesNodePool, nodeID) {
+			do.sleeper.Sleep(5 * time.Second)
+			continue
+		}
+
+		return nil
+	}
+}
+
+func (do digitalOceanImpl) GetCluster(ctx context.Context, clusterID string) (*state.ClusterStatus, error) {
+	cluster, _, err := do.client.Kubernetes.Get(ctx, clusterID)
+
+	if err != nil {
+		return nil, newError("get cluster", err)
+	}
+
+	clusterStatus := &state.ClusterStatus{
+		ID:          cluster.ID,
+		Name:        cluster.Name,
+		RegionSlug:  cluster.RegionSlug,
+		VPCID:       cluster.VPCUUID,
+		Tags:        cluster.Tags,
+		AutoUpgrade: cluster.AutoUpgrade,
+		VersionSlug: cluster.VersionSlug,
+		Endpoint:    cluster.Endpoint,
+		IPv4:        cluster.IPv4,
+		URN:         clusterURN(cluster.ID),
+		CreatedAt:   cluster.CreatedAt,
+		UpdatedAt:   cluster.UpdatedAt,
+	}
+
+	if cluster.MaintenancePolicy != nil {
+		clusterStatus.MaintenancePolicy = &state.MaintenancePolicy{
+			StartTime: cluster.MaintenancePolicy.StartTime,
+			Day:       cluster.MaintenancePolicy.Day.String(),
+		}
+	}
+
+	if cluster.Status != nil {
+		clusterStatus.State = string(cluster.Status.State)
+		clusterStatus.Message = cluster.Status.Message
+	}
+
+	return clusterStatus, nil
+}
+
+// buildMaintenancePolicy leaves the window to DigitalOcean when there is no
+// policy. The policies come from existing clusters, an unknown day falls
+// back to any day.
+func buildMaintenancePolicy(policy *state.MaintenancePolicy) *godo.KubernetesMaintenancePolicy {
+	if policy == nil {
+		return nil
+	}
+
+	day, err := godo.KubernetesMaintenanceToDay(policy.Day)
+
+	if err != nil {
+		day = godo.KubernetesMaintenanceDayAny
+	}
+
+	return &godo.KubernetesMaintenancePolicy{StartTime: policy.StartTime, Day: day}
+}
+
+// clusterURN builds the URN DigitalOcean uses to reference the cluster in
+// projects, which godo does not expose yet.
+func clusterURN(clusterID string) string {
+	return "do:kubernetes:" + clusterID
+}
+
+// clusterStatusError keeps the reason DigitalOcean gives for a failed cluster
+// so it reaches the Rancher UI.
+func clusterStatusError(cluster *godo.KubernetesCluster) error {
+	if cluster.Status.Message == "" {
+		return fmt.Errorf("cluster %s is in %s state", cluster.ID, cluster.Status.State)
+	}
+
+	return fmt.Errorf("cluster %s is in %s state: %s", cluster.ID, cluster.Status.State, cluster.Status.Message)
+}
+
+// observeRateLimit records the rate limit budget DigitalOcean reports on every
+// response.
+func observeRateLimit(_ *http.Request, response *http.Response) {
+	if response == nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(response.Header.Get("RateLimit-Limit"))
+
+	if err != nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(response.Header.Get("RateLimit-Remaining"))
+
+	if err != nil {
+		return
+	}
+
+	metrics.SetRateLimit(limit, remaining)
+}
+
+// retryDelay tells how long to wait before calling again after err. Permanent
+// errors are never retried and retryable ones at most maxRetries times.
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	serviceError, ok := AsError(err)
+
+	if !ok || !serviceError.Retryable() || attempt >= maxRetries {
+		return 0, false
+	}
+
+	delay := time.Duration(attempt+1) * retryBaseDelay
+
+	if serviceError.RetryAfter > delay {
+		delay = serviceError.RetryAfter
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay, true
+}
+
+// isNodePoolReplaced reports whether the node is gone from the pool and the
+// pool is back to its desired count with every node running.
+func isNodePoolReplaced(nodePool *godo.KubernetesNodePool, nodeID string) bool {
+	if len(nodePool.Nodes) < nodePool.Count {
+		return false
+	}
+
+	for _, node := range nodePool.Nodes {
+		if node.ID == nodeID {
+			return false
+		}
+
+		if node.Status == nil || node.Status.State != nodeStatusRunning {
+			return false
+		}
+	}
+
+	return true
+}
+
+func buildNodesState(kubernetesNodes []*godo.KubernetesNode) []state.Node {
+	nodes := make([]state.Node, 0, len(kubernetesNodes))
+
+	for _, kubernetesNode := range kubernetesNodes {
+		node := state.Node{
+			ID:        kubernetesNode.ID,
+			Name:      kubernetesNode.Name,
+			DropletID: kubernetesNode.DropletID,
+			CreatedAt: kubernetesNode.CreatedAt,
+			UpdatedAt: kubernetesNode.UpdatedAt,
+		}
+
+		if kubernetesNode.Status != nil {
+			node.State = kubernetesNode.Status.State
+			node.Message = kubernetesNode.Status.Message
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
 func (do digitalOceanImpl) buildNodePoolCreateRequest(nodePool state.NodePool) []*godo.KubernetesNodePoolCreateRequest{
 
 	request := &godo.KubernetesNodePoolCreateRequest{
@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeDigitalOcean(t *testing.T) (*fake.Server, DigitalOcean, *sleeperStub) {
+	server := fake.NewServer()
+	server.SetToken("fake-token")
+
+	sleeper := &sleeperStub{}
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "fake-token"}), sleeper,
+		ClientConfig{BaseURL: server.URL})
+
+	return server, digitalOcean, sleeper
+}
+
+func newClusterStates() (state.Cluster, state.NodePool) {
+	autoUpgrade, autoScale := true, true
+
+	clusterState := state.Cluster{
+		Name:        "rancher-cluster",
+		RegionSlug:  "nyc1",
+		VersionSlug: "1.17.6-do.0",
+		VPCID:       "5a4981aa-9653-4bd1-bef5-d6bff52042e4",
+		Tags:        []string{"rancher"},
+		AutoUpgrade: &autoUpgrade,
+	}
+
+	nodePoolState := state.NodePool{
+		Name:      "workers",
+		Size:      "s-2vcpu-4gb",
+		Count:     2,
+		AutoScale: &autoScale,
+		MinNodes:  1,
+		MaxNodes:  4,
+		Labels:    map[string]string{"role": "worker"},
+	}
+
+	return clusterState, nodePoolState
+}
+
+func createFakeCluster(t *testing.T, digitalOcean DigitalOcean) (string, string) {
+	clusterState, nodePoolState := newClusterStates()
+
+	clusterID, nodePoolID, err := digitalOcean.CreateCluster(context.TODO(), clusterState, nodePoolState)
+
+	assert.NoError(t, err)
+
+	return clusterID, nodePoolID
+}
+
+func TestCreateClusterRequest(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterID, nodePoolID := createFakeCluster(t, digitalOcean)
+
+	requests := server.RequestsTo(http.MethodPost, "/v2/kubernetes/clusters")
+	assert.Len(t, requests, 1)
+
+	createRequest := godo.KubernetesClusterCreateRequest{}
+	assert.NoError(t, requests[0].Decode(&createRequest))
+
+	assert.Equal(t, "rancher-cluster", createRequest.Name, "Name equals")
+	assert.Equal(t, "nyc1", createRequest.RegionSlug, "Region equals")
+	assert.Equal(t, "1.17.6-do.0", createRequest.VersionSlug, "Version equals")
+	assert.Equal(t, "5a4981aa-9653-4bd1-bef5-d6bff52042e4", createRequest.VPCUUID, "VPC sent")
+	assert.Equal(t, []string{"rancher", "rancher-managed", "rancher-cluster:rancher-cluster"}, createRequest.Tags,
+		"Tags equals with the managed ones")
+	assert.True(t, createRequest.AutoUpgrade, "AutoUpgrade sent")
+	assert.Len(t, createRequest.NodePools, 1)
+	assert.Equal(t, []string{"rancher-managed", "rancher-cluster:rancher-cluster"}, createRequest.NodePools[0].Tags,
+		"Node pool tagged")
+	assert.Equal(t, 1, createRequest.NodePools[0].MinNodes, "MinNodes sent with auto scale")
+	assert.Equal(t, 4, createRequest.NodePools[0].MaxNodes, "MaxNodes sent with auto scale")
+
+	cluster, ok := server.Cluster(clusterID)
+
+	assert.True(t, ok, "Cluster stored")
+	assert.Equal(t, nodePoolID, cluster.NodePools[0].ID, "Node pool id returned")
+	assert.Equal(t, godo.KubernetesClusterStatusProvisioning, cluster.Status.State, "Cluster provisioning")
+}
+
+func TestUserTagsKeepManagedTags(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterID, nodePoolID := createFakeCluster(t, digitalOcean)
+	clusterState, nodePoolState := newClusterStates()
+
+	clusterState.Name = "renamed"
+	clusterState.Tags = []string{}
+	assert.NoError(t, digitalOcean.UpdateCluster(context.TODO(), clusterID, clusterState))
+
+	clusterReads := len(server.RequestsTo(http.MethodGet, "/v2/kubernetes/clusters/"+clusterID))
+
+	nodePoolState.Tags = []string{"web"}
+	assert.NoError(t, digitalOcean.UpdateNodePool(context.TODO(), clusterID, nodePoolID,
+		state.TagNodePool(clusterState, nodePoolState)))
+
+	cluster, _ := server.Cluster(clusterID)
+	assert.Equal(t, []string{"rancher-managed", "rancher-cluster:renamed"}, cluster.Tags,
+		"Managed tags kept and the cluster retagged")
+
+	nodePool, err := digitalOcean.GetNodePool(context.TODO(), clusterID, nodePoolID)
+
+	assert.NoError(t, err)
+	assert.Subset(t, nodePool.Tags, []string{"web", "rancher-managed", "rancher-cluster:renamed"},
+		"Node pool follows the cluster")
+
+	nodePoolID, err = digitalOcean.CreateNodePool(context.TODO(), clusterID, state.TagNodePool(clusterState,
+		state.NodePool{Name: "batch", Size: "s-1vcpu-2gb", Count: 1, AutoScale: new(bool)}))
+
+	assert.NoError(t, err)
+
+	nodePool, err = digitalOcean.GetNodePool(context.TODO(), clusterID, nodePoolID)
+
+	assert.NoError(t, err)
+	assert.Subset(t, nodePool.Tags, []string{"rancher-managed", "rancher-cluster:renamed"}, "New node pool tagged")
+	assert.Len(t, server.RequestsTo(http.MethodGet, "/v2/kubernetes/clusters/"+clusterID), clusterReads,
+		"Cluster not read again for its tags")
+}
+
+func TestCreateClusterValidation(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterState, nodePoolState := newClusterStates()
+	clusterState.RegionSlug = "mars1"
+
+	_, _, err := digitalOcean.CreateCluster(context.TODO(), clusterState, nodePoolState)
+
+	assert.True(t, IsValidation(err), "Unknown region rejected")
+}
+
+func TestWaitClusterCreated(t *testing.T) {
+	server, digitalOcean, sleeper := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.SetProvisioningPolls(3)
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+
+	assert.NoError(t, digitalOcean.WaitClusterCreated(context.TODO(), clusterID))
+	assert.Len(t, sleeper.durations, 3, "Waited while provisioning")
+
+	clusterStatus, err := digitalOcean.GetCluster(context.TODO(), clusterID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "running", clusterStatus.State, "Cluster running")
+}
+
+func TestWaitClusterCreatedFailedProvisioning(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+	server.SetClusterState(clusterID, godo.KubernetesClusterStatusError, "droplet limit exceeded")
+
+	err := digitalOcean.WaitClusterCreated(context.TODO(), clusterID)
+
+	assert.EqualError(t, err, "cluster "+clusterID+" is in error state: droplet limit exceeded")
+}
+
+func TestUpdateClusterAndNodePool(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterID, nodePoolID := createFakeCluster(t, digitalOcean)
+	clusterState, nodePoolState := newClusterStates()
+
+	autoUpgrade := false
+	clusterState.AutoUpgrade = &autoUpgrade
+	clusterState.Tags = []string{"rancher", "production"}
+
+	assert.NoError(t, digitalOcean.UpdateCluster(context.TODO(), clusterID, clusterState))
+
+	nodePoolState.Count = 3
+	assert.NoError(t, digitalOcean.UpdateNodePool(context.TODO(), clusterID, nodePoolID, nodePoolState))
+
+	cluster, _ := server.Cluster(clusterID)
+	assert.False(t, cluster.AutoUpgrade, "AutoUpgrade updated")
+	assert.Equal(t, []string{"rancher", "production", "rancher-managed", "rancher-cluster:rancher-cluster"},
+		cluster.Tags, "Tags updated with the managed ones")
+
+	nodePool, err := digitalOcean.GetNodePool(context.TODO(), clusterID, nodePoolID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, nodePool.Count, "Count updated")
+
+	nodes, err := digitalOcean.ListNodes(context.TODO(), clusterID, nodePoolID)
+
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3, "Node added")
+
+	nodePools, err := digitalOcean.ListNodePools(context.TODO(), clusterID)
+
+	assert.NoError(t, err)
+	assert.Len(t, nodePools, 1)
+	assert.Equal(t, map[string]string{"role": "worker"}, nodePools[0].Labels, "Labels kept")
+}
+
+func TestListNodePoolsReadsTaints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Query().Get("page") == "2" {
+			writer.Write([]byte(`{"node_pools":[{"id":"pool-2","name":"gpu","count":1,` +
+				`"taints":[{"key":"gpu","value":"true","effect":"NoSchedule"}]}],` +
+				`"links":{"pages":{"first":"https://api/v2/kubernetes/clusters/abcd/node_pools?page=1",` +
+				`"prev":"https://api/v2/kubernetes/clusters/abcd/node_pools?page=1"}}}`))
+			return
+		}
+
+		writer.Write([]byte(`{"node_pools":[{"id":"pool-1","name":"workers","count":3}],` +
+			`"links":{"pages":{"next":"https://api/v2/kubernetes/clusters/abcd/node_pools?page=2",` +
+			`"last":"https://api/v2/kubernetes/clusters/abcd/node_pools?page=2"}}}`))
+	}))
+	defer server.Close()
+
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "token"}), &sleeperStub{},
+		ClientConfig{BaseURL: server.URL})
+
+	nodePools, err := digitalOcean.ListNodePools(context.TODO(), "abcd")
+
+	assert.NoError(t, err)
+	assert.Len(t, nodePools, 2, "Every page read")
+	assert.Empty(t, nodePools[0].Taints, "Node pool without taints")
+	assert.Equal(t, []state.Taint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}}, nodePools[1].Taints,
+		"Taints read")
+}
+
+func TestCreateAndDeleteNodePool(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+	autoScale := false
+
+	nodePoolID, err := digitalOcean.CreateNodePool(context.TODO(), clusterID, state.NodePool{
+		Name:      "batch",
+		Size:      "s-4vcpu-8gb",
+		Count:     1,
+		AutoScale: &autoScale,
+	})
+
+	assert.NoError(t, err)
+
+	nodePool, err := digitalOcean.GetNodePool(context.TODO(), clusterID, nodePoolID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "batch", nodePool.Name, "Name equals")
+	assert.Equal(t, "s-4vcpu-8gb", nodePool.Size, "Size equals")
+
+	assert.NoError(t, digitalOcean.DeleteNodePool(context.TODO(), clusterID, nodePoolID))
+
+	_, err = digitalOcean.GetNodePool(context.TODO(), clusterID, nodePoolID)
+	assert.True(t, IsNotFound(err), "Node pool deleted")
+
+	_, err = digitalOcean.CreateNodePool(context.TODO(), clusterID, state.NodePool{Name: "invalid",
+		Size: "unknown-size", Count: 1, AutoScale: &autoScale})
+	assert.Error(t, err, "Invalid size reported")
+}
+
+func TestGetClusterSettings(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+
+	clusterStatus, err := digitalOcean.GetCluster(context.TODO(), clusterID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "rancher-cluster", clusterStatus.Name, "Name equals")
+	assert.Equal(t, "nyc1", clusterStatus.RegionSlug, "Region equals")
+	assert.Equal(t, "5a4981aa-9653-4bd1-bef5-d6bff52042e4", clusterStatus.VPCID, "VPC equals")
+	assert.Contains(t, clusterStatus.Tags, "rancher", "Tags read")
+	assert.True(t, clusterStatus.AutoUpgrade, "AutoUpgrade read")
+}
+
+func TestCreateClusterWithMaintenancePolicy(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterState, nodePoolState := newClusterStates()
+	clusterState.MaintenancePolicy = &state.MaintenancePolicy{StartTime: "04:00", Day: "sunday"}
+
+	clusterID, _, err := digitalOcean.CreateCluster(context.TODO(), clusterState, nodePoolState)
+
+	assert.NoError(t, err)
+
+	clusterStatus, err := digitalOcean.GetCluster(context.TODO(), clusterID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, clusterState.MaintenancePolicy, clusterStatus.MaintenancePolicy, "Maintenance policy kept")
+}
+
+func TestUpgradeKubernetesVersion(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.SetProvisioningPolls(0)
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+
+	assert.NoError(t, digitalOcean.UpgradeKubernetesVersion(context.TODO(), clusterID, "1.18.3-do.0"))
+
+	version, err := digitalOcean.GetKubernetesClusterVersion(context.TODO(), clusterID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1.18.3-do.0", version, "Version upgraded")
+
+	err = digitalOcean.UpgradeKubernetesVersion(context.TODO(), clusterID, "2.0.0-do.0")
+
+	assert.True(t, IsValidation(err), "Unknown version rejected")
+}
+
+func TestDeleteCluster(t *testing.T) {
+	server, digitalOcean, sleeper := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.SetDeletingPolls(2)
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+
+	assert.NoError(t, digitalOcean.DeleteCluster(context.TODO(), clusterID))
+	assert.NoError(t, digitalOcean.WaitClusterDeleted(context.TODO(), clusterID))
+	assert.Empty(t, sleeper.durations, "Deleted state ends the wait")
+
+	_, err := digitalOcean.GetCluster(context.TODO(), clusterID)
+	assert.NoError(t, err, "Deleted cluster still found")
+
+	_, err = digitalOcean.GetCluster(context.TODO(), clusterID)
+	assert.True(t, IsNotFound(err), "Deleted cluster gone")
+}
+
+func TestGetKubeConfig(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+
+	kubeConfig, err := digitalOcean.GetKubeConfig(clusterID)
+
+	assert.NoError(t, err)
+	assert.Len(t, kubeConfig.Users, 1)
+	assert.Equal(t, fake.KubeConfigToken(clusterID), kubeConfig.Users[0].User.Token, "Token read")
+	assert.Equal(t, "https://"+clusterID+".k8s.ondigitalocean.com", kubeConfig.Clusters[0].Cluster.Server,
+		"Server read")
+	assert.NotEmpty(t, kubeConfig.Clusters[0].Cluster.CertificateAuthorityData, "Certificate authority read")
+}
+
+func TestWaitNodeReplacedStops(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.SetProvisioningPolls(0)
+	clusterID, nodePoolID := createFakeCluster(t, digitalOcean)
+
+	nodes, err := digitalOcean.ListNodes(context.TODO(), clusterID, nodePoolID)
+	assert.NoError(t, err)
+
+	server.SetProvisioningPolls(1000)
+	assert.NoError(t, digitalOcean.DeleteNode(context.TODO(), clusterID, nodePoolID, nodes[0].ID, true, false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = digitalOcean.WaitNodeReplaced(ctx, clusterID, nodePoolID, nodes[0].ID)
+	assert.Equal(t, context.Canceled, err, "Cancelled context ends the wait")
+
+	defer func(timeout time.Duration) { nodeReplaceTimeout = timeout }(nodeReplaceTimeout)
+	nodeReplaceTimeout = 0
+
+	err = digitalOcean.WaitNodeReplaced(context.TODO(), clusterID, nodePoolID, nodes[0].ID)
+	assert.Error(t, err, "Wait gives up after the timeout")
+}
+
+func TestWaitNodeReplaced(t *testing.T) {
+	server, digitalOcean, sleeper := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.SetProvisioningPolls(0)
+	clusterID, nodePoolID := createFakeCluster(t, digitalOcean)
+
+	nodes, err := digitalOcean.ListNodes(context.TODO(), clusterID, nodePoolID)
+	assert.NoError(t, err)
+
+	server.SetProvisioningPolls(2)
+
+	assert.NoError(t, digitalOcean.DeleteNode(context.TODO(), clusterID, nodePoolID, nodes[0].ID, true, false))
+	assert.Equal(t, "1", server.RequestsTo(http.MethodDelete,
+		"/v2/kubernetes/clusters/"+clusterID+"/node_pools/"+nodePoolID+"/nodes/"+nodes[0].ID)[0].Query.Get("replace"),
+		"Replace requested")
+
+	assert.NoError(t, digitalOcean.WaitNodeReplaced(context.TODO(), clusterID, nodePoolID, nodes[0].ID))
+	assert.Len(t, sleeper.durations, 2, "Waited while the new node provisions")
+
+	nodes, err = digitalOcean.ListNodes(context.TODO(), clusterID, nodePoolID)
+
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2, "Pool back to its size")
+}
+
+func TestInjectedFailures(t *testing.T) {
+	server, digitalOcean, sleeper := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.SetProvisioningPolls(0)
+	clusterID, _ := createFakeCluster(t, digitalOcean)
+
+	server.Fail(fake.Failure{Method: http.MethodGet, Path: "/v2/kubernetes/clusters/" + clusterID,
+		Status: http.StatusServiceUnavailable, Message: "maintenance", Times: 2})
+
+	assert.NoError(t, digitalOcean.WaitClusterCreated(context.TODO(), clusterID), "Transient failures retried")
+	assert.Len(t, sleeper.durations, 2, "Slept between retries")
+
+	server.Fail(fake.Failure{Path: "/v2/kubernetes", Status: http.StatusTooManyRequests, Message: "slow down", Times: 1})
+
+	_, err := digitalOcean.GetCluster(context.TODO(), clusterID)
+
+	assert.True(t, IsRateLimited(err), "Rate limit classified")
+	assert.Contains(t, err.Error(), "slow down", "Message kept")
+
+	_, err = digitalOcean.GetCluster(context.TODO(), clusterID)
+	assert.NoError(t, err, "Failure consumed")
+}
+
+func TestUnauthorizedToken(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	server.SetToken("fake-token")
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "revoked"}), &sleeperStub{},
+		ClientConfig{BaseURL: server.URL})
+
+	_, err := digitalOcean.GetCluster(context.TODO(), "abcd")
+
+	assert.True(t, IsUnauthorized(err), "Wrong token rejected")
+}
+
+func TestGetAccountFromFake(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.SetAccount(fake.Account{UUID: "account", Status: "active", DropletLimit: 10, DropletCount: 3,
+		TeamUUID: "team", TeamName: "Platform"})
+	createFakeCluster(t, digitalOcean)
+
+	account, err := digitalOcean.GetAccount(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, account.DropletCount, "Cluster nodes counted as droplets")
+	assert.Equal(t, "Platform", account.TeamName, "Team read")
+	assert.True(t, account.WriteScope, "Write scope detected")
+}
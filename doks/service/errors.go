@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+type ErrorKind string
+
+const (
+	ErrorKindNotFound     ErrorKind = "NotFound"
+	ErrorKindUnauthorized ErrorKind = "Unauthorized"
+	ErrorKindForbidden    ErrorKind = "Forbidden"
+	ErrorKindRateLimited  ErrorKind = "RateLimited"
+	ErrorKindConflict     ErrorKind = "Conflict"
+	ErrorKindValidation   ErrorKind = "Validation"
+	ErrorKindTransient    ErrorKind = "Transient"
+	ErrorKindUnknown      ErrorKind = "Unknown"
+)
+
+// Error is a failed call to the DigitalOcean API. Kind tells callers how to
+// react, RequestID is what DigitalOcean support asks for.
+type Error struct {
+	Kind       ErrorKind
+	Operation  string
+	StatusCode int
+	Message    string
+	RequestID  string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("error in %s: %s", e.Operation, e.Message)
+
+	if e.RequestID != "" {
+		message = fmt.Sprintf("%s (request id %s)", message, e.RequestID)
+	}
+
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the same call may succeed later.
+func (e *Error) Retryable() bool {
+	return e.Kind == ErrorKindRateLimited || e.Kind == ErrorKindTransient
+}
+
+// NewValidationError reports a request that cannot succeed as given, such
+// as a count or an option the driver refuses before calling the API.
+func NewValidationError(operation, message string) error {
+	return &Error{
+		Kind:      ErrorKindValidation,
+		Operation: operation,
+		Message:   message,
+	}
+}
+
+// newError classifies an error returned by godo. Errors that are not API
+// responses are classified as transient when they come from the network.
+func newError(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := AsError(err); ok {
+		return err
+	}
+
+	serviceError := &Error{
+		Kind:      ErrorKindUnknown,
+		Operation: operation,
+		Err:       err,
+	}
+
+	if errorResponse, ok := err.(*godo.ErrorResponse); ok && errorResponse.Response != nil {
+		serviceError.StatusCode = errorResponse.Response.StatusCode
+		serviceError.Message = errorResponse.Message
+		serviceError.RequestID = errorResponse.RequestID
+		serviceError.Kind = kindFromStatus(errorResponse.Response.StatusCode)
+
+		if serviceError.Kind == ErrorKindRateLimited {
+			serviceError.RetryAfter = retryAfter(errorResponse.Response)
+		}
+
+		return serviceError
+	}
+
+	serviceError.Message = err.Error()
+
+	if isNetworkError(err) {
+		serviceError.Kind = ErrorKindTransient
+	}
+
+	return serviceError
+}
+
+func kindFromStatus(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusNotFound:
+		return ErrorKindNotFound
+	case statusCode == http.StatusUnauthorized:
+		return ErrorKindUnauthorized
+	case statusCode == http.StatusForbidden:
+		return ErrorKindForbidden
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorKindRateLimited
+	case statusCode == http.StatusConflict || statusCode == http.StatusPreconditionFailed:
+		return ErrorKindConflict
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		return ErrorKindValidation
+	case statusCode >= http.StatusInternalServerError || statusCode == http.StatusRequestTimeout:
+		return ErrorKindTransient
+	}
+
+	return ErrorKindUnknown
+}
+
+func retryAfter(response *http.Response) time.Duration {
+	reset, err := strconv.ParseInt(response.Header.Get("RateLimit-Reset"), 10, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	wait := time.Until(time.Unix(reset, 0))
+
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+// isNetworkError tells a failure to reach the API, which is worth retrying,
+// from a cancelled or expired context of the caller. The timeout of the http
+// client also wraps context.DeadlineExceeded, but in a net.Error of its own.
+func isNetworkError(err error) bool {
+	var urlError *url.Error
+
+	if errors.As(err, &urlError) {
+		err = urlError.Err
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netError net.Error
+
+	if !errors.As(err, &netError) {
+		return false
+	}
+
+	return !errors.Is(err, context.DeadlineExceeded) || netError != context.DeadlineExceeded
+}
+
+// AsError finds the service error in a chain wrapped either with fmt.Errorf
+// or with github.com/pkg/errors.
+func AsError(err error) (*Error, bool) {
+	for err != nil {
+		if serviceError, ok := err.(*Error); ok {
+			return serviceError, true
+		}
+
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapper.Unwrap()
+		case interface{ Cause() error }:
+			err = wrapper.Cause()
+		default:
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+func isKind(err error, kind ErrorKind) bool {
+	serviceError, ok := AsError(err)
+	return ok && serviceError.Kind == kind
+}
+
+func IsNotFound(err error) bool {
+	return isKind(err, ErrorKindNotFound)
+}
+
+func IsUnauthorized(err error) bool {
+	return isKind(err, ErrorKindUnauthorized)
+}
+
+func IsForbidden(err error) bool {
+	return isKind(err, ErrorKindForbidden)
+}
+
+func IsRateLimited(err error) bool {
+	return isKind(err, ErrorKindRateLimited)
+}
+
+func IsConflict(err error) bool {
+	return isKind(err, ErrorKindConflict)
+}
+
+func IsValidation(err error) bool {
+	return isKind(err, ErrorKindValidation)
+}
+
+func IsTransient(err error) bool {
+	return isKind(err, ErrorKindTransient)
+}
+
+// IsRetryable reports whether err is a rate limit or a transient failure.
+// Permanent errors and errors from outside the service are not retried.
+func IsRetryable(err error) bool {
+	serviceError, ok := AsError(err)
+	return ok && serviceError.Retryable()
+}
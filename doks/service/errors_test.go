@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type sleeperStub struct {
+	durations []time.Duration
+}
+
+func (sleeper *sleeperStub) Sleep(duration time.Duration) {
+	sleeper.durations = append(sleeper.durations, duration)
+}
+
+func errorResponse(statusCode int) error {
+	return &godo.ErrorResponse{
+		Response:  &http.Response{StatusCode: statusCode, Header: http.Header{}},
+		Message:   "api message",
+		RequestID: "request-1",
+	}
+}
+
+func TestNewErrorKinds(t *testing.T) {
+	kinds := map[int]ErrorKind{
+		http.StatusNotFound:            ErrorKindNotFound,
+		http.StatusUnauthorized:        ErrorKindUnauthorized,
+		http.StatusForbidden:           ErrorKindForbidden,
+		http.StatusTooManyRequests:     ErrorKindRateLimited,
+		http.StatusConflict:            ErrorKindConflict,
+		http.StatusUnprocessableEntity: ErrorKindValidation,
+		http.StatusServiceUnavailable:  ErrorKindTransient,
+		http.StatusTeapot:              ErrorKindUnknown,
+	}
+
+	for statusCode, kind := range kinds {
+		serviceError, ok := AsError(newError("get cluster", errorResponse(statusCode)))
+
+		assert.True(t, ok, "Service error built")
+		assert.Equal(t, kind, serviceError.Kind, fmt.Sprintf("Kind of status %d", statusCode))
+		assert.Equal(t, statusCode, serviceError.StatusCode, "Status code kept")
+	}
+}
+
+func TestErrorMessageCarriesRequestID(t *testing.T) {
+	err := newError("get cluster", errorResponse(http.StatusNotFound))
+
+	assert.Equal(t, "error in get cluster: api message (request id request-1)", err.Error())
+}
+
+func TestNewErrorNetworkIsTransient(t *testing.T) {
+	err := newError("get cluster", &url.Error{Op: "Get", URL: "https://api", Err: &timeoutError{}})
+
+	assert.True(t, IsTransient(err), "Network error is transient")
+	assert.True(t, IsRetryable(err), "Network error is retryable")
+}
+
+func TestNewErrorContextIsNotTransient(t *testing.T) {
+	for _, contextErr := range []error{context.Canceled, context.DeadlineExceeded,
+		fmt.Errorf("waiting: %w", context.DeadlineExceeded)} {
+
+		err := newError("get cluster", &url.Error{Op: "Get", URL: "https://api", Err: contextErr})
+
+		assert.False(t, IsTransient(err), "%v is not transient", contextErr)
+		assert.False(t, IsRetryable(err), "%v is not retried", contextErr)
+	}
+}
+
+func TestNewErrorNil(t *testing.T) {
+	assert.NoError(t, newError("get cluster", nil))
+}
+
+func TestAsErrorThroughWrappers(t *testing.T) {
+	err := newError("delete cluster", errorResponse(http.StatusNotFound))
+
+	assert.True(t, IsNotFound(errors.Wrap(err, "removing")), "Found through pkg/errors")
+	assert.True(t, IsNotFound(fmt.Errorf("removing: %w", err)), "Found through fmt.Errorf")
+	assert.False(t, IsNotFound(errors.New("not found")), "Plain errors are not classified")
+}
+
+func TestRetryDelay(t *testing.T) {
+	_, retry := retryDelay(newError("get cluster", errorResponse(http.StatusNotFound)), 0)
+	assert.False(t, retry, "Permanent error not retried")
+
+	delay, retry := retryDelay(newError("get cluster", errorResponse(http.StatusBadGateway)), 1)
+	assert.True(t, retry, "Transient error retried")
+	assert.Equal(t, 2*retryBaseDelay, delay, "Delay grows with the attempts")
+
+	_, retry = retryDelay(newError("get cluster", errorResponse(http.StatusBadGateway)), maxRetries)
+	assert.False(t, retry, "Retries exhausted")
+
+	rateLimited := errorResponse(http.StatusTooManyRequests).(*godo.ErrorResponse)
+	rateLimited.Response.Header.Set("RateLimit-Reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+
+	delay, retry = retryDelay(newError("get cluster", rateLimited), 0)
+	assert.True(t, retry, "Rate limited error retried")
+	assert.Equal(t, maxRetryDelay, delay, "Rate limit wait capped")
+}
+
+func TestWaitClusterRetriesTransientErrors(t *testing.T) {
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		calls++
+
+		if calls == 1 {
+			writer.WriteHeader(http.StatusBadGateway)
+			writer.Write([]byte(`{"id":"bad_gateway","message":"upstream"}`))
+			return
+		}
+
+		writer.Write([]byte(`{"kubernetes_cluster":{"id":"abcd","status":{"state":"running"}}}`))
+	}))
+	defer server.Close()
+
+	sleeper := &sleeperStub{}
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "token"}), sleeper, ClientConfig{})
+	digitalOcean.(*digitalOceanImpl).client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	err := digitalOcean.WaitClusterCreated(context.TODO(), "abcd")
+
+	assert.NoError(t, err, "Transient error retried")
+	assert.Equal(t, []time.Duration{retryBaseDelay}, sleeper.durations, "Slept once before retrying")
+}
+
+func TestWaitClusterStopsWithContext(t *testing.T) {
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		calls++
+		writer.Write([]byte(`{"kubernetes_cluster":{"id":"abcd","status":{"state":"provisioning"}}}`))
+	}))
+	defer server.Close()
+
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "token"}), &sleeperStub{}, ClientConfig{})
+	digitalOcean.(*digitalOceanImpl).client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+
+	err := digitalOcean.WaitClusterCreated(ctx, "abcd")
+
+	assert.Equal(t, context.DeadlineExceeded, err, "Expired context ends the wait")
+	assert.Equal(t, 0, calls, "No call after the deadline")
+}
+
+func TestWaitClusterDeletedNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte(`{"id":"not_found","message":"cluster not found"}`))
+	}))
+	defer server.Close()
+
+	digitalOcean := newDigitalOcean(newCredentialTokenSource(state.Credential{Token: "token"}), &sleeperStub{}, ClientConfig{})
+	digitalOcean.(*digitalOceanImpl).client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	assert.NoError(t, digitalOcean.WaitClusterDeleted(context.TODO(), "abcd"), "Deleted cluster not found")
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
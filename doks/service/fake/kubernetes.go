@@ -0,0 +1,778 @@
+package fake
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+const (
+	nodeStateProvisioning = "provisioning"
+	nodeStateRunning      = "running"
+	defaultPerPage        = 20
+)
+
+type cluster struct {
+	godo.KubernetesCluster
+	// pendingPolls is how many more reads see the cluster before its
+	// provisioning, upgrade or deletion completes.
+	pendingPolls int
+	deleted      bool
+}
+
+// KubeConfigToken is the user token found in the kubeconfig of the cluster.
+func KubeConfigToken(clusterID string) string {
+	return "kubeconfig-token-" + clusterID
+}
+
+// AddCluster stores a running cluster, as if it had been created outside of
+// the tests, and returns its id. Missing ids are generated.
+func (server *Server) AddCluster(kubernetesCluster *godo.KubernetesCluster) string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	stored := &cluster{}
+	copyValue(kubernetesCluster, &stored.KubernetesCluster)
+
+	if stored.ID == "" {
+		stored.ID = server.newID()
+	}
+
+	if stored.Status == nil {
+		stored.Status = &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusRunning}
+	}
+
+	for _, nodePool := range stored.NodePools {
+		if nodePool.ID == "" {
+			nodePool.ID = server.newID()
+		}
+
+		for len(nodePool.Nodes) < nodePool.Count {
+			node := server.newNode(nodePool)
+			node.Status.State = nodeStateRunning
+			delete(server.pendingNodes, node.ID)
+			nodePool.Nodes = append(nodePool.Nodes, node)
+		}
+	}
+
+	server.storeCluster(stored)
+
+	return stored.ID
+}
+
+// Cluster returns a copy of the cluster as stored, without counting as a
+// read.
+func (server *Server) Cluster(clusterID string) (*godo.KubernetesCluster, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	stored, ok := server.clusters[clusterID]
+
+	if !ok {
+		return nil, false
+	}
+
+	kubernetesCluster := &godo.KubernetesCluster{}
+	copyValue(&stored.KubernetesCluster, kubernetesCluster)
+
+	return kubernetesCluster, true
+}
+
+// SetClusterState forces the state of the cluster, for instance to report a
+// failed provisioning. Pending transitions are dropped.
+func (server *Server) SetClusterState(clusterID string, state godo.KubernetesClusterStatusState, message string) bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	stored, ok := server.clusters[clusterID]
+
+	if !ok || stored.deleted {
+		return false
+	}
+
+	stored.Status = &godo.KubernetesClusterStatus{State: state, Message: message}
+	stored.pendingPolls = 0
+
+	return true
+}
+
+func copyValue(from, to interface{}) {
+	data, _ := json.Marshal(from)
+	json.Unmarshal(data, to)
+}
+
+func (server *Server) storeCluster(stored *cluster) {
+	if _, ok := server.clusters[stored.ID]; !ok {
+		server.clusterIDs = append(server.clusterIDs, stored.ID)
+	}
+
+	server.clusters[stored.ID] = stored
+}
+
+func (server *Server) removeCluster(clusterID string) {
+	delete(server.clusters, clusterID)
+
+	for index, id := range server.clusterIDs {
+		if id == clusterID {
+			server.clusterIDs = append(server.clusterIDs[:index], server.clusterIDs[index+1:]...)
+			return
+		}
+	}
+}
+
+// liveCluster finds a cluster that is not being deleted.
+func (server *Server) liveCluster(clusterID string) *cluster {
+	stored, ok := server.clusters[clusterID]
+
+	if !ok || stored.deleted {
+		return nil
+	}
+
+	return stored
+}
+
+// observe moves the cluster towards the end of its pending transition on
+// every read, and reports whether a deleted cluster is gone.
+func (server *Server) observe(stored *cluster) bool {
+	if stored.deleted {
+		if stored.pendingPolls == 0 {
+			server.removeCluster(stored.ID)
+			return true
+		}
+
+		stored.pendingPolls--
+
+		return false
+	}
+
+	if stored.pendingPolls > 0 {
+		stored.pendingPolls--
+	} else if stored.Status.State == godo.KubernetesClusterStatusProvisioning ||
+		stored.Status.State == godo.KubernetesClusterStatusUpgrading {
+
+		stored.Status = &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusRunning}
+		stored.UpdatedAt = now()
+	}
+
+	for _, nodePool := range stored.NodePools {
+		server.observeNodes(nodePool)
+	}
+
+	return false
+}
+
+func (server *Server) observeNodes(nodePool *godo.KubernetesNodePool) {
+	for _, node := range nodePool.Nodes {
+		polls, pending := server.pendingNodes[node.ID]
+
+		if !pending {
+			continue
+		}
+
+		if polls > 0 {
+			server.pendingNodes[node.ID] = polls - 1
+			continue
+		}
+
+		delete(server.pendingNodes, node.ID)
+		node.Status = &godo.KubernetesNodeStatus{State: nodeStateRunning}
+		node.UpdatedAt = now()
+	}
+}
+
+func (server *Server) handleClusters(writer http.ResponseWriter, request *http.Request, body []byte) {
+	switch request.Method {
+	case http.MethodGet:
+		clusters := []*godo.KubernetesCluster{}
+
+		for _, clusterID := range append([]string(nil), server.clusterIDs...) {
+			stored := server.clusters[clusterID]
+
+			if !server.observe(stored) {
+				clusters = append(clusters, &stored.KubernetesCluster)
+			}
+		}
+
+		start, end, links := server.paginate(request, len(clusters))
+
+		server.writeJSON(writer, http.StatusOK, map[string]interface{}{
+			"kubernetes_clusters": clusters[start:end],
+			"links":               links,
+			"meta":                godo.Meta{Total: len(clusters)},
+		})
+	case http.MethodPost:
+		server.createCluster(writer, body)
+	default:
+		server.writeError(writer, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (server *Server) createCluster(writer http.ResponseWriter, body []byte) {
+	createRequest := godo.KubernetesClusterCreateRequest{}
+
+	if err := json.Unmarshal(body, &createRequest); err != nil {
+		server.writeError(writer, http.StatusBadRequest, "unable to parse the request body")
+		return
+	}
+
+	if message := server.validateCluster(&createRequest); message != "" {
+		server.writeError(writer, http.StatusUnprocessableEntity, message)
+		return
+	}
+
+	id := server.newID()
+	created := now()
+
+	stored := &cluster{
+		KubernetesCluster: godo.KubernetesCluster{
+			ID:                id,
+			Name:              createRequest.Name,
+			RegionSlug:        createRequest.RegionSlug,
+			VersionSlug:       server.versionSlug(createRequest.VersionSlug),
+			ClusterSubnet:     "10.244.0.0/16",
+			ServiceSubnet:     "10.245.0.0/16",
+			IPv4:              fmt.Sprintf("203.0.113.%d", server.lastID%250+1),
+			Endpoint:          fmt.Sprintf("https://%s.k8s.ondigitalocean.com", id),
+			Tags:              createRequest.Tags,
+			VPCUUID:           createRequest.VPCUUID,
+			MaintenancePolicy: createRequest.MaintenancePolicy,
+			AutoUpgrade:       createRequest.AutoUpgrade,
+			Status: &godo.KubernetesClusterStatus{
+				State:   godo.KubernetesClusterStatusProvisioning,
+				Message: "Provisioning",
+			},
+			CreatedAt: created,
+			UpdatedAt: created,
+		},
+		pendingPolls: server.provisioningPolls,
+	}
+
+	if stored.VPCUUID == "" {
+		stored.VPCUUID = server.newID()
+	}
+
+	for _, nodePoolRequest := range createRequest.NodePools {
+		stored.NodePools = append(stored.NodePools, server.newNodePool(nodePoolRequest))
+	}
+
+	server.storeCluster(stored)
+
+	server.writeJSON(writer, http.StatusCreated, map[string]interface{}{"kubernetes_cluster": &stored.KubernetesCluster})
+}
+
+func (server *Server) validateCluster(createRequest *godo.KubernetesClusterCreateRequest) string {
+	switch {
+	case createRequest.Name == "":
+		return "name is required"
+	case !server.hasRegion(createRequest.RegionSlug):
+		return fmt.Sprintf("region %q is not valid", createRequest.RegionSlug)
+	case server.versionSlug(createRequest.VersionSlug) == "":
+		return fmt.Sprintf("version %q is not valid", createRequest.VersionSlug)
+	case len(createRequest.NodePools) == 0:
+		return "at least one node pool is required"
+	}
+
+	for _, stored := range server.clusters {
+		if !stored.deleted && stored.Name == createRequest.Name {
+			return fmt.Sprintf("a cluster named %q already exists", createRequest.Name)
+		}
+	}
+
+	for _, nodePoolRequest := range createRequest.NodePools {
+		if message := server.validateNodePool(nodePoolRequest); message != "" {
+			return message
+		}
+	}
+
+	return ""
+}
+
+func (server *Server) validateNodePool(nodePoolRequest *godo.KubernetesNodePoolCreateRequest) string {
+	switch {
+	case nodePoolRequest.Name == "":
+		return "node pool name is required"
+	case !server.hasSize(nodePoolRequest.Size):
+		return fmt.Sprintf("node pool size %q is not valid", nodePoolRequest.Size)
+	case nodePoolRequest.Count < 1:
+		return "node pool count must be at least 1"
+	case nodePoolRequest.AutoScale && nodePoolRequest.MinNodes > nodePoolRequest.MaxNodes:
+		return "node pool min_nodes cannot be greater than max_nodes"
+	}
+
+	return ""
+}
+
+func (server *Server) hasRegion(slug string) bool {
+	for _, region := range server.options.Regions {
+		if region.Slug == slug {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (server *Server) hasSize(slug string) bool {
+	for _, size := range server.options.Sizes {
+		if size.Slug == slug {
+			return true
+		}
+	}
+
+	return false
+}
+
+// versionSlug resolves the requested version, latest being the first one of
+// the options. It is empty for unknown versions.
+func (server *Server) versionSlug(slug string) string {
+	if slug == "latest" && len(server.options.Versions) > 0 {
+		return server.options.Versions[0].Slug
+	}
+
+	for _, version := range server.options.Versions {
+		if version.Slug == slug {
+			return slug
+		}
+	}
+
+	return ""
+}
+
+func (server *Server) newNodePool(nodePoolRequest *godo.KubernetesNodePoolCreateRequest) *godo.KubernetesNodePool {
+	nodePool := &godo.KubernetesNodePool{
+		ID:        server.newID(),
+		Name:      nodePoolRequest.Name,
+		Size:      nodePoolRequest.Size,
+		Count:     nodePoolRequest.Count,
+		Tags:      nodePoolRequest.Tags,
+		Labels:    nodePoolRequest.Labels,
+		AutoScale: nodePoolRequest.AutoScale,
+		MinNodes:  nodePoolRequest.MinNodes,
+		MaxNodes:  nodePoolRequest.MaxNodes,
+	}
+
+	for len(nodePool.Nodes) < nodePool.Count {
+		nodePool.Nodes = append(nodePool.Nodes, server.newNode(nodePool))
+	}
+
+	return nodePool
+}
+
+func (server *Server) newNode(nodePool *godo.KubernetesNodePool) *godo.KubernetesNode {
+	id := server.newID()
+	created := now()
+
+	server.pendingNodes[id] = server.provisioningPolls
+
+	return &godo.KubernetesNode{
+		ID:        id,
+		Name:      fmt.Sprintf("%s-%s", nodePool.Name, id[len(id)-5:]),
+		Status:    &godo.KubernetesNodeStatus{State: nodeStateProvisioning},
+		DropletID: strconv.Itoa(100000000 + server.lastID),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+}
+
+func (server *Server) handleCluster(writer http.ResponseWriter, request *http.Request, body []byte, clusterID string) {
+	if request.Method == http.MethodGet {
+		stored, ok := server.clusters[clusterID]
+
+		if !ok || server.observe(stored) {
+			server.writeNotFound(writer)
+			return
+		}
+
+		server.writeJSON(writer, http.StatusOK, map[string]interface{}{"kubernetes_cluster": &stored.KubernetesCluster})
+		return
+	}
+
+	stored := server.liveCluster(clusterID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	switch request.Method {
+	case http.MethodPut:
+		updateRequest := godo.KubernetesClusterUpdateRequest{}
+
+		if err := json.Unmarshal(body, &updateRequest); err != nil {
+			server.writeError(writer, http.StatusBadRequest, "unable to parse the request body")
+			return
+		}
+
+		if updateRequest.Name != "" {
+			stored.Name = updateRequest.Name
+		}
+
+		stored.Tags = updateRequest.Tags
+
+		if updateRequest.AutoUpgrade != nil {
+			stored.AutoUpgrade = *updateRequest.AutoUpgrade
+		}
+
+		if updateRequest.MaintenancePolicy != nil {
+			stored.MaintenancePolicy = updateRequest.MaintenancePolicy
+		}
+
+		stored.UpdatedAt = now()
+
+		server.writeJSON(writer, http.StatusAccepted, map[string]interface{}{"kubernetes_cluster": &stored.KubernetesCluster})
+	case http.MethodDelete:
+		stored.deleted = true
+		stored.pendingPolls = server.deletingPolls
+		stored.Status = &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusDeleted}
+
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		server.writeError(writer, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (server *Server) getKubeConfig(writer http.ResponseWriter, clusterID string) {
+	stored := server.liveCluster(clusterID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	name := fmt.Sprintf("do-%s-%s", stored.RegionSlug, stored.Name)
+	certificateAuthority := base64.StdEncoding.EncodeToString([]byte("fake certificate authority of " + stored.ID))
+
+	writer.Header().Set("Content-Type", "application/yaml")
+	fmt.Fprintf(writer, `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: %s
+    server: %s
+  name: %s
+contexts:
+- context:
+    cluster: %s
+    user: %s-admin
+  name: %s
+current-context: %s
+kind: Config
+preferences: {}
+users:
+- name: %s-admin
+  user:
+    token: %s
+`, certificateAuthority, stored.Endpoint, name, name, name, name, name, name, KubeConfigToken(stored.ID))
+}
+
+func (server *Server) upgradeCluster(writer http.ResponseWriter, body []byte, clusterID string) {
+	stored := server.liveCluster(clusterID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	upgradeRequest := godo.KubernetesClusterUpgradeRequest{}
+
+	if err := json.Unmarshal(body, &upgradeRequest); err != nil {
+		server.writeError(writer, http.StatusBadRequest, "unable to parse the request body")
+		return
+	}
+
+	version := server.versionSlug(upgradeRequest.VersionSlug)
+
+	if version == "" {
+		server.writeError(writer, http.StatusUnprocessableEntity,
+			fmt.Sprintf("version %q is not valid", upgradeRequest.VersionSlug))
+		return
+	}
+
+	stored.VersionSlug = version
+	stored.Status = &godo.KubernetesClusterStatus{State: godo.KubernetesClusterStatusUpgrading, Message: "Upgrading"}
+	stored.pendingPolls = server.provisioningPolls
+	stored.UpdatedAt = now()
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// getUpgrades lists the versions newer than the one of the cluster, the
+// options being sorted from the newest.
+func (server *Server) getUpgrades(writer http.ResponseWriter, clusterID string) {
+	stored := server.liveCluster(clusterID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	upgrades := []*godo.KubernetesVersion{}
+
+	for _, version := range server.options.Versions {
+		if version.Slug == stored.VersionSlug {
+			break
+		}
+
+		upgrades = append(upgrades, version)
+	}
+
+	server.writeJSON(writer, http.StatusOK, map[string]interface{}{"available_upgrade_versions": upgrades})
+}
+
+func (server *Server) handleNodePools(writer http.ResponseWriter, request *http.Request, body []byte, clusterID string) {
+	stored := server.liveCluster(clusterID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		for _, nodePool := range stored.NodePools {
+			server.observeNodes(nodePool)
+		}
+
+		start, end, links := server.paginate(request, len(stored.NodePools))
+
+		server.writeJSON(writer, http.StatusOK, map[string]interface{}{
+			"node_pools": stored.NodePools[start:end],
+			"links":      links,
+			"meta":       godo.Meta{Total: len(stored.NodePools)},
+		})
+	case http.MethodPost:
+		createRequest := godo.KubernetesNodePoolCreateRequest{}
+
+		if err := json.Unmarshal(body, &createRequest); err != nil {
+			server.writeError(writer, http.StatusBadRequest, "unable to parse the request body")
+			return
+		}
+
+		if message := server.validateNodePool(&createRequest); message != "" {
+			server.writeError(writer, http.StatusUnprocessableEntity, message)
+			return
+		}
+
+		nodePool := server.newNodePool(&createRequest)
+		stored.NodePools = append(stored.NodePools, nodePool)
+
+		server.writeJSON(writer, http.StatusCreated, map[string]interface{}{"node_pool": nodePool})
+	default:
+		server.writeError(writer, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (server *Server) handleNodePool(writer http.ResponseWriter, request *http.Request, body []byte,
+	clusterID, nodePoolID string) {
+
+	stored := server.liveCluster(clusterID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	index, nodePool := findNodePool(stored, nodePoolID)
+
+	if nodePool == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		server.observeNodes(nodePool)
+		server.writeJSON(writer, http.StatusOK, map[string]interface{}{"node_pool": nodePool})
+	case http.MethodPut:
+		updateRequest := godo.KubernetesNodePoolUpdateRequest{}
+
+		if err := json.Unmarshal(body, &updateRequest); err != nil {
+			server.writeError(writer, http.StatusBadRequest, "unable to parse the request body")
+			return
+		}
+
+		if message := server.updateNodePool(nodePool, &updateRequest); message != "" {
+			server.writeError(writer, http.StatusUnprocessableEntity, message)
+			return
+		}
+
+		server.writeJSON(writer, http.StatusAccepted, map[string]interface{}{"node_pool": nodePool})
+	case http.MethodDelete:
+		if len(stored.NodePools) == 1 {
+			server.writeError(writer, http.StatusUnprocessableEntity, "a cluster needs at least one node pool")
+			return
+		}
+
+		stored.NodePools = append(stored.NodePools[:index], stored.NodePools[index+1:]...)
+
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		server.writeError(writer, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (server *Server) updateNodePool(nodePool *godo.KubernetesNodePool,
+	updateRequest *godo.KubernetesNodePoolUpdateRequest) string {
+
+	autoScale, minNodes, maxNodes := nodePool.AutoScale, nodePool.MinNodes, nodePool.MaxNodes
+
+	if updateRequest.AutoScale != nil {
+		autoScale = *updateRequest.AutoScale
+	}
+
+	if updateRequest.MinNodes != nil {
+		minNodes = *updateRequest.MinNodes
+	}
+
+	if updateRequest.MaxNodes != nil {
+		maxNodes = *updateRequest.MaxNodes
+	}
+
+	if autoScale && minNodes > maxNodes {
+		return "node pool min_nodes cannot be greater than max_nodes"
+	}
+
+	if updateRequest.Count != nil && *updateRequest.Count < 1 {
+		return "node pool count must be at least 1"
+	}
+
+	if updateRequest.Name != "" {
+		nodePool.Name = updateRequest.Name
+	}
+
+	nodePool.Tags = updateRequest.Tags
+	nodePool.Labels = updateRequest.Labels
+	nodePool.AutoScale, nodePool.MinNodes, nodePool.MaxNodes = autoScale, minNodes, maxNodes
+
+	if updateRequest.Count != nil {
+		nodePool.Count = *updateRequest.Count
+
+		for len(nodePool.Nodes) < nodePool.Count {
+			nodePool.Nodes = append(nodePool.Nodes, server.newNode(nodePool))
+		}
+
+		for _, node := range nodePool.Nodes[nodePool.Count:] {
+			delete(server.pendingNodes, node.ID)
+		}
+
+		nodePool.Nodes = nodePool.Nodes[:nodePool.Count]
+	}
+
+	return ""
+}
+
+// handleNode deletes a node. A replaced node is swapped for a new one that
+// provisions like the nodes of a new cluster.
+func (server *Server) handleNode(writer http.ResponseWriter, request *http.Request, clusterID, nodePoolID, nodeID string) {
+	if request.Method != http.MethodDelete {
+		server.writeError(writer, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	stored := server.liveCluster(clusterID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	_, nodePool := findNodePool(stored, nodePoolID)
+
+	if nodePool == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	for index, node := range nodePool.Nodes {
+		if node.ID != nodeID {
+			continue
+		}
+
+		delete(server.pendingNodes, node.ID)
+		nodePool.Nodes = append(nodePool.Nodes[:index:index], nodePool.Nodes[index+1:]...)
+
+		if isSet(request.URL.Query(), "replace") {
+			nodePool.Nodes = append(nodePool.Nodes, server.newNode(nodePool))
+		} else {
+			nodePool.Count--
+		}
+
+		writer.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	server.writeNotFound(writer)
+}
+
+func findNodePool(stored *cluster, nodePoolID string) (int, *godo.KubernetesNodePool) {
+	for index, nodePool := range stored.NodePools {
+		if nodePool.ID == nodePoolID {
+			return index, nodePool
+		}
+	}
+
+	return -1, nil
+}
+
+func isSet(query url.Values, name string) bool {
+	value := query.Get(name)
+	return value == "1" || value == "true"
+}
+
+// paginate returns the bounds of the requested page and the links godo
+// follows to the next ones.
+func (server *Server) paginate(request *http.Request, total int) (int, int, *godo.Links) {
+	page, _ := strconv.Atoi(request.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(request.URL.Query().Get("per_page"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+
+	start := (page - 1) * perPage
+	end := start + perPage
+
+	if start > total {
+		start = total
+	}
+
+	if end > total {
+		end = total
+	}
+
+	pageURL := func(page int) string {
+		return fmt.Sprintf("%s%s?page=%d&per_page=%d", server.URL, request.URL.Path, page, perPage)
+	}
+
+	pages := &godo.Pages{}
+	lastPage := (total + perPage - 1) / perPage
+
+	if page > 1 {
+		pages.First = pageURL(1)
+		pages.Prev = pageURL(page - 1)
+	}
+
+	if page < lastPage {
+		pages.Next = pageURL(page + 1)
+		pages.Last = pageURL(lastPage)
+	}
+
+	return start, end, &godo.Links{Pages: pages}
+}
+
+func (server *Server) writeNotFound(writer http.ResponseWriter) {
+	server.writeError(writer, http.StatusNotFound, "The resource you were accessing could not be found.")
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
@@ -0,0 +1,132 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// project is a project kept by the server with the URNs assigned to it.
+type project struct {
+	godo.Project
+	resources []string
+}
+
+// AddProject stores a project and returns its ID, generated when empty. The
+// first project added is the default one.
+func (server *Server) AddProject(projectToAdd godo.Project) string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if projectToAdd.ID == "" {
+		projectToAdd.ID = server.newID()
+	}
+
+	projectToAdd.IsDefault = len(server.projectIDs) == 0
+	server.projects[projectToAdd.ID] = &project{Project: projectToAdd}
+	server.projectIDs = append(server.projectIDs, projectToAdd.ID)
+
+	return projectToAdd.ID
+}
+
+// ProjectResources returns the URNs assigned to the project.
+func (server *Server) ProjectResources(projectID string) []string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	stored, ok := server.projects[projectID]
+
+	if !ok {
+		return nil
+	}
+
+	return append([]string(nil), stored.resources...)
+}
+
+func (server *Server) listProjects(writer http.ResponseWriter, request *http.Request) {
+	start, end, links := server.paginate(request, len(server.projectIDs))
+	projects := []godo.Project{}
+
+	for _, projectID := range server.projectIDs[start:end] {
+		projects = append(projects, server.projects[projectID].Project)
+	}
+
+	server.writeJSON(writer, http.StatusOK, map[string]interface{}{
+		"projects": projects,
+		"links":    links,
+		"meta":     godo.Meta{Total: len(server.projectIDs)},
+	})
+}
+
+func (server *Server) getProject(writer http.ResponseWriter, projectID string) {
+	stored := server.findProject(projectID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	server.writeJSON(writer, http.StatusOK, map[string]interface{}{"project": stored.Project})
+}
+
+// assignResources moves the URNs to the project, out of any other one.
+func (server *Server) assignResources(writer http.ResponseWriter, body []byte, projectID string) {
+	stored := server.findProject(projectID)
+
+	if stored == nil {
+		server.writeNotFound(writer)
+		return
+	}
+
+	assignRequest := struct {
+		Resources []string `json:"resources"`
+	}{}
+
+	if err := json.Unmarshal(body, &assignRequest); err != nil || len(assignRequest.Resources) == 0 {
+		server.writeError(writer, http.StatusUnprocessableEntity, "resources is invalid")
+		return
+	}
+
+	assigned := []godo.ProjectResource{}
+
+	for _, urn := range assignRequest.Resources {
+		for _, other := range server.projects {
+			other.resources = without(other.resources, urn)
+		}
+
+		stored.resources = append(stored.resources, urn)
+		assigned = append(assigned, godo.ProjectResource{URN: urn, AssignedAt: now().Format(time.RFC3339),
+			Status: "ok"})
+	}
+
+	server.writeJSON(writer, http.StatusOK, map[string]interface{}{"resources": assigned})
+}
+
+// findProject resolves the default alias the API accepts in place of an ID.
+func (server *Server) findProject(projectID string) *project {
+	if projectID == godo.DefaultProject {
+		for _, stored := range server.projects {
+			if stored.IsDefault {
+				return stored
+			}
+		}
+
+		return nil
+	}
+
+	return server.projects[projectID]
+}
+
+func without(values []string, value string) []string {
+	kept := values[:0]
+
+	for _, current := range values {
+		if current != value {
+			kept = append(kept, current)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,510 @@
+// Package fake is an in-process fake of the DigitalOcean Kubernetes API for
+// integration tests. It keeps clusters, node pools, nodes and projects in
+// memory, walks them through the states DigitalOcean reports and fails calls
+// on demand.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+const (
+	defaultProvisioningPolls = 2
+	defaultDeletingPolls     = 1
+	rateLimit                = 5000
+)
+
+// Account is what the account and droplets endpoints report.
+type Account struct {
+	UUID          string
+	Email         string
+	Status        string
+	StatusMessage string
+	DropletLimit  int
+	// DropletCount counts droplets outside the fake clusters, the nodes of
+	// the clusters are added to it.
+	DropletCount int
+	TeamUUID     string
+	TeamName     string
+}
+
+// Failure makes the server answer matching requests with an API error.
+type Failure struct {
+	// Method to match, any method when empty.
+	Method string
+	// Path prefix to match, such as /v2/kubernetes/clusters, any path when
+	// empty.
+	Path    string
+	Status  int
+	Message string
+	// Times the failure is returned before requests succeed again, forever
+	// when zero.
+	Times int
+	// RetryAfter is announced through the rate limit reset header of 429
+	// responses.
+	RetryAfter time.Duration
+}
+
+func (failure *Failure) matches(request *http.Request) bool {
+	if failure.Method != "" && failure.Method != request.Method {
+		return false
+	}
+
+	return strings.HasPrefix(request.URL.Path, failure.Path)
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Decode unmarshals the JSON body of the request.
+func (request Request) Decode(value interface{}) error {
+	return json.Unmarshal(request.Body, value)
+}
+
+// Server is the fake API. Point a godo client at its URL with a trailing
+// slash. New clusters and replacement nodes stay provisioning for a few reads
+// before they are running, deleted clusters are reported as deleted for a
+// few reads before they answer 404.
+type Server struct {
+	*httptest.Server
+
+	mutex             sync.Mutex
+	provisioningPolls int
+	deletingPolls     int
+	token             string
+	account           Account
+	options           *godo.KubernetesOptions
+	clusters          map[string]*cluster
+	clusterIDs        []string
+	projects          map[string]*project
+	sizePrices        map[string]float64
+	projectIDs        []string
+	pendingNodes      map[string]int
+	failures          []*Failure
+	requests          []Request
+	lastID            int
+	remaining         int
+}
+
+// NewServer starts a fake API with an empty account and the default
+// Kubernetes options. Close it when done.
+func NewServer() *Server {
+	server := &Server{
+		provisioningPolls: defaultProvisioningPolls,
+		deletingPolls:     defaultDeletingPolls,
+		account: Account{
+			UUID:         "b6fr89dbf6d9156cace5f3c78dc9851d957381ef",
+			Email:        "sammy@digitalocean.com",
+			Status:       "active",
+			DropletLimit: 25,
+		},
+		options:      defaultOptions(),
+		clusters:     map[string]*cluster{},
+		projects:     map[string]*project{},
+		sizePrices:   defaultSizePrices(),
+		pendingNodes: map[string]int{},
+		remaining:    rateLimit,
+	}
+
+	server.Server = httptest.NewServer(server)
+
+	return server
+}
+
+func defaultOptions() *godo.KubernetesOptions {
+	return &godo.KubernetesOptions{
+		Versions: []*godo.KubernetesVersion{
+			{Slug: "1.18.3-do.0", KubernetesVersion: "1.18.3"},
+			{Slug: "1.17.6-do.0", KubernetesVersion: "1.17.6"},
+			{Slug: "1.16.10-do.0", KubernetesVersion: "1.16.10"},
+		},
+		Regions: []*godo.KubernetesRegion{
+			{Name: "New York 1", Slug: "nyc1"},
+			{Name: "San Francisco 2", Slug: "sfo2"},
+			{Name: "Amsterdam 3", Slug: "ams3"},
+			{Name: "Frankfurt 1", Slug: "fra1"},
+		},
+		Sizes: []*godo.KubernetesNodeSize{
+			{Name: "s-1vcpu-2gb", Slug: "s-1vcpu-2gb"},
+			{Name: "s-2vcpu-4gb", Slug: "s-2vcpu-4gb"},
+			{Name: "s-4vcpu-8gb", Slug: "s-4vcpu-8gb"},
+		},
+	}
+}
+
+// defaultSizePrices are the monthly prices of the default node sizes.
+func defaultSizePrices() map[string]float64 {
+	return map[string]float64{
+		"s-1vcpu-2gb": 10,
+		"s-2vcpu-4gb": 20,
+		"s-4vcpu-8gb": 40,
+	}
+}
+
+// SetSizePrice sets the monthly price the sizes endpoint reports for a node
+// size.
+func (server *Server) SetSizePrice(slug string, priceMonthly float64) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.sizePrices[slug] = priceMonthly
+}
+
+// SetProvisioningPolls sets how many reads a cluster, an upgrade or a node
+// stays pending before it is running.
+func (server *Server) SetProvisioningPolls(polls int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.provisioningPolls = polls
+}
+
+// SetDeletingPolls sets how many reads a deleted cluster is still found.
+func (server *Server) SetDeletingPolls(polls int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.deletingPolls = polls
+}
+
+// SetToken makes the server answer 401 to requests without this bearer
+// token. Any token is accepted by default.
+func (server *Server) SetToken(token string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.token = token
+}
+
+func (server *Server) SetAccount(account Account) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.account = account
+}
+
+func (server *Server) SetOptions(options *godo.KubernetesOptions) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.options = options
+}
+
+// Fail registers a failure. Failures are checked in the order they were
+// registered.
+func (server *Server) Fail(failure Failure) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.failures = append(server.failures, &failure)
+}
+
+func (server *Server) ClearFailures() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.failures = nil
+}
+
+// Requests returns every request received so far.
+func (server *Server) Requests() []Request {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return append([]Request(nil), server.requests...)
+}
+
+// RequestsTo returns the requests received with the method and path.
+func (server *Server) RequestsTo(method, path string) []Request {
+	requests := []Request{}
+
+	for _, request := range server.Requests() {
+		if request.Method == method && request.Path == path {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests
+}
+
+func (server *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requests = append(server.requests, Request{
+		Method: request.Method,
+		Path:   request.URL.Path,
+		Query:  request.URL.Query(),
+		Header: request.Header.Clone(),
+		Body:   body,
+	})
+
+	if server.remaining > 0 {
+		server.remaining--
+	}
+
+	writer.Header().Set("RateLimit-Limit", strconv.Itoa(rateLimit))
+	writer.Header().Set("RateLimit-Remaining", strconv.Itoa(server.remaining))
+	writer.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+
+	if server.token != "" && request.Header.Get("Authorization") != "Bearer "+server.token {
+		server.writeError(writer, http.StatusUnauthorized, "Unable to authenticate you")
+		return
+	}
+
+	if failure := server.nextFailure(request); failure != nil {
+		if failure.Status == http.StatusTooManyRequests {
+			writer.Header().Set("RateLimit-Remaining", "0")
+			writer.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Add(failure.RetryAfter).Unix(), 10))
+		}
+
+		server.writeError(writer, failure.Status, failure.Message)
+		return
+	}
+
+	server.route(writer, request, body)
+}
+
+func (server *Server) nextFailure(request *http.Request) *Failure {
+	for index, failure := range server.failures {
+		if !failure.matches(request) {
+			continue
+		}
+
+		if failure.Times > 0 {
+			failure.Times--
+
+			if failure.Times == 0 {
+				server.failures = append(server.failures[:index], server.failures[index+1:]...)
+			}
+		}
+
+		return failure
+	}
+
+	return nil
+}
+
+func (server *Server) route(writer http.ResponseWriter, request *http.Request, body []byte) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(request.URL.Path, "/v2/"), "/"), "/")
+
+	if ids, ok := match(segments, "kubernetes", "clusters", "*", "node_pools", "*", "nodes", "*"); ok {
+		server.handleNode(writer, request, ids[0], ids[1], ids[2])
+		return
+	}
+
+	if ids, ok := match(segments, "kubernetes", "clusters", "*", "node_pools", "*"); ok {
+		server.handleNodePool(writer, request, body, ids[0], ids[1])
+		return
+	}
+
+	if ids, ok := match(segments, "kubernetes", "clusters", "*", "node_pools"); ok {
+		server.handleNodePools(writer, request, body, ids[0])
+		return
+	}
+
+	if ids, ok := match(segments, "kubernetes", "clusters", "*", "kubeconfig"); ok && request.Method == http.MethodGet {
+		server.getKubeConfig(writer, ids[0])
+		return
+	}
+
+	if ids, ok := match(segments, "kubernetes", "clusters", "*", "upgrade"); ok && request.Method == http.MethodPost {
+		server.upgradeCluster(writer, body, ids[0])
+		return
+	}
+
+	if ids, ok := match(segments, "kubernetes", "clusters", "*", "upgrades"); ok && request.Method == http.MethodGet {
+		server.getUpgrades(writer, ids[0])
+		return
+	}
+
+	if ids, ok := match(segments, "kubernetes", "clusters", "*"); ok {
+		server.handleCluster(writer, request, body, ids[0])
+		return
+	}
+
+	if _, ok := match(segments, "kubernetes", "clusters"); ok {
+		server.handleClusters(writer, request, body)
+		return
+	}
+
+	if _, ok := match(segments, "kubernetes", "options"); ok && request.Method == http.MethodGet {
+		server.writeJSON(writer, http.StatusOK, map[string]interface{}{"options": server.options})
+		return
+	}
+
+	if ids, ok := match(segments, "projects", "*", "resources"); ok && request.Method == http.MethodPost {
+		server.assignResources(writer, body, ids[0])
+		return
+	}
+
+	if ids, ok := match(segments, "projects", "*"); ok && request.Method == http.MethodGet {
+		server.getProject(writer, ids[0])
+		return
+	}
+
+	if _, ok := match(segments, "projects"); ok && request.Method == http.MethodGet {
+		server.listProjects(writer, request)
+		return
+	}
+
+	if _, ok := match(segments, "account"); ok && request.Method == http.MethodGet {
+		server.getAccount(writer)
+		return
+	}
+
+	if _, ok := match(segments, "droplets"); ok && request.Method == http.MethodGet {
+		server.listDroplets(writer)
+		return
+	}
+
+	if _, ok := match(segments, "sizes"); ok && request.Method == http.MethodGet {
+		server.listSizes(writer, request)
+		return
+	}
+
+	server.writeError(writer, http.StatusNotFound, "The resource you were accessing could not be found.")
+}
+
+// match compares the path segments with a pattern where * matches any
+// segment, and returns the matched segments.
+func match(segments []string, pattern ...string) ([]string, bool) {
+	if len(segments) != len(pattern) {
+		return nil, false
+	}
+
+	values := []string{}
+
+	for index, segment := range pattern {
+		if segment == "*" {
+			values = append(values, segments[index])
+			continue
+		}
+
+		if segment != segments[index] {
+			return nil, false
+		}
+	}
+
+	return values, true
+}
+
+func (server *Server) getAccount(writer http.ResponseWriter) {
+	account := map[string]interface{}{
+		"uuid":           server.account.UUID,
+		"email":          server.account.Email,
+		"status":         server.account.Status,
+		"status_message": server.account.StatusMessage,
+		"droplet_limit":  server.account.DropletLimit,
+		"email_verified": true,
+	}
+
+	if server.account.TeamUUID != "" {
+		account["team"] = map[string]string{"uuid": server.account.TeamUUID, "name": server.account.TeamName}
+	}
+
+	server.writeJSON(writer, http.StatusOK, map[string]interface{}{"account": account})
+}
+
+func (server *Server) listDroplets(writer http.ResponseWriter) {
+	total := server.account.DropletCount
+
+	for _, cluster := range server.clusters {
+		for _, nodePool := range cluster.NodePools {
+			total += len(nodePool.Nodes)
+		}
+	}
+
+	server.writeJSON(writer, http.StatusOK, map[string]interface{}{
+		"droplets": []interface{}{},
+		"links":    map[string]interface{}{},
+		"meta":     godo.Meta{Total: total},
+	})
+}
+
+// listSizes reports the node sizes of the Kubernetes options as droplet
+// sizes with their price.
+func (server *Server) listSizes(writer http.ResponseWriter, request *http.Request) {
+	sizes := []godo.Size{}
+
+	for _, size := range server.options.Sizes {
+		sizes = append(sizes, godo.Size{
+			Slug:         size.Slug,
+			PriceMonthly: server.sizePrices[size.Slug],
+			PriceHourly:  server.sizePrices[size.Slug] / 672,
+			Available:    true,
+		})
+	}
+
+	start, end, links := server.paginate(request, len(sizes))
+
+	server.writeJSON(writer, http.StatusOK, map[string]interface{}{
+		"sizes": sizes[start:end],
+		"links": links,
+		"meta":  godo.Meta{Total: len(sizes)},
+	})
+}
+
+func (server *Server) newID() string {
+	server.lastID++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", server.lastID)
+}
+
+func (server *Server) writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(value)
+}
+
+func (server *Server) writeError(writer http.ResponseWriter, status int, message string) {
+	requestID := server.newID()
+
+	writer.Header().Set("X-Request-Id", requestID)
+	server.writeJSON(writer, status, map[string]string{
+		"id":         errorID(status),
+		"message":    message,
+		"request_id": requestID,
+	})
+}
+
+func errorID(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusUnprocessableEntity:
+		return "unprocessable_entity"
+	case http.StatusTooManyRequests:
+		return "too_many_requests"
+	}
+
+	if status >= http.StatusInternalServerError {
+		return "server_error"
+	}
+
+	return "bad_request"
+}
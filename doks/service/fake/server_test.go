@@ -0,0 +1,119 @@
+package fake
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClient(server *Server) *godo.Client {
+	client := godo.NewClient(http.DefaultClient)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	return client
+}
+
+func TestListClustersPaginates(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	for _, name := range []string{"first", "second", "third"} {
+		server.AddCluster(&godo.KubernetesCluster{Name: name, RegionSlug: "nyc1"})
+	}
+
+	clusters, response, err := newClient(server).Kubernetes.List(context.TODO(), &godo.ListOptions{Page: 2, PerPage: 2})
+
+	assert.NoError(t, err)
+	assert.Len(t, clusters, 1, "Last page")
+	assert.Equal(t, "third", clusters[0].Name, "Clusters kept in creation order")
+	assert.True(t, response.Links.IsLastPage(), "No next page")
+
+	page, err := response.Links.CurrentPage()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page, "Current page read from the links")
+	assert.Equal(t, 3, response.Meta.Total, "Total equals")
+}
+
+func TestAddClusterIsRunning(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	clusterID := server.AddCluster(&godo.KubernetesCluster{
+		Name:      "existing",
+		NodePools: []*godo.KubernetesNodePool{{Name: "pool", Size: "s-1vcpu-2gb", Count: 2}},
+	})
+
+	cluster, _, err := newClient(server).Kubernetes.Get(context.TODO(), clusterID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, godo.KubernetesClusterStatusRunning, cluster.Status.State, "Seeded cluster running")
+	assert.Len(t, cluster.NodePools[0].Nodes, 2, "Nodes generated")
+	assert.Equal(t, "running", cluster.NodePools[0].Nodes[0].Status.State, "Seeded nodes running")
+}
+
+func TestUpgradesAndFailuresCount(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	clusterID := server.AddCluster(&godo.KubernetesCluster{Name: "old", VersionSlug: "1.16.10-do.0"})
+	client := newClient(server)
+
+	server.Fail(Failure{Method: http.MethodGet, Path: "/v2/kubernetes/clusters/" + clusterID + "/upgrades",
+		Status: http.StatusInternalServerError, Times: 1})
+
+	_, _, err := client.Kubernetes.GetUpgrades(context.TODO(), clusterID)
+	assert.Error(t, err, "Injected failure returned")
+
+	upgrades, _, err := client.Kubernetes.GetUpgrades(context.TODO(), clusterID)
+
+	assert.NoError(t, err, "Failure returned once")
+	assert.Len(t, upgrades, 2, "Newer versions listed")
+	assert.Len(t, server.Requests(), 2, "Requests recorded")
+}
+
+func TestScaleDownDropsNodes(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	clusterID := server.AddCluster(&godo.KubernetesCluster{
+		Name:      "scaled",
+		NodePools: []*godo.KubernetesNodePool{{Name: "pool", Size: "s-1vcpu-2gb", Count: 3}},
+	})
+	cluster, _ := server.Cluster(clusterID)
+	count := 1
+
+	nodePool, _, err := newClient(server).Kubernetes.UpdateNodePool(context.TODO(), clusterID, cluster.NodePools[0].ID,
+		&godo.KubernetesNodePoolUpdateRequest{Count: &count})
+
+	assert.NoError(t, err)
+	assert.Len(t, nodePool.Nodes, 1, "Nodes removed")
+	assert.Equal(t, cluster.NodePools[0].Nodes[0].ID, nodePool.Nodes[0].ID, "First node kept")
+}
+
+func TestAssignResourcesMovesThemBetweenProjects(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	defaultID := server.AddProject(godo.Project{Name: "default"})
+	teamID := server.AddProject(godo.Project{Name: "team-a"})
+	client := newClient(server)
+
+	_, _, err := client.Projects.AssignResources(context.TODO(), godo.DefaultProject, "do:kubernetes:abcd")
+	assert.NoError(t, err)
+
+	_, _, err = client.Projects.AssignResources(context.TODO(), teamID, "do:kubernetes:abcd")
+	assert.NoError(t, err)
+
+	assert.Empty(t, server.ProjectResources(defaultID), "Resource left the default project")
+	assert.Equal(t, []string{"do:kubernetes:abcd"}, server.ProjectResources(teamID), "Resource assigned")
+
+	_, response, err := client.Projects.Get(context.TODO(), "unknown")
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode, "Unknown project not found")
+}
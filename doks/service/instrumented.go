@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/kontainer-engine/store"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/metrics"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Outcome labels the metrics of a call with the kind of its error.
+func Outcome(err error) string {
+	if err == nil {
+		return metrics.OutcomeSuccess
+	}
+
+	if serviceError, ok := AsError(err); ok {
+		return string(serviceError.Kind)
+	}
+
+	return metrics.OutcomeError
+}
+
+// instrumentedDigitalOcean records the count, duration and outcome of every
+// call. The region label is taken from the context.
+type instrumentedDigitalOcean struct {
+	next DigitalOcean
+}
+
+func newInstrumentedDigitalOcean(next DigitalOcean) DigitalOcean {
+	return &instrumentedDigitalOcean{next: next}
+}
+
+// call is one instrumented call, traced as a child span of the driver
+// operation found in the context.
+type call struct {
+	ctx       context.Context
+	operation string
+	start     time.Time
+	span      trace.Span
+}
+
+func startCall(ctx context.Context, operation string, attributes ...attribute.KeyValue) *call {
+	ctx, span := tracing.StartSpan(ctx, "DigitalOcean."+operation, attributes...)
+
+	return &call{ctx: ctx, operation: operation, start: time.Now(), span: span}
+}
+
+func (c *call) end(err error) {
+	outcome := Outcome(err)
+
+	metrics.ObserveAPICall(c.ctx, c.operation, outcome, c.start)
+	tracing.End(c.span, err)
+
+	entry := logging.FromContext(c.ctx).WithFields(logrus.Fields{
+		"call":     c.operation,
+		"duration": time.Since(c.start).String(),
+		"outcome":  outcome,
+	})
+
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+
+	entry.Debug("DigitalOcean call completed")
+}
+
+func (do *instrumentedDigitalOcean) CreateCluster(ctx context.Context, clusterState state.Cluster,
+	nodePoolState state.NodePool) (string, string, error) {
+
+	call := startCall(ctx, "CreateCluster", tracing.RegionKey.String(clusterState.RegionSlug))
+	clusterID, nodePoolID, err := do.next.CreateCluster(call.ctx, clusterState, nodePoolState)
+	call.end(err)
+
+	return clusterID, nodePoolID, err
+}
+
+func (do *instrumentedDigitalOcean) UpdateCluster(ctx context.Context, clusterID string, cluster state.Cluster) error {
+	call := startCall(ctx, "UpdateCluster", tracing.ClusterIDKey.String(clusterID))
+	err := do.next.UpdateCluster(call.ctx, clusterID, cluster)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) GetKubernetesClusterVersion(ctx context.Context, clusterID string) (string, error) {
+	call := startCall(ctx, "GetKubernetesClusterVersion", tracing.ClusterIDKey.String(clusterID))
+	version, err := do.next.GetKubernetesClusterVersion(call.ctx, clusterID)
+	call.end(err)
+
+	return version, err
+}
+
+func (do *instrumentedDigitalOcean) UpgradeKubernetesVersion(ctx context.Context, clusterID, version string) error {
+	call := startCall(ctx, "UpgradeKubernetesVersion", tracing.ClusterIDKey.String(clusterID))
+	err := do.next.UpgradeKubernetesVersion(call.ctx, clusterID, version)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) DeleteCluster(ctx context.Context, clusterID string) error {
+	call := startCall(ctx, "DeleteCluster", tracing.ClusterIDKey.String(clusterID))
+	err := do.next.DeleteCluster(call.ctx, clusterID)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) UpdateNodePool(ctx context.Context, clusterID, nodePoolID string,
+	nodePool state.NodePool) error {
+
+	call := startCall(ctx, "UpdateNodePool", tracing.ClusterIDKey.String(clusterID), tracing.NodePoolIDKey.String(nodePoolID))
+	err := do.next.UpdateNodePool(call.ctx, clusterID, nodePoolID, nodePool)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) CreateNodePool(ctx context.Context, clusterID string,
+	nodePool state.NodePool) (string, error) {
+
+	call := startCall(ctx, "CreateNodePool", tracing.ClusterIDKey.String(clusterID))
+	nodePoolID, err := do.next.CreateNodePool(call.ctx, clusterID, nodePool)
+	call.end(err)
+
+	return nodePoolID, err
+}
+
+func (do *instrumentedDigitalOcean) DeleteNodePool(ctx context.Context, clusterID, nodePoolID string) error {
+	call := startCall(ctx, "DeleteNodePool", tracing.ClusterIDKey.String(clusterID), tracing.NodePoolIDKey.String(nodePoolID))
+	err := do.next.DeleteNodePool(call.ctx, clusterID, nodePoolID)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) GetNodePool(ctx context.Context, clusterID, nodePoolID string) (*state.NodePool, error) {
+	call := startCall(ctx, "GetNodePool", tracing.ClusterIDKey.String(clusterID), tracing.NodePoolIDKey.String(nodePoolID))
+	nodePool, err := do.next.GetNodePool(call.ctx, clusterID, nodePoolID)
+	call.end(err)
+
+	return nodePool, err
+}
+
+func (do *instrumentedDigitalOcean) ListNodePools(ctx context.Context, clusterID string) ([]state.NodePool, error) {
+	call := startCall(ctx, "ListNodePools", tracing.ClusterIDKey.String(clusterID))
+	nodePools, err := do.next.ListNodePools(call.ctx, clusterID)
+	call.end(err)
+
+	return nodePools, err
+}
+
+func (do *instrumentedDigitalOcean) ListNodes(ctx context.Context, clusterID, nodePoolID string) ([]state.Node, error) {
+	call := startCall(ctx, "ListNodes", tracing.ClusterIDKey.String(clusterID), tracing.NodePoolIDKey.String(nodePoolID))
+	nodes, err := do.next.ListNodes(call.ctx, clusterID, nodePoolID)
+	call.end(err)
+
+	return nodes, err
+}
+
+func (do *instrumentedDigitalOcean) DeleteNode(ctx context.Context, clusterID, nodePoolID, nodeID string,
+	replace, skipDrain bool) error {
+
+	call := startCall(ctx, "DeleteNode", tracing.ClusterIDKey.String(clusterID),
+		tracing.NodePoolIDKey.String(nodePoolID), tracing.NodeIDKey.String(nodeID))
+	err := do.next.DeleteNode(call.ctx, clusterID, nodePoolID, nodeID, replace, skipDrain)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) GetKubeConfig(clusterID string) (*store.KubeConfig, error) {
+	call := startCall(context.Background(), "GetKubeConfig", tracing.ClusterIDKey.String(clusterID))
+	kubeConfig, err := do.next.GetKubeConfig(clusterID)
+	call.end(err)
+
+	return kubeConfig, err
+}
+
+func (do *instrumentedDigitalOcean) WaitClusterCreated(ctx context.Context, clusterID string) error {
+	call := startCall(ctx, "WaitClusterCreated", tracing.ClusterIDKey.String(clusterID))
+	err := do.next.WaitClusterCreated(call.ctx, clusterID)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) WaitClusterDeleted(ctx context.Context, clusterID string) error {
+	call := startCall(ctx, "WaitClusterDeleted", tracing.ClusterIDKey.String(clusterID))
+	err := do.next.WaitClusterDeleted(call.ctx, clusterID)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) WaitNodeReplaced(ctx context.Context, clusterID, nodePoolID, nodeID string) error {
+	call := startCall(ctx, "WaitNodeReplaced", tracing.ClusterIDKey.String(clusterID),
+		tracing.NodePoolIDKey.String(nodePoolID), tracing.NodeIDKey.String(nodeID))
+	err := do.next.WaitNodeReplaced(call.ctx, clusterID, nodePoolID, nodeID)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) GetAccount(ctx context.Context) (*state.Account, error) {
+	call := startCall(ctx, "GetAccount")
+	account, err := do.next.GetAccount(call.ctx)
+	call.end(err)
+
+	return account, err
+}
+
+func (do *instrumentedDigitalOcean) GetCluster(ctx context.Context, clusterID string) (*state.ClusterStatus, error) {
+	call := startCall(ctx, "GetCluster", tracing.ClusterIDKey.String(clusterID))
+	clusterStatus, err := do.next.GetCluster(call.ctx, clusterID)
+	call.end(err)
+
+	return clusterStatus, err
+}
+
+func (do *instrumentedDigitalOcean) FindProject(ctx context.Context, projectID, projectName string) (*state.Project, error) {
+	call := startCall(ctx, "FindProject")
+	project, err := do.next.FindProject(call.ctx, projectID, projectName)
+	call.end(err)
+
+	return project, err
+}
+
+func (do *instrumentedDigitalOcean) AssignClusterToProject(ctx context.Context, projectID, clusterID string) error {
+	call := startCall(ctx, "AssignClusterToProject", tracing.ClusterIDKey.String(clusterID))
+	err := do.next.AssignClusterToProject(call.ctx, projectID, clusterID)
+	call.end(err)
+
+	return err
+}
+
+func (do *instrumentedDigitalOcean) FindOrphans(ctx context.Context, ownerTag string, knownClusterIDs []string) ([]Orphan, error) {
+	call := startCall(ctx, "FindOrphans")
+	orphans, err := do.next.FindOrphans(call.ctx, ownerTag, knownClusterIDs)
+	call.end(err)
+
+	return orphans, err
+}
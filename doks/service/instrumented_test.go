@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/metrics"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, metrics.OutcomeSuccess, Outcome(nil))
+	assert.Equal(t, metrics.OutcomeError, Outcome(errors.New("failed")))
+	assert.Equal(t, "NotFound", Outcome(&Error{Kind: ErrorKindNotFound}))
+}
+
+func TestObserveRateLimit(t *testing.T) {
+	response := &http.Response{Header: http.Header{}}
+	response.Header.Set("RateLimit-Limit", "5000")
+	response.Header.Set("RateLimit-Remaining", "4321")
+
+	assert.NotPanics(t, func() {
+		observeRateLimit(nil, nil)
+		observeRateLimit(nil, &http.Response{Header: http.Header{}})
+		observeRateLimit(nil, response)
+	})
+}
+
+func TestInstrumentedCallSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	server, digitalOcean := newClusterServer(`{"kubernetes_cluster":{"id":"abcd","status":{"state":"running"}}}`)
+	defer server.Close()
+
+	ctx, driverSpan := tracing.StartSpan(context.Background(), "Driver.PostCheck")
+
+	_, err := newInstrumentedDigitalOcean(digitalOcean).GetCluster(ctx, "abcd")
+	driverSpan.End()
+
+	assert.NoError(t, err)
+
+	spans := map[string]*sdktrace.SpanSnapshot{}
+
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	call, ok := spans["DigitalOcean.GetCluster"]
+
+	if !assert.True(t, ok, "service call traced") {
+		return
+	}
+
+	assert.Equal(t, spans["Driver.PostCheck"].SpanContext.SpanID(), call.Parent.SpanID(), "child of the driver span")
+	assert.Contains(t, call.Attributes, tracing.ClusterIDKey.String("abcd"))
+	assert.Len(t, exporter.GetSpans(), 3, "the godo request is traced too")
+
+	for _, span := range exporter.GetSpans() {
+		if span != call && span.Name != "Driver.PostCheck" {
+			assert.Equal(t, call.SpanContext.SpanID(), span.Parent.SpanID(), "request is a child of the call")
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// Orphan is a cluster tagged as managed by the driver that no known cluster
+// refers to, left behind by a failed create or a removal Rancher did not
+// finish.
+type Orphan struct {
+	ID         string
+	Name       string
+	RegionSlug string
+	CreatedAt  time.Time
+	NodeCount  int
+	// MonthlyCost sums the monthly price of the nodes, zero for sizes
+	// without a known price.
+	MonthlyCost float64
+}
+
+// Age is how long the cluster has existed at the given time.
+func (orphan Orphan) Age(now time.Time) time.Duration {
+	return now.Sub(orphan.CreatedAt)
+}
+
+// FindOrphans lists the clusters of the account carrying the managed tag and
+// the owner tag whose ID is not among the known ones. Clusters the driver did
+// not tag, or another installation owns, are never reported.
+func (do digitalOceanImpl) FindOrphans(ctx context.Context, ownerTag string, knownClusterIDs []string) ([]Orphan, error) {
+	if ownerTag == "" {
+		return nil, NewValidationError("find orphans",
+			"no owner tag given, set "+state.OwnerTagEnv+" so clusters of other installations are left alone")
+	}
+
+	known := map[string]bool{}
+
+	for _, clusterID := range knownClusterIDs {
+		known[clusterID] = true
+	}
+
+	clusters, err := do.listClusters(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var prices map[string]float64
+	orphans := []Orphan{}
+
+	for _, cluster := range clusters {
+		if known[cluster.ID] || !hasTag(cluster.Tags, state.ManagedTag) || !hasTag(cluster.Tags, ownerTag) {
+			continue
+		}
+
+		if prices == nil {
+			prices, err = do.sizePrices(ctx)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		orphan := Orphan{
+			ID:         cluster.ID,
+			Name:       cluster.Name,
+			RegionSlug: cluster.RegionSlug,
+			CreatedAt:  cluster.CreatedAt,
+		}
+
+		for _, nodePool := range cluster.NodePools {
+			orphan.NodeCount += nodePool.Count
+			orphan.MonthlyCost += float64(nodePool.Count) * prices[nodePool.Size]
+		}
+
+		orphans = append(orphans, orphan)
+	}
+
+	return orphans, nil
+}
+
+func (do digitalOceanImpl) listClusters(ctx context.Context) ([]*godo.KubernetesCluster, error) {
+	clusters := []*godo.KubernetesCluster{}
+	listOptions := &godo.ListOptions{}
+
+	for {
+		kubernetesClusters, response, err := do.client.Kubernetes.List(ctx, listOptions)
+
+		if err != nil {
+			return nil, newError("list clusters", err)
+		}
+
+		clusters = append(clusters, kubernetesClusters...)
+
+		if response.Links == nil || response.Links.IsLastPage() {
+			return clusters, nil
+		}
+
+		page, err := response.Links.CurrentPage()
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error in list clusters pagination")
+		}
+
+		listOptions.Page = page + 1
+	}
+}
+
+// sizePrices reads the monthly price of the droplet sizes nodes run on.
+func (do digitalOceanImpl) sizePrices(ctx context.Context) (map[string]float64, error) {
+	prices := map[string]float64{}
+	listOptions := &godo.ListOptions{}
+
+	for {
+		sizes, response, err := do.client.Sizes.List(ctx, listOptions)
+
+		if err != nil {
+			return nil, newError("list sizes", err)
+		}
+
+		for _, size := range sizes {
+			prices[size.Slug] = size.PriceMonthly
+		}
+
+		if response.Links == nil || response.Links.IsLastPage() {
+			return prices, nil
+		}
+
+		page, err := response.Links.CurrentPage()
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error in list sizes pagination")
+		}
+
+		listOptions.Page = page + 1
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, current := range tags {
+		if current == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindOrphans(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	created := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+
+	ownerTag := "rancher-owner:install-a"
+
+	knownID := server.AddCluster(&godo.KubernetesCluster{Name: "known", RegionSlug: "nyc1",
+		Tags: []string{state.ManagedTag, ownerTag}})
+	server.AddCluster(&godo.KubernetesCluster{Name: "unmanaged", RegionSlug: "nyc1", Tags: []string{"team-a"}})
+	server.AddCluster(&godo.KubernetesCluster{Name: "unowned", RegionSlug: "nyc1", CreatedAt: created,
+		Tags: []string{state.ManagedTag}})
+	server.AddCluster(&godo.KubernetesCluster{Name: "other", RegionSlug: "nyc1", CreatedAt: created,
+		Tags: []string{state.ManagedTag, "rancher-owner:install-b"}})
+	orphanID := server.AddCluster(&godo.KubernetesCluster{Name: "orphan", RegionSlug: "ams3", CreatedAt: created,
+		Tags: []string{"k8s", state.ManagedTag, "rancher-cluster:orphan", ownerTag},
+		NodePools: []*godo.KubernetesNodePool{
+			{Name: "workers", Size: "s-2vcpu-4gb", Count: 3},
+			{Name: "custom", Size: "s-8vcpu-16gb", Count: 1},
+		}})
+
+	orphans, err := digitalOcean.FindOrphans(context.TODO(), ownerTag, []string{knownID})
+
+	assert.NoError(t, err)
+	assert.Len(t, orphans, 1, "Known, unmanaged and unowned clusters left out")
+	assert.Equal(t, orphanID, orphans[0].ID)
+	assert.Equal(t, "orphan", orphans[0].Name)
+	assert.Equal(t, "ams3", orphans[0].RegionSlug)
+	assert.Equal(t, 4, orphans[0].NodeCount, "Nodes of every node pool counted")
+	assert.Equal(t, float64(60), orphans[0].MonthlyCost, "Sizes without a price cost nothing")
+	assert.True(t, orphans[0].Age(time.Now()) >= 48*time.Hour, "Age from the creation time")
+}
+
+func TestFindOrphansWithoutOrphans(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.AddCluster(&godo.KubernetesCluster{Name: "unmanaged", RegionSlug: "nyc1"})
+
+	orphans, err := digitalOcean.FindOrphans(context.TODO(), "rancher-owner:install-a", nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, orphans)
+	assert.Empty(t, server.RequestsTo("GET", "/v2/sizes"), "Prices only read for orphans")
+}
+
+func TestFindOrphansWithoutOwnerTag(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	_, err := digitalOcean.FindOrphans(context.TODO(), "", nil)
+
+	apiError, ok := AsError(err)
+
+	assert.True(t, ok, "Owner tag required")
+	assert.Equal(t, ErrorKindValidation, apiError.Kind)
+	assert.Empty(t, server.RequestsTo("GET", "/v2/kubernetes/clusters"), "Clusters not listed")
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalocean/godo"
+	"github.com/pkg/errors"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// FindProject reads the project with the ID, or else the one with the name.
+// A missing project is a validation error, since it comes from the options.
+func (do digitalOceanImpl) FindProject(ctx context.Context, projectID, projectName string) (*state.Project, error) {
+	if projectID != "" {
+		project, _, err := do.client.Projects.Get(ctx, projectID)
+
+		if err != nil {
+			err = newError(fmt.Sprintf("get project %s", projectID), err)
+
+			if IsNotFound(err) {
+				return nil, projectNotFound(projectID)
+			}
+
+			return nil, err
+		}
+
+		return &state.Project{ID: project.ID, Name: project.Name}, nil
+	}
+
+	listOptions := &godo.ListOptions{}
+
+	for {
+		projects, response, err := do.client.Projects.List(ctx, listOptions)
+
+		if err != nil {
+			return nil, newError("list projects", err)
+		}
+
+		for _, project := range projects {
+			if project.Name == projectName {
+				return &state.Project{ID: project.ID, Name: project.Name}, nil
+			}
+		}
+
+		if response.Links == nil || response.Links.IsLastPage() {
+			return nil, projectNotFound(projectName)
+		}
+
+		page, err := response.Links.CurrentPage()
+
+		if err != nil {
+			return nil, errors.Wrap(err, "error in list projects pagination")
+		}
+
+		listOptions.Page = page + 1
+	}
+}
+
+// AssignClusterToProject moves the cluster out of its current project.
+func (do digitalOceanImpl) AssignClusterToProject(ctx context.Context, projectID, clusterID string) error {
+	_, _, err := do.client.Projects.AssignResources(ctx, projectID, clusterURN(clusterID))
+
+	if err != nil {
+		return newError(fmt.Sprintf("assign cluster %s to project %s", clusterID, projectID), err)
+	}
+
+	return nil
+}
+
+func projectNotFound(project string) error {
+	return NewValidationError("find project", fmt.Sprintf("project %s not found", project))
+}
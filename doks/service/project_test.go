@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindProject(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.AddProject(godo.Project{Name: "default"})
+
+	for index := 0; index < 30; index++ {
+		server.AddProject(godo.Project{Name: fmt.Sprintf("team-%d", index)})
+	}
+
+	projectID := server.AddProject(godo.Project{Name: "platform"})
+
+	project, err := digitalOcean.FindProject(context.TODO(), projectID, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "platform", project.Name, "Project read by ID")
+
+	project, err = digitalOcean.FindProject(context.TODO(), "", "platform")
+
+	assert.NoError(t, err)
+	assert.Equal(t, projectID, project.ID, "Project found by name on a later page")
+
+	_, err = digitalOcean.FindProject(context.TODO(), "unknown", "")
+
+	assert.True(t, IsValidation(err), "Unknown project ID rejected")
+
+	_, err = digitalOcean.FindProject(context.TODO(), "", "unknown")
+
+	assert.True(t, IsValidation(err), "Unknown project name rejected")
+}
+
+func TestAssignClusterToProject(t *testing.T) {
+	server, digitalOcean, _ := newFakeDigitalOcean(t)
+	defer server.Close()
+
+	server.AddProject(godo.Project{Name: "default"})
+	projectID := server.AddProject(godo.Project{Name: "platform"})
+
+	assert.NoError(t, digitalOcean.AssignClusterToProject(context.TODO(), projectID, "abcd"))
+	assert.Equal(t, []string{"do:kubernetes:abcd"}, server.ProjectResources(projectID), "Cluster URN assigned")
+
+	err := digitalOcean.AssignClusterToProject(context.TODO(), "unknown", "abcd")
+
+	assert.True(t, IsNotFound(err), "Unknown project reported")
+}
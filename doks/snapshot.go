@@ -0,0 +1,209 @@
+package doks
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/rancher/kontainer-engine/store"
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/backup"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"k8s.io/client-go/rest"
+)
+
+// snapshotClients builds the clients needed by the ETCD operations. DOKS does
+// not expose etcd, so snapshots are backups of the Kubernetes resources kept in
+// an S3 compatible bucket.
+func (driver *Driver) snapshotClients(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions) (
+	state.Cluster, state.Backup, backup.Store, error) {
+
+	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildClusterStateFromClusterInfo in snapshotClients")
+		return state.Cluster{}, state.Backup{}, nil, err
+	}
+
+	backupState := driver.stateBuilder.BuildBackupFromOpts(opts)
+
+	backupStore, err := driver.backupStoreFactory(backupState)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error backupStoreFactory in snapshotClients")
+		return state.Cluster{}, state.Backup{}, nil, err
+	}
+
+	return clusterState, backupState, backupStore, nil
+}
+
+func (driver *Driver) clusterResources(ctx context.Context, clusterState state.Cluster) (backup.Resources, error) {
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
+
+	kubeConfig, err := digitalOceanService.GetKubeConfig(clusterState.ClusterID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error get kubeConfig in clusterResources")
+		return nil, err
+	}
+
+	restConfig, err := buildRestConfig(kubeConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.backupResourcesFactory(restConfig)
+}
+
+func (driver *Driver) saveSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) error {
+
+	clusterState, backupState, backupStore, err := driver.snapshotClients(ctx, clusterInfo, opts)
+
+	if err != nil {
+		return err
+	}
+
+	ctx = clusterContext(ctx, clusterState)
+
+	key, err := snapshotKey(backupState, clusterState, snapshotName)
+
+	if err != nil {
+		return err
+	}
+
+	resources, err := driver.clusterResources(ctx, clusterState)
+
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(resources.Export(writer))
+	}()
+
+	err = backupStore.Upload(ctx, key, reader)
+
+	reader.Close()
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("snapshot", snapshotName).Debug("Error upload snapshot")
+		return err
+	}
+
+	return nil
+}
+
+func (driver *Driver) restoreSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) error {
+
+	clusterState, backupState, backupStore, err := driver.snapshotClients(ctx, clusterInfo, opts)
+
+	if err != nil {
+		return err
+	}
+
+	ctx = clusterContext(ctx, clusterState)
+
+	key, err := snapshotKey(backupState, clusterState, snapshotName)
+
+	if err != nil {
+		return err
+	}
+
+	resources, err := driver.clusterResources(ctx, clusterState)
+
+	if err != nil {
+		return err
+	}
+
+	body, err := backupStore.Download(ctx, key)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("snapshot", snapshotName).Debug("Error download snapshot")
+		return err
+	}
+
+	defer body.Close()
+
+	err = resources.Import(body)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("snapshot", snapshotName).Debug("Error import snapshot")
+		return err
+	}
+
+	return nil
+}
+
+func (driver *Driver) removeSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo,
+	opts *types.DriverOptions, snapshotName string) error {
+
+	clusterState, backupState, backupStore, err := driver.snapshotClients(ctx, clusterInfo, opts)
+
+	if err != nil {
+		return err
+	}
+
+	ctx = clusterContext(ctx, clusterState)
+
+	key, err := snapshotKey(backupState, clusterState, snapshotName)
+
+	if err != nil {
+		return err
+	}
+
+	err = backupStore.Delete(ctx, key)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("snapshot", snapshotName).Debug("Error delete snapshot")
+		return err
+	}
+
+	return nil
+}
+
+// snapshotKey places a snapshot under the folder of its cluster. Names that
+// could reach out of that folder are rejected.
+func snapshotKey(backupState state.Backup, clusterState state.Cluster, snapshotName string) (string, error) {
+	if snapshotName == "" || strings.ContainsAny(snapshotName, `/\`) || strings.Contains(snapshotName, "..") {
+		return "", service.NewValidationError("snapshot key", fmt.Sprintf("invalid snapshot name %q", snapshotName))
+	}
+
+	return path.Join(backupState.Folder, clusterState.ClusterID, snapshotName+".tar.gz"), nil
+}
+
+func buildRestConfig(kubeConfig *store.KubeConfig) (*rest.Config, error) {
+	if len(kubeConfig.Clusters) == 0 {
+		return nil, errors.New("the kubeconfig file is invalid. Cluster not found")
+	}
+
+	if len(kubeConfig.Users) == 0 {
+		return nil, errors.New("the kubeconfig file is invalid. Token not found")
+	}
+
+	cluster := kubeConfig.Clusters[0].Cluster
+
+	caData, err := base64.StdEncoding.DecodeString(cluster.CertificateAuthorityData)
+
+	if err != nil {
+		return nil, errors.New("the kubeconfig file is invalid. Certificate authority is not base64")
+	}
+
+	return &rest.Config{
+		Host:        cluster.Server,
+		BearerToken: kubeConfig.Users[0].User.Token,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: caData,
+		},
+	}, nil
+}
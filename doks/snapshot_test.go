@@ -0,0 +1,189 @@
+package doks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/store"
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/backup"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+type backupStoreStub struct {
+	objects map[string][]byte
+}
+
+func (s *backupStoreStub) Upload(_ context.Context, key string, body io.Reader) error {
+	content, err := ioutil.ReadAll(body)
+	s.objects[key] = content
+	return err
+}
+
+func (s *backupStoreStub) Download(_ context.Context, key string) (io.ReadCloser, error) {
+	content, ok := s.objects[key]
+
+	if !ok {
+		return nil, errors.New("snapshot not found")
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *backupStoreStub) Delete(_ context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+type backupResourcesStub struct {
+	imported []byte
+}
+
+func (r *backupResourcesStub) Export(writer io.Writer) error {
+	_, err := writer.Write([]byte("cluster resources"))
+	return err
+}
+
+func (r *backupResourcesStub) Import(reader io.Reader) error {
+	content, err := ioutil.ReadAll(reader)
+	r.imported = content
+	return err
+}
+
+var snapshotKubeConfig = &store.KubeConfig{
+	Clusters: []store.ConfigCluster{
+		{Cluster: store.DataCluster{Server: "https://abcd.k8s.ondigitalocean.com", CertificateAuthorityData: "Y2VydA=="}},
+	},
+	Users: []store.ConfigUser{
+		{User: store.UserData{Token: "kube-token"}},
+	},
+}
+
+func newSnapshotDriver(backupStore *backupStoreStub, resources *backupResourcesStub) (Driver, *StateBuilderMock, *DigitalOceanMock) {
+	returnState := state.Cluster{Token: "a405b7bd3e0d", ClusterID: "abcd"}
+	returnBackup := state.Backup{Bucket: "backups", Folder: "rancher"}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return returnState, nil
+		},
+		buildBackupFromOptsMock: func(_ *types.DriverOptions) state.Backup {
+			return returnBackup
+		},
+	}
+
+	digitalOceanMock := &DigitalOceanMock{
+		getKubeConfigMock: func(_ string) (*store.KubeConfig, error) {
+			return snapshotKubeConfig, nil
+		},
+	}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", &types.ClusterInfo{}).Return(returnState)
+	stateBuilderMock.On("BuildBackupFromOpts", &types.DriverOptions{}).Return(returnBackup)
+	digitalOceanMock.On("GetKubeConfig", "abcd").Return(snapshotKubeConfig, nil)
+
+	driver := Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean { return digitalOceanMock },
+		backupStoreFactory: func(_ state.Backup) (backup.Store, error) {
+			return backupStore, nil
+		},
+		backupResourcesFactory: func(restConfig *rest.Config) (backup.Resources, error) {
+			return resources, nil
+		},
+	}
+
+	return driver, stateBuilderMock, digitalOceanMock
+}
+
+func TestETCDSaveUploadsResources(t *testing.T) {
+	backupStore := &backupStoreStub{objects: map[string][]byte{}}
+	driver, stateBuilderMock, digitalOceanMock := newSnapshotDriver(backupStore, &backupResourcesStub{})
+
+	err := driver.ETCDSave(context.TODO(), &types.ClusterInfo{}, &types.DriverOptions{}, "snapshot-1")
+
+	stateBuilderMock.AssertExpectations(t)
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err, "Not error in etcd save")
+	assert.Equal(t, []byte("cluster resources"), backupStore.objects["rancher/abcd/snapshot-1.tar.gz"],
+		"Snapshot uploaded under the cluster folder")
+}
+
+func TestETCDRestoreImportsResources(t *testing.T) {
+	backupStore := &backupStoreStub{objects: map[string][]byte{
+		"rancher/abcd/snapshot-1.tar.gz": []byte("cluster resources"),
+	}}
+	resources := &backupResourcesStub{}
+	driver, _, _ := newSnapshotDriver(backupStore, resources)
+
+	clusterInfo := &types.ClusterInfo{}
+
+	info, err := driver.ETCDRestore(context.TODO(), clusterInfo, &types.DriverOptions{}, "snapshot-1")
+
+	assert.NoError(t, err, "Not error in etcd restore")
+	assert.Equal(t, clusterInfo, info, "ClusterInfo returned")
+	assert.Equal(t, []byte("cluster resources"), resources.imported, "Snapshot imported")
+}
+
+func TestETCDRestoreMissingSnapshot(t *testing.T) {
+	backupStore := &backupStoreStub{objects: map[string][]byte{}}
+	driver, _, _ := newSnapshotDriver(backupStore, &backupResourcesStub{})
+
+	_, err := driver.ETCDRestore(context.TODO(), &types.ClusterInfo{}, &types.DriverOptions{}, "snapshot-1")
+
+	assert.Error(t, err, "Error in etcd restore of missing snapshot")
+}
+
+func TestETCDRemoveSnapshot(t *testing.T) {
+	backupStore := &backupStoreStub{objects: map[string][]byte{
+		"rancher/abcd/snapshot-1.tar.gz": []byte("cluster resources"),
+	}}
+	driver, _, _ := newSnapshotDriver(backupStore, &backupResourcesStub{})
+
+	err := driver.ETCDRemoveSnapshot(context.TODO(), &types.ClusterInfo{}, &types.DriverOptions{}, "snapshot-1")
+
+	assert.NoError(t, err, "Not error in etcd remove snapshot")
+	assert.Empty(t, backupStore.objects, "Snapshot deleted")
+}
+
+func TestSnapshotKeyRejectsPaths(t *testing.T) {
+	key, err := snapshotKey(state.Backup{Folder: "rancher"}, state.Cluster{ClusterID: "abcd"}, "snapshot-1")
+
+	assert.NoError(t, err, "Not error in snapshot key")
+	assert.Equal(t, "rancher/abcd/snapshot-1.tar.gz", key, "Key under the cluster folder")
+
+	for _, snapshotName := range []string{"", "../efgh/snapshot-1", "efgh/snapshot-1", `..\snapshot-1`, ".."} {
+		_, err := snapshotKey(state.Backup{Folder: "rancher"}, state.Cluster{ClusterID: "abcd"}, snapshotName)
+
+		assert.True(t, service.IsValidation(err), "Snapshot name %q rejected", snapshotName)
+	}
+}
+
+func TestETCDRemoveSnapshotOutsideClusterFolder(t *testing.T) {
+	backupStore := &backupStoreStub{objects: map[string][]byte{
+		"rancher/efgh/snapshot-1.tar.gz": []byte("other cluster resources"),
+	}}
+	driver, _, _ := newSnapshotDriver(backupStore, &backupResourcesStub{})
+
+	err := driver.ETCDRemoveSnapshot(context.TODO(), &types.ClusterInfo{}, &types.DriverOptions{}, "../efgh/snapshot-1")
+
+	assert.Error(t, err, "Error in etcd remove snapshot outside the cluster folder")
+	assert.Len(t, backupStore.objects, 1, "Other cluster snapshot kept")
+}
+
+func TestBuildRestConfig(t *testing.T) {
+	restConfig, err := buildRestConfig(snapshotKubeConfig)
+
+	assert.NoError(t, err, "Not error in build rest config")
+	assert.Equal(t, "https://abcd.k8s.ondigitalocean.com", restConfig.Host, "Host equals")
+	assert.Equal(t, "kube-token", restConfig.BearerToken, "Token equals")
+	assert.Equal(t, []byte("cert"), restConfig.TLSClientConfig.CAData, "CA decoded")
+}
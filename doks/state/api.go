@@ -0,0 +1,33 @@
+package state
+
+import "github.com/rancher/kontainer-engine/types"
+
+// API tunes the DigitalOcean requests of the cluster. Empty fields fall back
+// to the settings of the driver process. The endpoint, the proxy and the
+// trusted certificates decide where the token is sent, so only the driver
+// process sets them.
+type API struct {
+	Timeout         string `json:"timeout,omitempty"`
+	UserAgentSuffix string `json:"user_agent_suffix,omitempty"`
+}
+
+// Merge overrides the settings with the ones reported, leaving the others
+// untouched.
+func (api API) Merge(reported API) API {
+	if reported.Timeout != "" {
+		api.Timeout = reported.Timeout
+	}
+
+	if reported.UserAgentSuffix != "" {
+		api.UserAgentSuffix = reported.UserAgentSuffix
+	}
+
+	return api
+}
+
+func buildAPI(getValue func(typ string, keys ...string) interface{}) API {
+	return API{
+		Timeout:         getValue(types.StringType, "api-timeout", "apiTimeout").(string),
+		UserAgentSuffix: getValue(types.StringType, "user-agent-suffix", "userAgentSuffix").(string),
+	}
+}
@@ -0,0 +1,214 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// StateKeyEnv holds the keys used to encrypt the token kept in the cluster
+	// state, separated by commas. The first key encrypts, the others are only
+	// used to decrypt state written before a key rotation.
+	StateKeyEnv = "DOKS_STATE_KEY"
+	// StateKeyFileEnv points to a file with one key per line, newest first.
+	StateKeyFileEnv = "DOKS_STATE_KEY_FILE"
+
+	encryptedTokenPrefix = "enc:"
+	// tokenFormat tokens hold a random salt, the nonce and the sealed token,
+	// with the AES key derived from the state key and the salt by HKDF.
+	tokenFormat = encryptedTokenPrefix + "v1:"
+
+	saltLength = 16
+	keyLength  = 32
+)
+
+type tokenKey struct {
+	secret []byte
+}
+
+// TokenCipher encrypts the access token stored in the cluster state with
+// AES-256-GCM. Each token gets its own key, derived from the configured
+// secret and a random salt with HKDF-SHA256. State keys are generated
+// secrets rather than passwords, so no slow derivation is needed.
+type TokenCipher struct {
+	keys []tokenKey
+}
+
+func NewTokenCipher(secrets ...string) (*TokenCipher, error) {
+	tokenCipher := &TokenCipher{}
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+
+		tokenCipher.keys = append(tokenCipher.keys, tokenKey{secret: []byte(secret)})
+	}
+
+	if len(tokenCipher.keys) == 0 {
+		return nil, errors.New("no state key was reported")
+	}
+
+	return tokenCipher, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create state cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create state cipher")
+	}
+
+	return aead, nil
+}
+
+func (key tokenKey) aead(salt []byte) (cipher.AEAD, error) {
+	derived := make([]byte, keyLength)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key.secret, salt, []byte(tokenFormat)), derived); err != nil {
+		return nil, errors.Wrap(err, "could not derive state key")
+	}
+
+	return newAEAD(derived)
+}
+
+// LoadTokenCipher reads the state keys from the environment. It returns a nil
+// cipher when no key is configured, in which case tokens are kept in plain text.
+func LoadTokenCipher() (*TokenCipher, error) {
+	if keyFile := os.Getenv(StateKeyFileEnv); keyFile != "" {
+		content, err := ioutil.ReadFile(keyFile)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read state key file %s", keyFile)
+		}
+
+		return NewTokenCipher(splitKeys(string(content), "\n")...)
+	}
+
+	if keys := os.Getenv(StateKeyEnv); keys != "" {
+		return NewTokenCipher(splitKeys(keys, ",")...)
+	}
+
+	return nil, nil
+}
+
+func splitKeys(keys, separator string) []string {
+	secrets := []string{}
+
+	for _, key := range strings.Split(keys, separator) {
+		key = strings.TrimSpace(key)
+
+		if key != "" && !strings.HasPrefix(key, "#") {
+			secrets = append(secrets, key)
+		}
+	}
+
+	return secrets
+}
+
+// Encrypt always uses the newest key, so saving a state re-encrypts its token
+// after a key rotation.
+func (tokenCipher *TokenCipher) Encrypt(token string) (string, error) {
+	salt := make([]byte, saltLength)
+
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", errors.Wrap(err, "could not generate salt")
+	}
+
+	aead, err := tokenCipher.keys[0].aead(salt)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "could not generate nonce")
+	}
+
+	sealed := append(salt, nonce...)
+	sealed = aead.Seal(sealed, nonce, []byte(token), []byte(tokenFormat))
+
+	return tokenFormat + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt tries the keys newest first, since tokens of the current format do
+// not tell which key sealed them.
+func (tokenCipher *TokenCipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, tokenFormat) {
+		return "", errors.New("encrypted token has an unknown format")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, tokenFormat))
+
+	if err != nil || len(sealed) < saltLength {
+		return "", errors.New("encrypted token is malformed")
+	}
+
+	salt, sealed := sealed[:saltLength], sealed[saltLength:]
+
+	for i, key := range tokenCipher.keys {
+		aead, err := key.aead(salt)
+
+		if err != nil {
+			return "", err
+		}
+
+		nonceSize := aead.NonceSize()
+
+		if len(sealed) < nonceSize {
+			return "", errors.New("encrypted token is malformed")
+		}
+
+		token, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(tokenFormat))
+
+		if err != nil {
+			continue
+		}
+
+		if i > 0 {
+			logrus.Debug("Token encrypted with a previous state key, it is re-encrypted on the next save")
+		}
+
+		return string(token), nil
+	}
+
+	return "", errors.New("token was not encrypted with any of the state keys")
+}
+
+func isEncryptedToken(value string) bool {
+	return strings.HasPrefix(value, encryptedTokenPrefix)
+}
+
+var (
+	defaultTokenCipher     *TokenCipher
+	defaultTokenCipherErr  error
+	defaultTokenCipherOnce sync.Once
+)
+
+func getTokenCipher() (*TokenCipher, error) {
+	defaultTokenCipherOnce.Do(func() {
+		defaultTokenCipher, defaultTokenCipherErr = LoadTokenCipher()
+	})
+
+	return defaultTokenCipher, defaultTokenCipherErr
+}
@@ -0,0 +1,179 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+const cipherToken = "a405b7bd3e0d6193f605368102a2deafe4067ed542c92166c6d772fe7e2df019"
+
+func useTokenCipher(t *testing.T, tokenCipher *TokenCipher) {
+	defaultTokenCipher, defaultTokenCipherErr = tokenCipher, nil
+	defaultTokenCipherOnce.Do(func() {})
+
+	t.Cleanup(func() {
+		defaultTokenCipher, defaultTokenCipherErr = nil, nil
+		defaultTokenCipherOnce = sync.Once{}
+	})
+}
+
+func persistedToken(t *testing.T, clusterInfo *types.ClusterInfo) string {
+	persisted := Cluster{}
+	assert.NoError(t, json.Unmarshal([]byte(clusterInfo.Metadata["state"]), &persisted))
+	return persisted.Token
+}
+
+func TestTokenCipherEncryptDecrypt(t *testing.T) {
+	tokenCipher, err := NewTokenCipher("current-key")
+
+	assert.NoError(t, err, "Not error in new token cipher")
+
+	encrypted, err := tokenCipher.Encrypt(cipherToken)
+
+	assert.NoError(t, err, "Not error in encrypt")
+	assert.True(t, isEncryptedToken(encrypted), "Token encrypted")
+	assert.NotContains(t, encrypted, cipherToken, "Token not in plain text")
+
+	decrypted, err := tokenCipher.Decrypt(encrypted)
+
+	assert.NoError(t, err, "Not error in decrypt")
+	assert.Equal(t, cipherToken, decrypted, "Token equals")
+}
+
+func TestTokenCipherUnknownKey(t *testing.T) {
+	oldCipher, _ := NewTokenCipher("old-key")
+	newCipher, _ := NewTokenCipher("new-key")
+
+	encrypted, _ := oldCipher.Encrypt(cipherToken)
+
+	_, err := newCipher.Decrypt(encrypted)
+
+	assert.Error(t, err, "Error decrypting with unknown key")
+}
+
+func TestTokenCipherSaltsEveryToken(t *testing.T) {
+	tokenCipher, _ := NewTokenCipher("current-key")
+
+	first, err := tokenCipher.Encrypt(cipherToken)
+	assert.NoError(t, err)
+
+	second, err := tokenCipher.Encrypt(cipherToken)
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, "enc:v1:"), "Token in the current format")
+	assert.NotEqual(t, first, second, "Each token gets its own salt")
+}
+
+func TestNewTokenCipherWithoutKeys(t *testing.T) {
+	_, err := NewTokenCipher("", "")
+
+	assert.Error(t, err, "Error without keys")
+}
+
+func TestLoadTokenCipherFromKeyFile(t *testing.T) {
+	keyDir, err := ioutil.TempDir("", "doks-state-key")
+	assert.NoError(t, err)
+	defer os.RemoveAll(keyDir)
+
+	keyFile := filepath.Join(keyDir, "state.key")
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("# rotated\nnew-key\nold-key\n"), 0600))
+
+	os.Setenv(StateKeyFileEnv, keyFile)
+	defer os.Unsetenv(StateKeyFileEnv)
+
+	tokenCipher, err := LoadTokenCipher()
+
+	assert.NoError(t, err, "Not error in load token cipher")
+	assert.Len(t, tokenCipher.keys, 2, "Both keys loaded")
+}
+
+func TestLoadTokenCipherNotConfigured(t *testing.T) {
+	tokenCipher, err := LoadTokenCipher()
+
+	assert.NoError(t, err, "Not error without keys")
+	assert.Nil(t, tokenCipher, "No cipher without keys")
+}
+
+func TestSaveEncryptsToken(t *testing.T) {
+	tokenCipher, _ := NewTokenCipher("current-key")
+	useTokenCipher(t, tokenCipher)
+
+	clusterInfo := &types.ClusterInfo{}
+	clusterState := Cluster{ClusterID: "abcd", Token: cipherToken}
+
+	assert.NoError(t, clusterState.Save(clusterInfo), "Not error in save")
+	assert.NotContains(t, clusterInfo.Metadata["state"], cipherToken, "Token not saved in plain text")
+	assert.Equal(t, cipherToken, clusterState.Token, "Saved state keeps the token")
+
+	loadedState, err := stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.NoError(t, err, "Not error in build state")
+	assert.Equal(t, cipherToken, loadedState.Token, "Token decrypted")
+}
+
+func TestSaveMigratesPlainTextToken(t *testing.T) {
+	clusterInfo := &types.ClusterInfo{
+		Metadata: map[string]string{"state": `{"cluster_id":"abcd","token":"` + cipherToken + `"}`},
+	}
+
+	tokenCipher, _ := NewTokenCipher("current-key")
+	useTokenCipher(t, tokenCipher)
+
+	clusterState, err := stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.NoError(t, err, "Not error in build plain text state")
+	assert.Equal(t, cipherToken, clusterState.Token, "Plain text token read")
+
+	assert.NoError(t, clusterState.Save(clusterInfo), "Not error in save")
+	assert.True(t, isEncryptedToken(persistedToken(t, clusterInfo)), "Token encrypted on save")
+}
+
+func TestSaveReencryptsAfterKeyRotation(t *testing.T) {
+	oldCipher, _ := NewTokenCipher("old-key")
+	useTokenCipher(t, oldCipher)
+
+	clusterInfo := &types.ClusterInfo{}
+	clusterState := Cluster{ClusterID: "abcd", Token: cipherToken}
+	assert.NoError(t, clusterState.Save(clusterInfo))
+
+	oldEncrypted := persistedToken(t, clusterInfo)
+
+	rotatedCipher, _ := NewTokenCipher("new-key", "old-key")
+	useTokenCipher(t, rotatedCipher)
+
+	loadedState, err := stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.NoError(t, err, "Token encrypted with old key still readable")
+	assert.NoError(t, loadedState.Save(clusterInfo))
+
+	newEncrypted := persistedToken(t, clusterInfo)
+	newCipher, _ := NewTokenCipher("new-key")
+
+	_, err = newCipher.Decrypt(newEncrypted)
+
+	assert.NotEqual(t, oldEncrypted, newEncrypted, "Token re-encrypted")
+	assert.NoError(t, err, "Token re-encrypted with the new key")
+}
+
+func TestBuildEncryptedStateWithoutKey(t *testing.T) {
+	tokenCipher, _ := NewTokenCipher("current-key")
+	useTokenCipher(t, tokenCipher)
+
+	clusterInfo := &types.ClusterInfo{}
+	clusterState := Cluster{ClusterID: "abcd", Token: cipherToken}
+	assert.NoError(t, clusterState.Save(clusterInfo))
+
+	useTokenCipher(t, nil)
+
+	_, err := stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
+
+	assert.Error(t, err, "Error reading encrypted token without key")
+}
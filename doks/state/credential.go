@@ -0,0 +1,92 @@
+package state
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rancher/kontainer-engine/types"
+)
+
+const (
+	CredentialSourceToken           = "token"
+	CredentialSourceEnv             = "env"
+	CredentialSourceFile            = "file"
+	CredentialSourceCloudCredential = "cloud-credential"
+	CredentialSourceVault           = "vault"
+
+	DefaultTokenEnv   = "DIGITALOCEAN_ACCESS_TOKEN"
+	DefaultVaultField = "token"
+)
+
+// Credential tells where the DigitalOcean token is read from. Apart from the
+// literal token, only the reference is kept in the cluster state. The Vault
+// server is a setting of the driver process, never of a cluster.
+type Credential struct {
+	Source            string `json:"source,omitempty"`
+	Token             string `json:"-"`
+	Env               string `json:"env,omitempty"`
+	File              string `json:"file,omitempty"`
+	CloudCredentialID string `json:"cloud_credential_id,omitempty"`
+	VaultPath         string `json:"vault_path,omitempty"`
+	VaultField        string `json:"vault_field,omitempty"`
+}
+
+// DigitalOceanCredential returns the credential used to reach the DigitalOcean
+// API. States saved before credential sources existed use the literal token.
+func (state Cluster) DigitalOceanCredential() Credential {
+	credential := state.Credential
+
+	if credential.Source == "" {
+		credential.Source = CredentialSourceToken
+	}
+
+	credential.Token = state.Token
+
+	return credential
+}
+
+func (credential Credential) Validate() error {
+	switch credential.Source {
+	case "", CredentialSourceToken:
+		if credential.Token == "" {
+			return errors.New("token was not reported")
+		}
+	case CredentialSourceEnv:
+		return nil
+	case CredentialSourceFile:
+		if credential.File == "" {
+			return errors.New("token file was not reported")
+		}
+	case CredentialSourceCloudCredential:
+		if credential.CloudCredentialID == "" {
+			return errors.New("cloud credential id was not reported")
+		}
+	case CredentialSourceVault:
+		if credential.VaultPath == "" {
+			return errors.New("vault path was not reported")
+		}
+	default:
+		return errors.Errorf("credential source %s is not supported", credential.Source)
+	}
+
+	return nil
+}
+
+func buildCredential(getValue func(typ string, keys ...string) interface{}) Credential {
+	credential := Credential{
+		Source:            getValue(types.StringType, "credential-source", "credentialSource").(string),
+		Env:               getValue(types.StringType, "token-env", "tokenEnv").(string),
+		File:              getValue(types.StringType, "token-file", "tokenFile").(string),
+		CloudCredentialID: getValue(types.StringType, "cloud-credential-id", "cloudCredentialId").(string),
+		VaultPath:         getValue(types.StringType, "vault-path", "vaultPath").(string),
+		VaultField:        getValue(types.StringType, "vault-field", "vaultField").(string),
+	}
+
+	if credential.Source == CredentialSourceEnv && credential.Env == "" {
+		credential.Env = DefaultTokenEnv
+	}
+
+	if credential.Source == CredentialSourceVault && credential.VaultField == "" {
+		credential.VaultField = DefaultVaultField
+	}
+
+	return credential
+}
@@ -3,10 +3,14 @@ package state
 import (
 	"encoding/json"
 	"github.com/pkg/errors"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/rancher/kontainer-engine/drivers/options"
 	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
 )
 
 type Cluster struct {
@@ -20,9 +24,30 @@ type Cluster struct {
 	VPCID       string `json:"vpc_id,omitempty"`
 	VersionSlug string `json:"version_slug,omitempty"`
 	NodePoolID  string `json:"node_pool_id,omitempty"`
+	AutoRepair  *bool `json:"auto_repair,omitempty"`
+	ScalingPolicy string `json:"scaling_policy,omitempty"`
+	Credential  Credential `json:"credential,omitempty"`
+	TeamUUID    string `json:"team_uuid,omitempty"`
+	API         API `json:"api,omitempty"`
+	MaintenancePolicy *MaintenancePolicy `json:"maintenance_policy,omitempty"`
+	CloneFromClusterID string `json:"clone_from_cluster_id,omitempty"`
+	ProjectID   string `json:"project_id,omitempty"`
+	// ProjectName is kept so an update with the same name skips the lookup.
+	ProjectName string `json:"project_name,omitempty"`
+	// NodeRecycle is the key of the last node recycle request run. The
+	// options keep the request and Rancher sends them on every update.
+	NodeRecycle string `json:"node_recycle,omitempty"`
+}
+
+// MaintenancePolicy is the weekly window DigitalOcean upgrades the cluster
+// in. Day is a weekday in lower case or "any".
+type MaintenancePolicy struct {
+	StartTime string `json:"start_time,omitempty"`
+	Day       string `json:"day,omitempty"`
 }
 
 type NodePool struct {
+	ID        string
 	Name      string
 	Size      string
 	Count     int
@@ -31,10 +56,141 @@ type NodePool struct {
 	AutoScale *bool
 	MinNodes  int
 	MaxNodes  int
+	Nodes     []Node
+	// Taints are read from DigitalOcean only, the client in use cannot set them.
+	Taints []Taint
+}
+
+type Taint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+func (taint Taint) String() string {
+	if taint.Value == "" {
+		return taint.Key + ":" + taint.Effect
+	}
+
+	return taint.Key + "=" + taint.Value + ":" + taint.Effect
+}
+
+type Node struct {
+	ID        string
+	Name      string
+	State     string
+	Message   string
+	DropletID string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type NodeRecycle struct {
+	NodeNames []string
+	AllNodes  bool
+	SkipDrain bool
+}
+
+func (recycle NodeRecycle) IsRequested() bool {
+	return recycle.AllNodes || len(recycle.NodeNames) > 0
+}
+
+// Key identifies the nodes requested, empty when none is.
+func (recycle NodeRecycle) Key() string {
+	if recycle.AllNodes {
+		return "all"
+	}
+
+	names := append([]string{}, recycle.NodeNames...)
+	sort.Strings(names)
+
+	return strings.Join(names, ",")
+}
+
+type Backup struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Folder    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+}
+
+type RepairRecord struct {
+	NodePoolID string    `json:"node_pool_id"`
+	NodeID     string    `json:"node_id"`
+	NodeName   string    `json:"node_name"`
+	Reason     string    `json:"reason"`
+	RepairedAt time.Time `json:"repaired_at"`
+}
+
+type RepairHistory []RepairRecord
+
+// LastRepair returns the most recent repair of the node pool, or nil when the
+// pool has never been repaired.
+func (history RepairHistory) LastRepair(nodePoolID string) *RepairRecord {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].NodePoolID == nodePoolID {
+			return &history[i]
+		}
+	}
+
+	return nil
+}
+
+func (history RepairHistory) Save(clusterInfo *types.ClusterInfo) error {
+	bytes, err := json.Marshal(history)
+
+	if err != nil {
+		return errors.Wrap(err, "could not marshal repair history")
+	}
+
+	if clusterInfo.Metadata == nil {
+		clusterInfo.Metadata = make(map[string]string)
+	}
+
+	clusterInfo.Metadata["repair-history"] = string(bytes)
+
+	return nil
+}
+
+func LoadRepairHistory(clusterInfo *types.ClusterInfo) (RepairHistory, error) {
+	history := RepairHistory{}
+
+	historyJson, ok := clusterInfo.Metadata["repair-history"]
+
+	if !ok {
+		return history, nil
+	}
+
+	err := json.Unmarshal([]byte(historyJson), &history)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal repair history")
+	}
+
+	return history, nil
 }
 
 func (state *Cluster) Save(clusterInfo *types.ClusterInfo) error{
-	bytes, err := json.Marshal(state)
+	tokenCipher, err := getTokenCipher()
+
+	if err != nil {
+		return err
+	}
+
+	persisted := *state
+
+	if tokenCipher != nil && persisted.Token != "" {
+		persisted.Token, err = tokenCipher.Encrypt(persisted.Token)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	bytes, err := json.Marshal(persisted)
 
 	if err != nil {
 		return errors.Wrap(err, "could not marshal state")
@@ -52,6 +208,8 @@ func (state *Cluster) Save(clusterInfo *types.ClusterInfo) error{
 type Builder interface {
 	BuildStatesFromOpts(driverOptions *types.DriverOptions) (Cluster, NodePool ,error)
 	BuildClusterStateFromClusterInfo(clusterInfo *types.ClusterInfo)(Cluster,error)
+	BuildNodeRecycleFromOpts(driverOptions *types.DriverOptions) NodeRecycle
+	BuildBackupFromOpts(driverOptions *types.DriverOptions) Backup
 }
 
 type builderImpl struct{}
@@ -70,13 +228,25 @@ func (builderImpl) BuildStatesFromOpts(driverOptions *types.DriverOptions) (Clus
 	}
 
 	clusterState.Token = getValue(types.StringType, "token").(string)
+	logging.AddSecret(clusterState.Token)
+	clusterState.Credential = buildCredential(getValue)
+
+	if clusterState.Credential.Source != "" && clusterState.Credential.Source != CredentialSourceToken {
+		clusterState.Token = ""
+	}
+	clusterState.API = buildAPI(getValue)
 	clusterState.DisplayName = getValue(types.StringType, "display-name", "displayName").(string)
 	clusterState.Name = getValue(types.StringType, "name").(string)
 	clusterState.Tags = getTagsFromStringSlice(getValue(types.StringSliceType, "tags").(*types.StringSlice))
 	clusterState.AutoUpgrade = getBoolPointer(getValue(types.BoolPointerType, "auto-upgraded", "autoUpgraded"))
 	clusterState.RegionSlug = getValue(types.StringType, "region-slug", "regionSlug").(string)
 	clusterState.VPCID = getValue(types.StringType, "vpc-id", "vpcID").(string)
+	clusterState.CloneFromClusterID = getValue(types.StringType, "clone-from-cluster-id", "cloneFromClusterId").(string)
+	clusterState.ProjectID = getValue(types.StringType, "project-id", "projectId").(string)
+	clusterState.ProjectName = getValue(types.StringType, "project-name", "projectName").(string)
 	clusterState.VersionSlug = getValue(types.StringType, "version-slug", "versionSlug").(string)
+	clusterState.AutoRepair = getBoolPointer(getValue(types.BoolPointerType, "auto-repair", "autoRepair"))
+	clusterState.ScalingPolicy = getValue(types.StringType, "scaling-policy", "scalingPolicy").(string)
 	nodePoolState.Name = getValue(types.StringType, "node-pool-name", "nodePoolName").(string)
 	nodePoolState.AutoScale = getBoolPointer(
 		getValue(types.BoolPointerType, "node-pool-autoscale", "nodePoolAutoscale"),
@@ -109,10 +279,175 @@ func (builderImpl) BuildClusterStateFromClusterInfo(clusterInfo *types.ClusterIn
 
 	err := json.Unmarshal([]byte(stateJson),&state)
 
+	if err != nil || !isEncryptedToken(state.Token) {
+		logging.AddSecret(state.Token)
+		return state, err
+	}
+
+	tokenCipher, err := getTokenCipher()
+
+	if err != nil {
+		return state, err
+	}
+
+	if tokenCipher == nil {
+		return state, errors.New("the token in the state is encrypted but no state key was reported")
+	}
+
+	state.Token, err = tokenCipher.Decrypt(state.Token)
+	logging.AddSecret(state.Token)
+
 	return state, err
 
 }
 
+func (builderImpl) BuildNodeRecycleFromOpts(driverOptions *types.DriverOptions) NodeRecycle {
+
+	getValue := func(typ string, keys ...string) interface{} {
+		return options.GetValueFromDriverOptions(driverOptions, typ, keys...)
+	}
+
+	recycle := NodeRecycle{
+		NodeNames: getTagsFromStringSlice(getValue(types.StringSliceType, "recycle-nodes", "recycleNodes").(*types.StringSlice)),
+	}
+
+	if allNodes := getBoolPointer(getValue(types.BoolPointerType, "recycle-all-nodes", "recycleAllNodes")); allNodes != nil {
+		recycle.AllNodes = *allNodes
+	}
+
+	if skipDrain := getBoolPointer(getValue(types.BoolPointerType, "recycle-skip-drain", "recycleSkipDrain")); skipDrain != nil {
+		recycle.SkipDrain = *skipDrain
+	}
+
+	return recycle
+}
+
+func (builderImpl) BuildBackupFromOpts(driverOptions *types.DriverOptions) Backup {
+
+	getValue := func(typ string, keys ...string) interface{} {
+		return options.GetValueFromDriverOptions(driverOptions, typ, keys...)
+	}
+
+	backup := Backup{
+		Endpoint:  getValue(types.StringType, "backup-endpoint", "backupEndpoint").(string),
+		Region:    getValue(types.StringType, "backup-region", "backupRegion").(string),
+		Bucket:    getValue(types.StringType, "backup-bucket", "backupBucket").(string),
+		Folder:    getValue(types.StringType, "backup-folder", "backupFolder").(string),
+		AccessKey: getValue(types.StringType, "backup-access-key", "backupAccessKey").(string),
+		SecretKey: getValue(types.StringType, "backup-secret-key", "backupSecretKey").(string),
+	}
+
+	if pathStyle := getBoolPointer(getValue(types.BoolPointerType, "backup-path-style", "backupPathStyle")); pathStyle != nil {
+		backup.PathStyle = *pathStyle
+	}
+
+	return backup
+}
+
+const (
+	// ManagedTag marks the clusters and node pools created by the driver.
+	ManagedTag = "rancher-managed"
+	// ClusterTagPrefix starts the tag naming the cluster a resource belongs
+	// to, as in rancher-cluster:<name>.
+	ClusterTagPrefix = "rancher-cluster:"
+	// OwnerTagPrefix starts the tag naming the installation of the driver
+	// that created a resource, as in rancher-owner:<owner>.
+	OwnerTagPrefix = "rancher-owner:"
+	// OwnerTagEnv names the installation of the driver. Its clusters carry
+	// the owner tag, and only they are reported as orphans.
+	OwnerTagEnv = "DOKS_OWNER_TAG"
+
+	maxTagLength = 255
+)
+
+// UserTags leaves out the tags DigitalOcean adds to clusters and node pools,
+// such as k8s and k8s:<cluster id>, and the ones the driver manages.
+func UserTags(tags []string) []string {
+	filtered := []string{}
+
+	for _, tag := range tags {
+		if tag != "k8s" && !strings.HasPrefix(tag, "k8s:") && !IsManagedTag(tag) {
+			filtered = append(filtered, tag)
+		}
+	}
+
+	return filtered
+}
+
+// IsManagedTag reports whether the driver adds the tag itself.
+func IsManagedTag(tag string) bool {
+	return tag == ManagedTag || strings.HasPrefix(tag, ClusterTagPrefix) || strings.HasPrefix(tag, OwnerTagPrefix)
+}
+
+// OwnerTag is the tag naming the installation set in DOKS_OWNER_TAG, empty
+// when none is set.
+func OwnerTag() string {
+	owner := strings.TrimSpace(os.Getenv(OwnerTagEnv))
+
+	if owner == "" {
+		return ""
+	}
+
+	return prefixedTag(OwnerTagPrefix, owner)
+}
+
+// ManagedTags are the tags the driver keeps on the cluster and its node
+// pools, so they can be found in the API whatever tags users choose.
+func ManagedTags(clusterState Cluster) []string {
+	tags := []string{ManagedTag}
+	name := clusterState.Name
+
+	if name == "" {
+		name = clusterState.DisplayName
+	}
+
+	if name != "" {
+		tags = append(tags, prefixedTag(ClusterTagPrefix, name))
+	}
+
+	if ownerTag := OwnerTag(); ownerTag != "" {
+		tags = append(tags, ownerTag)
+	}
+
+	return tags
+}
+
+// TagNodePool gives the node pool the managed tags of its cluster, in place
+// of the ones it carries, before it is sent to the API.
+func TagNodePool(clusterState Cluster, nodePool NodePool) NodePool {
+	nodePool.Tags = WithManagedTags(nodePool.Tags, ManagedTags(clusterState))
+
+	return nodePool
+}
+
+// WithManagedTags replaces the managed tags found in the tags with the given
+// ones, so user tags never remove them and renamed clusters are retagged.
+func WithManagedTags(tags, managedTags []string) []string {
+	return append(UserTags(tags), managedTags...)
+}
+
+// prefixedTag follows the prefix with the name in the characters
+// DigitalOcean accepts in tags, other characters are replaced with a dash.
+func prefixedTag(prefix, name string) string {
+	tag := []rune(prefix)
+
+	for _, character := range name {
+		switch {
+		case character >= 'a' && character <= 'z', character >= 'A' && character <= 'Z',
+			character >= '0' && character <= '9', character == '_', character == '-', character == ':':
+			tag = append(tag, character)
+		default:
+			tag = append(tag, '-')
+		}
+	}
+
+	if len(tag) > maxTagLength {
+		tag = tag[:maxTagLength]
+	}
+
+	return string(tag)
+}
+
 func getTagsFromStringSlice(tagsString *types.StringSlice)[]string{
 	if tagsString.Value == nil {
 		return []string{}
@@ -147,3 +482,75 @@ func getLabelsFromStringSlice(labelsString *types.StringSlice) map[string]string
 
 	return labels
 }
+
+// Account describes the DigitalOcean account a token belongs to.
+type Account struct {
+	UUID          string
+	Email         string
+	Status        string
+	StatusMessage string
+	TeamUUID      string
+	TeamName      string
+	DropletLimit  int
+	DropletCount  int
+	WriteScope    bool
+}
+
+// Project is a DigitalOcean project resources are grouped in.
+type Project struct {
+	ID   string
+	Name string
+}
+
+// ClusterStatus is what DigitalOcean reports about a running cluster.
+type ClusterStatus struct {
+	ID          string
+	Name        string
+	RegionSlug  string
+	VPCID       string
+	Tags        []string
+	AutoUpgrade bool
+	MaintenancePolicy *MaintenancePolicy
+	State       string
+	Message     string
+	VersionSlug string
+	Endpoint    string
+	IPv4        string
+	URN         string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Save exposes the status in the cluster info so Rancher users can see it.
+func (status ClusterStatus) Save(clusterInfo *types.ClusterInfo) {
+	if clusterInfo.Metadata == nil {
+		clusterInfo.Metadata = make(map[string]string)
+	}
+
+	clusterInfo.Status = status.State
+
+	metadata := map[string]string{
+		"status":         status.State,
+		"status-message": status.Message,
+		"endpoint":       status.Endpoint,
+		"ipv4":           status.IPv4,
+		"urn":            status.URN,
+	}
+
+	if !status.CreatedAt.IsZero() {
+		metadata["created-at"] = status.CreatedAt.UTC().Format(time.RFC3339)
+	}
+
+	if !status.UpdatedAt.IsZero() {
+		metadata["updated-at"] = status.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+
+	for key, value := range metadata {
+		if value == "" {
+			delete(clusterInfo.Metadata, key)
+			continue
+		}
+
+		clusterInfo.Metadata[key] = value
+	}
+}
@@ -1,7 +1,9 @@
 package state
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/rancher/kontainer-engine/types"
 	"github.com/stretchr/testify/assert"
@@ -64,6 +66,8 @@ func TestGetStateFromOptsKeysSnakeCase(t *testing.T) {
 			"region-slug":    regionSlug,
 			"version-slug":   versionSlug,
 			"vpc-id":         vpcID,
+			"clone-from-cluster-id": "source",
+			"project-name":          "team-a",
 			"node-pool-name": nodePoolName,
 			"node-pool-size": nodePoolSize,
 		},
@@ -91,6 +95,8 @@ func TestGetStateFromOptsKeysSnakeCase(t *testing.T) {
 	assert.Equal(t, regionSlug, clusterState.RegionSlug, "RegionSlug equals")
 	assert.Equal(t, versionSlug, clusterState.VersionSlug, "VersionSlug equals")
 	assert.Equal(t, vpcID, clusterState.VPCID, "VPCID equals")
+	assert.Equal(t, "source", clusterState.CloneFromClusterID, "CloneFromClusterID equals")
+	assert.Equal(t, "team-a", clusterState.ProjectName, "ProjectName equals")
 	assert.Equal(t, nodePoolName, nodePoolState.Name, "nodePoolName equals")
 	assert.Equal(t, nodePoolSize, nodePoolState.Size, "nodePoolSize equals")
 	assert.Equal(t, autoUpgraded, *clusterState.AutoUpgrade, "autoUpgraded equals")
@@ -215,6 +221,258 @@ func TestGetStateWithNilValues(t *testing.T){
 	assert.Equal(t, 0, nodePoolState.Count, "nodePoolCount equals")
 	assert.Equal(t, 0, nodePoolState.MinNodes, "nodePoolMin equals")
 	assert.Equal(t, 0, nodePoolState.MaxNodes, "nodePoolMax equals")
+	assert.Nil(t, clusterState.AutoRepair, "autoRepair equals")
 }
 
 
+
+func TestBuildNodeRecycleFromOpts(t *testing.T) {
+	driverOptions := types.DriverOptions{
+		BoolOptions: map[string]bool{
+			"recycle-skip-drain": true,
+		},
+		StringSliceOptions: map[string]*types.StringSlice{
+			"recycle-nodes": {Value: []string{"pool-1-aaaa", "pool-1-bbbb"}},
+		},
+	}
+
+	recycle := stateBuilder.BuildNodeRecycleFromOpts(&driverOptions)
+
+	assert.Equal(t, []string{"pool-1-aaaa", "pool-1-bbbb"}, recycle.NodeNames, "NodeNames equals")
+	assert.False(t, recycle.AllNodes, "AllNodes not set")
+	assert.True(t, recycle.SkipDrain, "SkipDrain equals")
+	assert.True(t, recycle.IsRequested(), "Recycle requested")
+}
+
+func TestBuildNodeRecycleFromOptsNotRequested(t *testing.T) {
+	recycle := stateBuilder.BuildNodeRecycleFromOpts(&types.DriverOptions{})
+
+	assert.Equal(t, []string{}, recycle.NodeNames, "NodeNames empty")
+	assert.False(t, recycle.IsRequested(), "Recycle not requested")
+}
+
+func TestRepairHistorySaveAndLoad(t *testing.T) {
+	clusterInfo := &types.ClusterInfo{}
+
+	history := RepairHistory{
+		{NodePoolID: "pool-1", NodeID: "node-1", NodeName: "pool-1-aaaa", Reason: "node in error state"},
+		{NodePoolID: "pool-2", NodeID: "node-2", NodeName: "pool-2-bbbb", Reason: "node in error state"},
+		{NodePoolID: "pool-1", NodeID: "node-3", NodeName: "pool-1-cccc", Reason: "node in error state"},
+	}
+
+	err := history.Save(clusterInfo)
+
+	assert.NoError(t, err, "Not error in save repair history")
+
+	loadedHistory, err := LoadRepairHistory(clusterInfo)
+
+	assert.NoError(t, err, "Not error in load repair history")
+	assert.Equal(t, history, loadedHistory, "Repair history equals")
+	assert.Equal(t, "node-3", loadedHistory.LastRepair("pool-1").NodeID, "Last repair of pool-1")
+	assert.Nil(t, loadedHistory.LastRepair("pool-3"), "Pool never repaired")
+}
+
+func TestLoadRepairHistoryEmpty(t *testing.T) {
+	history, err := LoadRepairHistory(&types.ClusterInfo{})
+
+	assert.NoError(t, err, "Not error in load empty repair history")
+	assert.Empty(t, history, "Repair history empty")
+}
+
+func TestBuildBackupFromOpts(t *testing.T) {
+	driverOptions := types.DriverOptions{
+		StringOptions: map[string]string{
+			"backup-endpoint":   "http://minio:9000",
+			"backup-region":     "us-east-1",
+			"backup-bucket":     "backups",
+			"backup-folder":     "rancher",
+			"backup-access-key": "access",
+			"backup-secret-key": "secret",
+		},
+		BoolOptions: map[string]bool{
+			"backup-path-style": true,
+		},
+	}
+
+	backup := stateBuilder.BuildBackupFromOpts(&driverOptions)
+
+	expectedBackup := Backup{
+		Endpoint:  "http://minio:9000",
+		Region:    "us-east-1",
+		Bucket:    "backups",
+		Folder:    "rancher",
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: true,
+	}
+
+	assert.Equal(t, expectedBackup, backup, "Backup equals")
+}
+
+func TestBuildCredentialFromOpts(t *testing.T) {
+	driverOptions := types.DriverOptions{
+		StringOptions: map[string]string{
+			"token":             "literal-token",
+			"credential-source": "vault",
+			"vault-path":        "secret/data/doks",
+		},
+	}
+
+	clusterState, _, err := stateBuilder.BuildStatesFromOpts(&driverOptions)
+
+	expectedCredential := Credential{
+		Source:     CredentialSourceVault,
+		VaultPath:  "secret/data/doks",
+		VaultField: DefaultVaultField,
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCredential, clusterState.Credential, "Credential equals")
+	assert.Empty(t, clusterState.Token, "Literal token not kept with another credential source")
+}
+
+func TestBuildCloudCredentialFromOpts(t *testing.T) {
+	driverOptions := types.DriverOptions{
+		StringOptions: map[string]string{
+			"credentialSource":  "cloud-credential",
+			"cloudCredentialId": "cattle-global-data:cc-abcde",
+		},
+	}
+
+	clusterState, _, err := stateBuilder.BuildStatesFromOpts(&driverOptions)
+
+	expectedCredential := Credential{
+		Source:            CredentialSourceCloudCredential,
+		CloudCredentialID: "cattle-global-data:cc-abcde",
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCredential, clusterState.Credential, "Credential equals")
+}
+
+func TestBuildAPIFromOpts(t *testing.T) {
+	driverOptions := types.DriverOptions{
+		StringOptions: map[string]string{
+			"api-url":           "http://127.0.0.1:8080",
+			"proxyUrl":          "http://proxy:3128",
+			"api-timeout":       "45s",
+			"user-agent-suffix": "rancher/2.4",
+		},
+	}
+
+	clusterState, _, err := stateBuilder.BuildStatesFromOpts(&driverOptions)
+
+	expectedAPI := API{
+		Timeout:         "45s",
+		UserAgentSuffix: "rancher/2.4",
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedAPI, clusterState.API, "Endpoint and proxy left to the driver process")
+}
+
+func TestAPIMerge(t *testing.T) {
+	api := API{Timeout: "45s", UserAgentSuffix: "rancher"}
+
+	merged := api.Merge(API{Timeout: "1m"})
+
+	assert.Equal(t, API{Timeout: "1m", UserAgentSuffix: "rancher"}, merged,
+		"Reported settings override")
+	assert.Equal(t, api, api.Merge(API{}), "Nothing reported")
+}
+
+func TestDigitalOceanCredentialFromLegacyState(t *testing.T) {
+	clusterState := Cluster{Token: "literal-token"}
+
+	credential := clusterState.DigitalOceanCredential()
+
+	assert.Equal(t, CredentialSourceToken, credential.Source, "Legacy state uses the literal token")
+	assert.Equal(t, "literal-token", credential.Token, "Token equals")
+	assert.NoError(t, credential.Validate())
+}
+
+func TestCredentialValidate(t *testing.T) {
+	assert.Error(t, Credential{Source: CredentialSourceToken}.Validate(), "Token required")
+	assert.Error(t, Credential{Source: CredentialSourceFile}.Validate(), "File required")
+	assert.Error(t, Credential{Source: CredentialSourceCloudCredential}.Validate(), "Cloud credential required")
+	assert.NoError(t, Credential{Source: CredentialSourceCloudCredential, CloudCredentialID: "cc-abcde"}.Validate(),
+		"Cloud credential valid")
+	assert.Error(t, Credential{Source: CredentialSourceVault}.Validate(), "Vault path required")
+	assert.Error(t, Credential{Source: "ssm"}.Validate(), "Unknown source")
+	assert.NoError(t, Credential{Source: CredentialSourceEnv}.Validate(), "Env has a default")
+}
+
+func TestSaveKeepsOnlyCredentialReference(t *testing.T) {
+	clusterInfo := &types.ClusterInfo{}
+	clusterState := Cluster{
+		ClusterID:  "abcd",
+		Credential: Credential{Source: CredentialSourceFile, File: "/run/secrets/doks", Token: "secret"},
+	}
+
+	assert.NoError(t, clusterState.Save(clusterInfo))
+	assert.NotContains(t, clusterInfo.Metadata["state"], "secret\"", "Secret not saved")
+	assert.Contains(t, clusterInfo.Metadata["state"], "/run/secrets/doks", "Reference saved")
+}
+
+func TestClusterStatusSave(t *testing.T) {
+	clusterInfo := &types.ClusterInfo{
+		Metadata: map[string]string{"state": "{}", "status-message": "Provisioning"},
+	}
+
+	clusterStatus := ClusterStatus{
+		State:     "errored",
+		Endpoint:  "https://abcd.k8s.ondigitalocean.com",
+		URN:       "do:kubernetes:abcd",
+		UpdatedAt: time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	clusterStatus.Save(clusterInfo)
+
+	assert.Equal(t, "errored", clusterInfo.Status, "Status equals")
+	assert.Equal(t, "errored", clusterInfo.Metadata["status"], "Status metadata equals")
+	assert.Equal(t, "do:kubernetes:abcd", clusterInfo.Metadata["urn"], "URN equals")
+	assert.Equal(t, "2020-06-01T10:00:00Z", clusterInfo.Metadata["updated-at"], "Update time equals")
+	assert.Equal(t, "{}", clusterInfo.Metadata["state"], "State kept")
+
+	_, ok := clusterInfo.Metadata["status-message"]
+
+	assert.False(t, ok, "Stale status message removed")
+}
+
+func TestManagedTags(t *testing.T) {
+	assert.Equal(t, []string{ManagedTag, "rancher-cluster:production"}, ManagedTags(Cluster{Name: "production"}))
+	assert.Equal(t, []string{ManagedTag, "rancher-cluster:Team-A-cluster"},
+		ManagedTags(Cluster{DisplayName: "Team A/cluster"}), "Display name used with invalid characters replaced")
+	assert.Equal(t, []string{ManagedTag}, ManagedTags(Cluster{}), "Marker alone without a name")
+}
+
+func TestManagedTagsWithOwner(t *testing.T) {
+	os.Setenv(OwnerTagEnv, "rancher.example.com")
+	defer os.Unsetenv(OwnerTagEnv)
+
+	assert.Equal(t, []string{ManagedTag, "rancher-cluster:production", "rancher-owner:rancher-example-com"},
+		ManagedTags(Cluster{Name: "production"}), "Owner tag added with invalid characters replaced")
+	assert.True(t, IsManagedTag("rancher-owner:rancher-example-com"))
+}
+
+func TestTagNodePool(t *testing.T) {
+	nodePool := TagNodePool(Cluster{Name: "production"}, NodePool{Name: "workers", Tags: []string{"web", ManagedTag}})
+
+	assert.Equal(t, []string{"web", ManagedTag, "rancher-cluster:production"}, nodePool.Tags,
+		"Managed tags of the cluster added")
+	assert.Equal(t, "workers", nodePool.Name)
+}
+
+func TestWithManagedTags(t *testing.T) {
+	tags := WithManagedTags([]string{"web", "k8s", "rancher-cluster:old", ManagedTag},
+		ManagedTags(Cluster{Name: "new"}))
+
+	assert.Equal(t, []string{"web", ManagedTag, "rancher-cluster:new"}, tags, "Managed tags replaced")
+	assert.Equal(t, []string{"web"}, UserTags(tags), "Managed tags are not user tags")
+}
+
+func TestNodeRecycleKey(t *testing.T) {
+	assert.Equal(t, "all", NodeRecycle{AllNodes: true, NodeNames: []string{"a"}}.Key())
+	assert.Equal(t, "a,b", NodeRecycle{NodeNames: []string{"b", "a"}}.Key(), "Order ignored")
+	assert.Empty(t, NodeRecycle{SkipDrain: true}.Key(), "Nothing requested")
+}
@@ -0,0 +1,61 @@
+package state
+
+import "sort"
+
+// Template returns the create options reproducing the cluster with the node
+// pool, as BuildStatesFromOpts reads them. The credential is left out, with
+// the paths and variable names telling where the token is kept, and so are
+// the settings bound to one cluster or region: the IDs and the VPC.
+func Template(clusterState Cluster, nodePool NodePool) map[string]interface{} {
+	template := map[string]interface{}{}
+
+	setString := func(name, value string) {
+		if value != "" {
+			template[name] = value
+		}
+	}
+
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			template[name] = *value
+		}
+	}
+
+	setString("api-timeout", clusterState.API.Timeout)
+	setString("user-agent-suffix", clusterState.API.UserAgentSuffix)
+
+	setString("display-name", clusterState.DisplayName)
+	setString("name", clusterState.Name)
+	setBool("auto-upgraded", clusterState.AutoUpgrade)
+	setBool("auto-repair", clusterState.AutoRepair)
+	setString("scaling-policy", clusterState.ScalingPolicy)
+	setString("region-slug", clusterState.RegionSlug)
+	setString("version-slug", clusterState.VersionSlug)
+
+	if len(clusterState.Tags) > 0 {
+		template["tags"] = clusterState.Tags
+	}
+
+	setString("node-pool-name", nodePool.Name)
+	setString("node-pool-size", nodePool.Size)
+	template["node-pool-count"] = nodePool.Count
+	setBool("node-pool-autoscale", nodePool.AutoScale)
+
+	if nodePool.AutoScale != nil && *nodePool.AutoScale {
+		template["node-pool-min"] = nodePool.MinNodes
+		template["node-pool-max"] = nodePool.MaxNodes
+	}
+
+	if len(nodePool.Labels) > 0 {
+		labels := make([]string, 0, len(nodePool.Labels))
+
+		for key, value := range nodePool.Labels {
+			labels = append(labels, key+"="+value)
+		}
+
+		sort.Strings(labels)
+		template["node-pool-labels"] = labels
+	}
+
+	return template
+}
@@ -0,0 +1,88 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func templateDriverOptions(template map[string]interface{}) *types.DriverOptions {
+	driverOptions := &types.DriverOptions{
+		BoolOptions:        map[string]bool{},
+		StringOptions:      map[string]string{},
+		IntOptions:         map[string]int64{},
+		StringSliceOptions: map[string]*types.StringSlice{},
+	}
+
+	for name, value := range template {
+		switch typed := value.(type) {
+		case string:
+			driverOptions.StringOptions[name] = typed
+		case bool:
+			driverOptions.BoolOptions[name] = typed
+		case int:
+			driverOptions.IntOptions[name] = int64(typed)
+		case []string:
+			driverOptions.StringSliceOptions[name] = &types.StringSlice{Value: typed}
+		}
+	}
+
+	return driverOptions
+}
+
+func TestTemplateRoundTrip(t *testing.T) {
+	autoUpgrade, autoScale := true, true
+
+	clusterState := Cluster{
+		ClusterID:   "abcd",
+		NodePoolID:  "pool",
+		Token:       "secret-token",
+		Name:        "staging",
+		DisplayName: "Staging",
+		Tags:        []string{"team-a"},
+		AutoUpgrade: &autoUpgrade,
+		RegionSlug:  "nyc1",
+		VPCID:       "vpc",
+		VersionSlug: "1.17.6-do.0",
+		Credential:  Credential{Source: CredentialSourceVault, VaultPath: "secret/doks", VaultField: "token"},
+		API:         API{Timeout: "30s"},
+	}
+
+	nodePool := NodePool{
+		Name:      "workers",
+		Size:      "s-2vcpu-4gb",
+		Count:     3,
+		AutoScale: &autoScale,
+		MinNodes:  1,
+		MaxNodes:  5,
+		Labels:    map[string]string{"role": "worker", "tier": "web"},
+	}
+
+	template := Template(clusterState, nodePool)
+
+	for _, name := range []string{"token", "credential-source", "token-env", "token-file", "cloud-credential-id",
+		"vault-path", "vault-field"} {
+		assert.NotContains(t, template, name, name+" removed")
+	}
+
+	assert.Equal(t, "30s", template["api-timeout"], "API timeout kept")
+
+	rebuilt, rebuiltNodePool, err := NewBuilder().BuildStatesFromOpts(templateDriverOptions(template))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", rebuilt.Name, "Name equals")
+	assert.Equal(t, "Staging", rebuilt.DisplayName, "Display name equals")
+	assert.Equal(t, []string{"team-a"}, rebuilt.Tags, "Tags equal")
+	assert.Equal(t, &autoUpgrade, rebuilt.AutoUpgrade, "AutoUpgrade equals")
+	assert.Equal(t, "nyc1", rebuilt.RegionSlug, "Region equals")
+	assert.Equal(t, "1.17.6-do.0", rebuilt.VersionSlug, "Version equals")
+	assert.Empty(t, rebuilt.VPCID, "VPC left out")
+	assert.Empty(t, rebuilt.ClusterID, "Cluster ID left out")
+	assert.Empty(t, rebuilt.Token, "No token")
+	assert.Equal(t, Credential{}, rebuilt.Credential, "Credential left to the importer")
+	assert.Equal(t, "30s", rebuilt.API.Timeout, "API settings kept")
+
+	nodePool.Nodes = nil
+	assert.Equal(t, nodePool, rebuiltNodePool, "Node pool equals")
+}
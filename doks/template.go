@@ -0,0 +1,36 @@
+package doks
+
+import (
+	"context"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// ExportTemplate returns the create options reproducing the cluster, from its
+// state and the live configuration of its node pool. The token and the
+// settings bound to the cluster are left out, so the template can create a
+// copy in another region or account.
+func (driver *Driver) ExportTemplate(ctx context.Context, clusterInfo *types.ClusterInfo) (map[string]interface{}, error) {
+	ctx = operationContext(ctx, "ExportTemplate")
+
+	clusterState, err := driver.stateBuilder.BuildClusterStateFromClusterInfo(clusterInfo)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error BuildClusterStateFromClusterInfo in ExportTemplate")
+		return nil, err
+	}
+
+	ctx = clusterContext(ctx, clusterState)
+	digitalOceanService := driver.digitalOceanFactory(clusterState.DigitalOceanCredential(), clusterState.API)
+
+	nodePool, err := digitalOceanService.GetNodePool(ctx, clusterState.ClusterID, clusterState.NodePoolID)
+
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Debug("Error GetNodePool in ExportTemplate")
+		return nil, err
+	}
+
+	return state.Template(clusterState, *nodePool), nil
+}
@@ -0,0 +1,89 @@
+package doks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDriverExportTemplate(t *testing.T) {
+	autoUpgrade, autoScale := true, false
+
+	clusterState := state.Cluster{
+		ClusterID:   "abcd",
+		NodePoolID:  "pool",
+		Token:       "secret-token",
+		Name:        "staging",
+		RegionSlug:  "nyc1",
+		VersionSlug: "1.17.6-do.0",
+		VPCID:       "vpc",
+		AutoUpgrade: &autoUpgrade,
+	}
+
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return clusterState, nil
+		},
+	}
+
+	digitalOceanMock := &DigitalOceanMock{
+		getNodePoolMock: func(_ context.Context, _, _ string) (*state.NodePool, error) {
+			return &state.NodePool{ID: "pool", Name: "workers", Size: "s-2vcpu-4gb", Count: 4, AutoScale: &autoScale,
+				Labels: map[string]string{"role": "worker"}}, nil
+		},
+	}
+
+	driver := Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean { return digitalOceanMock },
+	}
+
+	clusterInfo := &types.ClusterInfo{}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", clusterInfo)
+	digitalOceanMock.On("GetNodePool", mock.Anything, "abcd", "pool")
+
+	template, err := driver.ExportTemplate(context.TODO(), clusterInfo)
+
+	stateBuilderMock.AssertExpectations(t)
+	digitalOceanMock.AssertExpectations(t)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", template["name"], "Name exported")
+	assert.Equal(t, 4, template["node-pool-count"], "Live node count exported")
+	assert.Equal(t, []string{"role=worker"}, template["node-pool-labels"], "Labels exported")
+	assert.NotContains(t, template, "token", "Token removed")
+	assert.NotContains(t, template, "vpc-id", "VPC left out")
+}
+
+func TestDriverExportTemplateErrorInGetNodePool(t *testing.T) {
+	stateBuilderMock := &StateBuilderMock{
+		buildStateFromClusterInfo: func(_ *types.ClusterInfo) (state.Cluster, error) {
+			return state.Cluster{ClusterID: "abcd", NodePoolID: "pool"}, nil
+		},
+	}
+
+	digitalOceanMock := &DigitalOceanMock{
+		getNodePoolMock: func(_ context.Context, _, _ string) (*state.NodePool, error) {
+			return nil, errors.New("node pool not found")
+		},
+	}
+
+	driver := Driver{
+		stateBuilder:        stateBuilderMock,
+		digitalOceanFactory: func(credential state.Credential, api state.API) service.DigitalOcean { return digitalOceanMock },
+	}
+
+	stateBuilderMock.On("BuildClusterStateFromClusterInfo", mock.Anything)
+	digitalOceanMock.On("GetNodePool", mock.Anything, "abcd", "pool")
+
+	_, err := driver.ExportTemplate(context.TODO(), &types.ClusterInfo{})
+
+	assert.Error(t, err, "Error returned")
+}
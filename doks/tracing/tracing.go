@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterEnv = "DOKS_TRACING_EXPORTER"
+	EndpointEnv = "DOKS_OTLP_ENDPOINT"
+	InsecureEnv = "DOKS_OTLP_INSECURE"
+
+	ExporterNone     = "none"
+	ExporterOTLP     = "otlp"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterStdout   = "stdout"
+
+	serviceName     = "kontainer-engine-driver-doks"
+	instrumentation = "github.com/ribeiro-rodrigo/kontainer-engine-driver-doks"
+)
+
+var (
+	ClusterIDKey  = attribute.Key("doks.cluster.id")
+	NodePoolIDKey = attribute.Key("doks.node_pool.id")
+	NodeIDKey     = attribute.Key("doks.node.id")
+	RegionKey     = attribute.Key("doks.region")
+)
+
+// Config selects where spans are exported. Tracing is off with the none
+// exporter, which is the default.
+type Config struct {
+	Exporter string
+	Endpoint string
+	Insecure bool
+	// Writer receives the spans of the stdout exporter, os.Stdout when nil.
+	Writer io.Writer
+}
+
+func ConfigFromEnv() Config {
+	insecure, _ := strconv.ParseBool(os.Getenv(InsecureEnv))
+
+	return Config{
+		Exporter: os.Getenv(ExporterEnv),
+		Endpoint: os.Getenv(EndpointEnv),
+		Insecure: insecure,
+	}
+}
+
+// Shutdown flushes the pending spans and stops the exporter.
+type Shutdown func(ctx context.Context) error
+
+func noShutdown(context.Context) error {
+	return nil
+}
+
+// Setup installs the global tracer provider described by config.
+func Setup(ctx context.Context, config Config) (Shutdown, error) {
+	exporter, err := newExporter(ctx, config)
+
+	if err != nil || exporter == nil {
+		return noShutdown, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.ServiceNameKey.String(serviceName))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(config.Exporter) {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		writer := config.Writer
+
+		if writer == nil {
+			writer = os.Stdout
+		}
+
+		return stdout.NewExporter(stdout.WithWriter(writer), stdout.WithPrettyPrint(), stdout.WithoutMetricExport())
+	case ExporterOTLP:
+		options := []otlpgrpc.Option{}
+
+		if config.Endpoint != "" {
+			options = append(options, otlpgrpc.WithEndpoint(config.Endpoint))
+		}
+
+		if config.Insecure {
+			options = append(options, otlpgrpc.WithInsecure())
+		}
+
+		exporter, err := otlp.NewExporter(ctx, otlpgrpc.NewDriver(options...))
+
+		return exporter, errors.Wrap(err, "could not start the OTLP exporter")
+	case ExporterOTLPHTTP:
+		options := []otlphttp.Option{}
+
+		if config.Endpoint != "" {
+			options = append(options, otlphttp.WithEndpoint(config.Endpoint))
+		}
+
+		if config.Insecure {
+			options = append(options, otlphttp.WithInsecure())
+		}
+
+		exporter, err := otlp.NewExporter(ctx, otlphttp.NewDriver(options...))
+
+		return exporter, errors.Wrap(err, "could not start the OTLP exporter")
+	}
+
+	return nil, fmt.Errorf("invalid tracing exporter %q, expected %s, %s, %s or %s",
+		config.Exporter, ExporterNone, ExporterOTLP, ExporterOTLPHTTP, ExporterStdout)
+}
+
+// StartSpan starts a span named name, child of the span in ctx if any.
+// Empty string attributes are left out.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	set := make([]attribute.KeyValue, 0, len(attributes))
+
+	for _, keyValue := range attributes {
+		if keyValue.Value.Type() == attribute.STRING && keyValue.Value.AsString() == "" {
+			continue
+		}
+
+		set = append(set, keyValue)
+	}
+
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(set...))
+}
+
+// End records err on span, when there is one, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// NewTransport traces every request sent through base.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	return exporter
+}
+
+func TestSetupNone(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetupInvalidExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+
+	assert.Error(t, err)
+}
+
+func TestSetupStdout(t *testing.T) {
+	output := &bytes.Buffer{}
+
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, Writer: output})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, span := StartSpan(context.Background(), "Driver.Create", ClusterIDKey.String("abcd"))
+	End(span, nil)
+
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, output.String(), "Driver.Create")
+	assert.Contains(t, output.String(), "doks.cluster.id")
+}
+
+func TestStartSpan(t *testing.T) {
+	exporter := recordSpans()
+
+	ctx, parent := StartSpan(context.Background(), "Driver.Remove",
+		ClusterIDKey.String("abcd"), NodePoolIDKey.String(""))
+	_, child := StartSpan(ctx, "DigitalOcean.DeleteCluster")
+
+	End(child, errors.New("cluster is locked"))
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	assert.Equal(t, "DigitalOcean.DeleteCluster", spans[0].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID(), "child of the driver span")
+	assert.Equal(t, codes.Error, spans[0].StatusCode)
+	assert.Equal(t, "cluster is locked", spans[0].StatusMessage)
+
+	assert.Equal(t, []attribute.KeyValue{ClusterIDKey.String("abcd")}, spans[1].Attributes,
+		"empty attributes are left out")
+	assert.Equal(t, codes.Unset, spans[1].StatusCode)
+}
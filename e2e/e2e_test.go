@@ -0,0 +1,244 @@
+//go:build e2e
+// +build e2e
+
+// Package e2e runs the driver binary against the fake DigitalOcean API and
+// drives it over gRPC like Rancher does.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/client"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const modulePath = "github.com/ribeiro-rodrigo/kontainer-engine-driver-doks"
+
+var (
+	binary  string
+	address string
+	api     *fake.Server
+	driver  types.CloseableDriver
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runSuite(m))
+}
+
+// runSuite builds and starts the driver, runs the tests and stops it.
+func runSuite(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "doks-e2e")
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	defer os.RemoveAll(dir)
+
+	binary = filepath.Join(dir, "kontainer-engine-driver-doks")
+
+	if output, err := exec.Command("go", "build", "-o", binary, modulePath).CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "error building the driver: %v\n%s", err, output)
+		return 1
+	}
+
+	api = fake.NewServer()
+	defer api.Close()
+
+	api.SetProvisioningPolls(0)
+
+	address, err = freeAddress()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	_, port, _ := net.SplitHostPort(address)
+	logs := &bytes.Buffer{}
+
+	server := exec.Command(binary, "--port", port, "--log-level", "debug")
+	server.Env = append(os.Environ(), service.APIURLEnv+"="+api.URL)
+	server.Stdout = logs
+	server.Stderr = logs
+
+	if err := server.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	driver, err = client.Dial(ctx, address)
+
+	if err != nil {
+		server.Process.Kill()
+		fmt.Fprintf(os.Stderr, "driver did not start: %v\n%s", err, logs)
+		return 1
+	}
+
+	defer driver.Close()
+
+	code := m.Run()
+
+	server.Process.Signal(syscall.SIGTERM)
+
+	if err := server.Wait(); err != nil {
+		fmt.Fprintf(os.Stderr, "driver did not stop cleanly: %v\n", err)
+		code = 1
+	}
+
+	if code != 0 {
+		fmt.Fprintf(os.Stderr, "driver logs:\n%s", logs)
+	}
+
+	return code
+}
+
+func freeAddress() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		return "", err
+	}
+
+	defer listener.Close()
+
+	return listener.Addr().String(), nil
+}
+
+func driverOptions(t *testing.T, flags *types.DriverFlags, values options.Values, defaults bool) *types.DriverOptions {
+	driverOptions, err := values.DriverOptions(flags, defaults)
+	assert.NoError(t, err)
+
+	return driverOptions
+}
+
+func clusterID(t *testing.T, info *types.ClusterInfo) string {
+	clusterState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(info)
+	assert.NoError(t, err)
+
+	return clusterState.ClusterID
+}
+
+func TestCapabilities(t *testing.T) {
+	capabilities, err := driver.GetCapabilities(context.TODO())
+
+	assert.NoError(t, err)
+	assert.True(t, capabilities.HasGetVersionCapability(), "Get version")
+	assert.True(t, capabilities.HasSetVersionCapability(), "Set version")
+	assert.True(t, capabilities.HasGetClusterSizeCapability(), "Get cluster size")
+	assert.True(t, capabilities.HasSetClusterSizeCapability(), "Set cluster size")
+}
+
+func TestDriverOptions(t *testing.T) {
+	createFlags, err := driver.GetDriverCreateOptions(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Contains(t, createFlags.Options, "token", "Token accepted on create")
+	assert.Contains(t, createFlags.Options, "region-slug", "Region accepted on create")
+
+	updateFlags, err := driver.GetDriverUpdateOptions(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Contains(t, updateFlags.Options, "node-pool-count", "Node count accepted on update")
+}
+
+func TestClusterLifecycle(t *testing.T) {
+	ctx := context.TODO()
+
+	createFlags, err := driver.GetDriverCreateOptions(ctx)
+	assert.NoError(t, err)
+
+	info, err := driver.Create(ctx, driverOptions(t, createFlags, options.Values{
+		"token":           "e2e-token",
+		"name":            "e2e-cluster",
+		"region-slug":     "nyc1",
+		"version-slug":    "1.17.6-do.0",
+		"node-pool-name":  "workers",
+		"node-pool-size":  "s-1vcpu-2gb",
+		"node-pool-count": 2,
+	}, true), nil)
+
+	if !assert.NoError(t, err, "Cluster created") {
+		return
+	}
+
+	assert.NotEmpty(t, info.Metadata["state"], "State returned to Rancher")
+
+	info, err = driver.PostCheck(ctx, info)
+
+	if !assert.NoError(t, err, "Cluster checked") {
+		return
+	}
+
+	assert.NotEmpty(t, info.Endpoint, "Endpoint read from the kubeconfig")
+	assert.NotEmpty(t, info.ServiceAccountToken, "Token read from the kubeconfig")
+	assert.Equal(t, int64(2), info.NodeCount, "Node count read")
+
+	updateFlags, err := driver.GetDriverUpdateOptions(ctx)
+	assert.NoError(t, err)
+
+	info, err = driver.Update(ctx, info, driverOptions(t, updateFlags, options.Values{"node-pool-count": 3}, false))
+
+	assert.NoError(t, err, "Cluster updated")
+
+	count, err := driver.GetClusterSize(ctx, info)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count.Count, "Node pool resized")
+
+	version, err := driver.GetVersion(ctx, info)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1.17.6-do.0", version.Version, "Version read")
+
+	assert.NoError(t, driver.Remove(ctx, info), "Cluster removed")
+	assert.NotEmpty(t, api.RequestsTo(http.MethodDelete, "/v2/kubernetes/clusters/"+clusterID(t, info)),
+		"Delete sent to the API")
+}
+
+func TestCreateErrorReported(t *testing.T) {
+	api.Fail(fake.Failure{Method: http.MethodPost, Path: "/v2/kubernetes/clusters", Status: http.StatusUnprocessableEntity,
+		Message: "region is not available", Times: 1})
+	defer api.ClearFailures()
+
+	createFlags, err := driver.GetDriverCreateOptions(context.TODO())
+	assert.NoError(t, err)
+
+	_, err = driver.Create(context.TODO(), driverOptions(t, createFlags, options.Values{
+		"token":          "e2e-token",
+		"name":           "failing-cluster",
+		"region-slug":    "nyc1",
+		"version-slug":   "1.17.6-do.0",
+		"node-pool-name": "workers",
+		"node-pool-size": "s-1vcpu-2gb",
+	}, true), nil)
+
+	assert.Error(t, err, "API error reported over gRPC")
+}
+
+func TestClientCommand(t *testing.T) {
+	output, err := exec.Command(binary, "client", "--address", address, "capabilities").CombinedOutput()
+
+	assert.NoError(t, err, "%s", output)
+	assert.Contains(t, string(output), "capabilities", "Capabilities printed")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/rancher/kontainer-engine/types"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks"
+	"gopkg.in/yaml.v2"
+)
+
+// exportCommand writes a cluster as a template of create options.
+const exportCommand = "export"
+
+// templateExporter is the part of the driver exporting cluster templates.
+type templateExporter interface {
+	ExportTemplate(ctx context.Context, clusterInfo *types.ClusterInfo) (map[string]interface{}, error)
+}
+
+// runExport writes the template of the cluster in the state file, as YAML or
+// JSON, to --output or stdout. The template is accepted by create --options.
+func runExport(ctx context.Context, exporter templateExporter, args []string, stdout io.Writer) error {
+	var stateFile, outputFile, format string
+
+	flags := flag.NewFlagSet(exportCommand, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&stateFile, "state", "", "file holding the cluster info")
+	flags.StringVar(&outputFile, "output", "", "file the template is written to, stdout when empty")
+	flags.StringVar(&format, "format", "yaml", "template format: yaml or json")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %v", exportCommand, err)
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%s: unexpected arguments %v", exportCommand, flags.Args())
+	}
+
+	if stateFile == "" {
+		return fmt.Errorf("%s: --state is required", exportCommand)
+	}
+
+	marshal, ok := map[string]func(interface{}) ([]byte, error){
+		"yaml": yaml.Marshal,
+		"json": func(value interface{}) ([]byte, error) {
+			data, err := json.MarshalIndent(value, "", "  ")
+			return append(data, '\n'), err
+		},
+	}[format]
+
+	if !ok {
+		return fmt.Errorf("%s: format %q is not supported, use yaml or json", exportCommand, format)
+	}
+
+	clusterInfo, err := readClusterInfo(stateFile, false)
+
+	if err != nil {
+		return err
+	}
+
+	template, err := exporter.ExportTemplate(ctx, clusterInfo)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := marshal(template)
+
+	if err != nil {
+		return err
+	}
+
+	if outputFile == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+
+	return ioutil.WriteFile(outputFile, data, 0644)
+}
+
+// runExportCLI exports with an in-process driver.
+func runExportCLI(args []string) error {
+	ctx, done, err := setupCLI()
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	driver := doks.NewDriver()
+
+	return runExport(ctx, &driver, args, os.Stdout)
+}
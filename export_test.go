@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCommand(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	server.SetProvisioningPolls(0)
+
+	os.Setenv(service.APIURLEnv, server.URL)
+	defer os.Unsetenv(service.APIURLEnv)
+
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	optionsFile := filepath.Join(dir, "options.yaml")
+	stateFile := filepath.Join(dir, "state.json")
+	templateFile := filepath.Join(dir, "template.json")
+
+	writeFile(t, optionsFile, `
+token: secret-token
+name: staging
+region-slug: nyc1
+version-slug: 1.17.6-do.0
+tags: [team-a]
+node-pool-name: workers
+node-pool-size: s-1vcpu-2gb
+node-pool-count: 2
+node-pool-labels: [role=worker]
+`)
+
+	_, err := runTestCommand(t, "create", "--options", optionsFile, "--state", stateFile)
+	assert.NoError(t, err, "Cluster created")
+
+	_, err = runTestCommand(t, "set-size", "--state", stateFile, "--count", "3")
+	assert.NoError(t, err, "Cluster resized")
+
+	driver := doks.NewDriver()
+	output := &bytes.Buffer{}
+
+	assert.NoError(t, runExport(context.TODO(), &driver, []string{"--state", stateFile}, output))
+	assert.NotContains(t, output.String(), "secret-token", "Token removed")
+	assert.Contains(t, output.String(), "name: staging", "YAML written")
+
+	err = runExport(context.TODO(), &driver, []string{"--state", stateFile, "--format", "json", "--output", templateFile},
+		output)
+	assert.NoError(t, err)
+
+	driverOptions, err := readOptions(templateFile, options.NewBuilder().BuildCreateOptions(), true)
+
+	assert.NoError(t, err, "Template accepted as create options")
+
+	clusterState, nodePool, err := state.NewBuilder().BuildStatesFromOpts(driverOptions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", clusterState.Name, "Name equals")
+	assert.Equal(t, []string{"team-a"}, clusterState.Tags, "Tags equal")
+	assert.Empty(t, clusterState.Token, "No token")
+	assert.Equal(t, 3, nodePool.Count, "Live node count exported")
+	assert.Equal(t, map[string]string{"role": "worker"}, nodePool.Labels, "Labels equal")
+}
+
+func TestExportArguments(t *testing.T) {
+	driver := doks.NewDriver()
+
+	for _, args := range [][]string{
+		{},
+		{"--state", "state.json", "--format", "toml"},
+		{"--state", "missing.json"},
+		{"--state", "state.json", "extra"},
+	} {
+		assert.Error(t, runExport(context.TODO(), &driver, args, &bytes.Buffer{}), "args %v", args)
+	}
+}
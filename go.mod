@@ -3,23 +3,33 @@ module github.com/ribeiro-rodrigo/kontainer-engine-driver-doks
 go 1.14
 
 require (
+	github.com/aws/aws-sdk-go v1.25.48
 	github.com/digitalocean/godo v1.36.0
-	github.com/ghodss/yaml v1.0.0
+	github.com/evanphx/json-patch v4.2.0+incompatible // indirect
 	github.com/gogo/protobuf v1.3.0 // indirect
-	github.com/oracle/oci-go-sdk v19.0.0+incompatible
+	github.com/googleapis/gnostic v0.2.0 // indirect
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v1.1.0
 	github.com/rancher/kontainer-engine v0.0.0-20190711161432-b98bad2201bb
 	github.com/rancher/rke v0.2.8 // indirect
-	github.com/rancher/types v0.0.0-20190916163052-4cf2c20529fd // indirect
 	github.com/sirupsen/logrus v1.4.2
-	github.com/stretchr/testify v1.5.1
+	github.com/spf13/pflag v1.0.1 // indirect
+	github.com/stretchr/testify v1.7.0
 	github.com/urfave/cli v1.21.0 // indirect
-	golang.org/x/crypto v0.0.0-20190829043050-9756ffdc2472 // indirect
-	golang.org/x/net v0.0.0-20200324143707-d3edc9973b7e
-	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55 // indirect
-	google.golang.org/grpc v1.23.0 // indirect
-	gopkg.in/yaml.v2 v2.2.2
-	k8s.io/api v0.0.0-20190805182251-6c9aa3caf3d6
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.20.0
+	go.opentelemetry.io/otel v0.20.0
+	go.opentelemetry.io/otel/exporters/otlp v0.20.0
+	go.opentelemetry.io/otel/exporters/stdout v0.20.0
+	go.opentelemetry.io/otel/sdk v0.20.0
+	go.opentelemetry.io/otel/trace v0.20.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
+	gopkg.in/yaml.v2 v2.2.3
+	k8s.io/api v0.0.0-20190805182251-6c9aa3caf3d6 // indirect
 	k8s.io/apimachinery v0.0.0-20190816221834-a9f1d8a9c101
 	k8s.io/client-go v11.0.1-0.20190805182715-88a2adca7e76+incompatible
+	k8s.io/kube-openapi v0.0.0-20190502190224-411b2483e503 // indirect
+	k8s.io/utils v0.0.0-20190506122338-8fab8cb257d5 // indirect
+	sigs.k8s.io/yaml v1.1.0 // indirect
 )
@@ -1,36 +1,268 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
-	"sync"
+	"syscall"
+	"time"
 
 	"github.com/rancher/kontainer-engine/types"
 	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/logging"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/metrics"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/tracing"
 	"github.com/sirupsen/logrus"
 )
 
-var wg = &sync.WaitGroup{}
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+const (
+	defaultListenAddress = "127.0.0.1"
+	defaultDrainTimeout  = 30 * time.Second
+	// abortTimeout is how long cancelled calls get to return after the drain
+	// period before the process exits anyway.
+	abortTimeout = 10 * time.Second
+)
+
+type config struct {
+	port           int
+	listenAddress  string
+	logLevel       string
+	logFormat      string
+	metricsAddress string
+	drainTimeout   time.Duration
+	showVersion    bool
+}
+
+func newFlagSet(cfg *config) *flag.FlagSet {
+	flags := flag.NewFlagSet("kontainer-engine-driver-doks", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.IntVar(&cfg.port, "port", 0, "port of the driver gRPC server")
+	flags.StringVar(&cfg.listenAddress, "listen-address", defaultListenAddress, "address the gRPC server listens on")
+	flags.StringVar(&cfg.logLevel, "log-level", os.Getenv(logging.LevelEnv), "log level: debug, info, warn or error")
+	flags.StringVar(&cfg.logFormat, "log-format", os.Getenv(logging.FormatEnv), "log format: text or json")
+	flags.StringVar(&cfg.metricsAddress, "metrics-address", os.Getenv("DOKS_METRICS_ADDRESS"),
+		"address of the Prometheus metrics listener, disabled when empty")
+	flags.DurationVar(&cfg.drainTimeout, "drain-timeout", defaultDrainTimeout,
+		"time running calls get to finish on shutdown before they are cancelled")
+	flags.BoolVar(&cfg.showVersion, "version", false, "print the driver version and exit")
+
+	return flags
+}
+
+// parseArgs reads the flags. Rancher starts the driver with the port as the
+// only argument, so a positional port is accepted as well as --port.
+func parseArgs(args []string) (config, error) {
+	cfg := config{}
+	flags := newFlagSet(&cfg)
+
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if cfg.showVersion {
+		return cfg, nil
+	}
+
+	if flags.NArg() > 1 {
+		return cfg, fmt.Errorf("unexpected arguments %v", flags.Args()[1:])
+	}
+
+	if flags.NArg() == 1 {
+		port, err := strconv.Atoi(flags.Arg(0))
+
+		if err != nil {
+			return cfg, fmt.Errorf("argument %q not parsable as a port", flags.Arg(0))
+		}
+
+		cfg.port = port
+	}
+
+	if cfg.port <= 0 || cfg.port > 65535 {
+		return cfg, errors.New("no valid port provided, use --port or pass it as the first argument")
+	}
+
+	if cfg.drainTimeout < 0 {
+		return cfg, errors.New("drain timeout cannot be negative")
+	}
+
+	return cfg, nil
+}
 
 func main() {
-	if os.Args[1] == "" {
-		panic(errors.New("no port provided"))
+	if handled, err := runSubcommand(os.Args[1:]); handled {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+		return
 	}
 
-	port, err := strconv.Atoi(os.Args[1])
+	cfg, err := parseArgs(os.Args[1:])
+
+	if err == flag.ErrHelp {
+		printUsage()
+		return
+	}
 
 	if err != nil {
-		panic(fmt.Errorf("argument not parsable as int: %v", err))
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		printUsage()
+		os.Exit(2)
+	}
+
+	if cfg.showVersion {
+		fmt.Println(version)
+		return
+	}
+
+	if err := run(cfg); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// runSubcommand runs the command line tools, handled is false when args
+// start the gRPC server.
+func runSubcommand(args []string) (handled bool, err error) {
+	switch {
+	case len(args) > 0 && args[0] == clientCommand:
+		return true, runClient(args[1:], os.Stdout)
+	case len(args) > 0 && args[0] == applyCommand:
+		return true, runApplyCLI(args[1:])
+	case len(args) > 0 && args[0] == exportCommand:
+		return true, runExportCLI(args[1:])
+	case len(args) > 0 && args[0] == orphansCommand:
+		return true, runOrphansCLI(args[1:])
+	case isCommand(args):
+		return true, runCLI(args)
+	}
+
+	return false, nil
+}
+
+func printUsage() {
+	flags := newFlagSet(&config{})
+	flags.SetOutput(os.Stderr)
+
+	fmt.Fprintln(os.Stderr, "usage: kontainer-engine-driver-doks [flags] [port]")
+	fmt.Fprintln(os.Stderr, "       kontainer-engine-driver-doks <command> --state file [--options file] [flags]")
+	fmt.Fprintln(os.Stderr, "       kontainer-engine-driver-doks client --address host:port <command> [flags]")
+	fmt.Fprintln(os.Stderr, "       kontainer-engine-driver-doks apply --spec file --state file [--dry-run] [--prune]")
+	fmt.Fprintln(os.Stderr, "       kontainer-engine-driver-doks export --state file [--format yaml|json] [--output file]")
+	fmt.Fprintln(os.Stderr, "       kontainer-engine-driver-doks orphans --options file [--known-ids ids] [--state file]... [--min-age hours] [--delete --yes]")
+	flags.PrintDefaults()
+
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+
+	for _, name := range commandNames() {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].description)
+	}
+}
+
+func run(cfg config) error {
+	if err := logging.Configure(cfg.logLevel, cfg.logFormat); err != nil {
+		return err
+	}
+
+	clientConfig, err := service.ClientConfigFromEnv()
+
+	if err != nil {
+		return err
+	}
+
+	if err := clientConfig.Validate(); err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.ConfigFromEnv())
+
+	if err != nil {
+		return err
+	}
+
+	var metricsServer *http.Server
+
+	if cfg.metricsAddress != "" {
+		metricsServer, err = metrics.Serve(cfg.metricsAddress)
+
+		if err != nil {
+			return err
+		}
+
+		logrus.Infof("DOKS driver metrics available at http://%s/metrics", metricsServer.Addr)
 	}
 
-	addr := make(chan string)
 	driver := doks.NewDriver()
-	go types.NewServer(&driver, addr).ServeOrDie(fmt.Sprintf("127.0.0.1:%v", port))
+	gracefulDriver := doks.NewGracefulDriver(doks.NewInstrumentedDriver(&driver))
+
+	addr := make(chan string, 1)
+	serveErr := make(chan error, 1)
+	server := types.NewServer(gracefulDriver, addr)
+
+	go server.Serve(fmt.Sprintf("%s:%d", cfg.listenAddress, cfg.port), serveErr)
+
+	select {
+	case address := <-addr:
+		logrus.Infof("DOKS driver %s up and running on %s", version, address)
+	case err := <-serveErr:
+		return err
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case sig := <-signals:
+		logrus.Infof("Received %s, draining running calls for up to %s", sig, cfg.drainTimeout)
+	case err := <-serveErr:
+		return err
+	}
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.drainTimeout)
+	defer cancelDrain()
+
+	abortCtx, abort := context.WithTimeout(context.Background(), cfg.drainTimeout+abortTimeout)
+	defer abort()
+
+	// a second signal skips the drain
+	go func() {
+		select {
+		case <-signals:
+			logrus.Warn("Received a second signal, cancelling running calls")
+			cancelDrain()
+			abort()
+		case <-abortCtx.Done():
+		}
+	}()
+
+	if err := gracefulDriver.Shutdown(drainCtx, abortCtx); err != nil {
+		logrus.Warnf("Running calls were cancelled on shutdown: %v", err)
+	}
+
+	server.Stop()
+
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancelStop()
+
+	if metricsServer != nil {
+		metricsServer.Shutdown(stopCtx)
+	}
+
+	if err := shutdownTracing(stopCtx); err != nil {
+		logrus.Warnf("Error flushing traces on shutdown: %v", err)
+	}
 
-	logrus.Debugf("DOKS driver up and running on at %v", <-addr)
+	logrus.Info("DOKS driver stopped")
 
-	wg.Add(1)
-	wg.Wait()
+	return nil
 }
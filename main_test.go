@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArgsPositionalPort(t *testing.T) {
+	cfg, err := parseArgs([]string{"--log-level", "info", "9000"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9000, cfg.port)
+	assert.Equal(t, "info", cfg.logLevel)
+	assert.Equal(t, defaultListenAddress, cfg.listenAddress)
+	assert.Equal(t, defaultDrainTimeout, cfg.drainTimeout)
+}
+
+func TestParseArgsFlags(t *testing.T) {
+	cfg, err := parseArgs([]string{"--port", "9001", "--listen-address", "0.0.0.0",
+		"--metrics-address", ":9090", "--drain-timeout", "1m", "--log-format", "json"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9001, cfg.port)
+	assert.Equal(t, "0.0.0.0", cfg.listenAddress)
+	assert.Equal(t, ":9090", cfg.metricsAddress)
+	assert.Equal(t, time.Minute, cfg.drainTimeout)
+	assert.Equal(t, "json", cfg.logFormat)
+}
+
+func TestParseArgsVersion(t *testing.T) {
+	cfg, err := parseArgs([]string{"--version"})
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.showVersion)
+}
+
+func TestParseArgsInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{""},
+		{"port"},
+		{"70000"},
+		{"9000", "9001"},
+		{"--drain-timeout", "-1s", "9000"},
+		{"--unknown", "9000"},
+	} {
+		_, err := parseArgs(args)
+
+		assert.Error(t, err, "args %v", args)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/options"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+)
+
+// orphansCommand reports, and optionally deletes, the clusters tagged by the
+// driver that no known cluster refers to.
+const orphansCommand = "orphans"
+
+// stringList collects a flag given several times.
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+type orphansArgs struct {
+	optionsFile string
+	knownIDs    string
+	stateFiles  stringList
+	format      string
+	minAge      int
+	deleteAll   bool
+	confirmed   bool
+}
+
+type orphanReport struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	RegionSlug  string    `json:"region_slug"`
+	CreatedAt   time.Time `json:"created_at"`
+	Age         string    `json:"age"`
+	NodeCount   int       `json:"node_count"`
+	MonthlyCost float64   `json:"monthly_cost"`
+	Deleted     bool      `json:"deleted,omitempty"`
+}
+
+func parseOrphansArgs(args []string) (orphansArgs, error) {
+	parsed := orphansArgs{}
+
+	flags := flag.NewFlagSet(orphansCommand, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&parsed.optionsFile, "options", "", "YAML or JSON file with the credential and API options")
+	flags.StringVar(&parsed.knownIDs, "known-ids", "", "comma separated IDs of the clusters known to Rancher")
+	flags.Var(&parsed.stateFiles, "state", "state file of a known cluster, may be repeated")
+	flags.StringVar(&parsed.format, "format", "text", "report format: text or json")
+	flags.IntVar(&parsed.minAge, "min-age", 1, "hours a cluster must have existed to be reported")
+	flags.BoolVar(&parsed.deleteAll, "delete", false, "delete the orphaned clusters")
+	flags.BoolVar(&parsed.confirmed, "yes", false, "confirm the deletion asked with --delete")
+
+	if err := flags.Parse(args); err != nil {
+		return parsed, fmt.Errorf("%s: %v", orphansCommand, err)
+	}
+
+	if flags.NArg() > 0 {
+		return parsed, fmt.Errorf("%s: unexpected arguments %v", orphansCommand, flags.Args())
+	}
+
+	if parsed.optionsFile == "" {
+		return parsed, fmt.Errorf("%s: --options is required", orphansCommand)
+	}
+
+	if parsed.format != "text" && parsed.format != "json" {
+		return parsed, fmt.Errorf("%s: format %q is not supported, use text or json", orphansCommand, parsed.format)
+	}
+
+	if parsed.minAge < 0 {
+		return parsed, fmt.Errorf("%s: --min-age must not be negative", orphansCommand)
+	}
+
+	if parsed.deleteAll && !parsed.confirmed {
+		return parsed, fmt.Errorf("%s: --delete removes the clusters for good, confirm with --yes", orphansCommand)
+	}
+
+	return parsed, nil
+}
+
+// runOrphans lists the orphaned clusters of the account the options give
+// access to, with their age, node count and estimated monthly cost. Clusters
+// younger than --min-age are left out, since Rancher may not have saved them
+// yet. With --delete they are deleted as well, and the report tells which
+// ones were.
+func runOrphans(ctx context.Context, factory service.DigitalOceanFactory, args []string, stdout io.Writer) error {
+	parsed, err := parseOrphansArgs(args)
+
+	if err != nil {
+		return err
+	}
+
+	clusterState, err := readCredentialOptions(parsed.optionsFile)
+
+	if err != nil {
+		return err
+	}
+
+	knownIDs, err := knownClusterIDs(parsed)
+
+	if err != nil {
+		return err
+	}
+
+	if parsed.deleteAll && len(knownIDs) == 0 {
+		return fmt.Errorf("%s: --delete needs the known clusters, from --known-ids or --state, "+
+			"or every managed cluster would be deleted", orphansCommand)
+	}
+
+	digitalOceanService := factory(clusterState.DigitalOceanCredential(), clusterState.API)
+
+	orphans, err := digitalOceanService.FindOrphans(ctx, state.OwnerTag(), knownIDs)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	minAge := time.Duration(parsed.minAge) * time.Hour
+	reports := make([]orphanReport, 0, len(orphans))
+
+	for _, orphan := range orphans {
+		if orphan.Age(now) < minAge {
+			continue
+		}
+
+		reports = append(reports, orphanReport{
+			ID:          orphan.ID,
+			Name:        orphan.Name,
+			RegionSlug:  orphan.RegionSlug,
+			CreatedAt:   orphan.CreatedAt,
+			Age:         formatAge(orphan.Age(now)),
+			NodeCount:   orphan.NodeCount,
+			MonthlyCost: orphan.MonthlyCost,
+		})
+	}
+
+	var deleteErr error
+
+	if parsed.deleteAll {
+		for index := range reports {
+			err := digitalOceanService.DeleteCluster(ctx, reports[index].ID)
+
+			if err != nil && !service.IsNotFound(err) {
+				if deleteErr == nil {
+					deleteErr = err
+				}
+
+				continue
+			}
+
+			reports[index].Deleted = true
+		}
+	}
+
+	if parsed.format == "json" {
+		if err := printJSON(stdout, reports); err != nil {
+			return err
+		}
+
+		return deleteErr
+	}
+
+	if err := printOrphans(stdout, reports); err != nil {
+		return err
+	}
+
+	return deleteErr
+}
+
+// readCredentialOptions reads the credential and API settings from options
+// in the create options format.
+func readCredentialOptions(path string) (state.Cluster, error) {
+	driverOptions, err := readOptions(path, options.NewBuilder().BuildCreateOptions(), false)
+
+	if err != nil {
+		return state.Cluster{}, err
+	}
+
+	clusterState, _, err := state.NewBuilder().BuildStatesFromOpts(driverOptions)
+
+	if err != nil {
+		return clusterState, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := service.ValidateAPI(clusterState.API); err != nil {
+		return clusterState, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if err := clusterState.DigitalOceanCredential().Validate(); err != nil {
+		return clusterState, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return clusterState, nil
+}
+
+// knownClusterIDs gathers the IDs given on the command line and the ones of
+// the state files.
+func knownClusterIDs(parsed orphansArgs) ([]string, error) {
+	knownIDs := []string{}
+
+	for _, clusterID := range strings.Split(parsed.knownIDs, ",") {
+		if clusterID = strings.TrimSpace(clusterID); clusterID != "" {
+			knownIDs = append(knownIDs, clusterID)
+		}
+	}
+
+	for _, stateFile := range parsed.stateFiles {
+		clusterInfo, err := readClusterInfo(stateFile, false)
+
+		if err != nil {
+			return nil, err
+		}
+
+		clusterState, err := state.NewBuilder().BuildClusterStateFromClusterInfo(clusterInfo)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", stateFile, err)
+		}
+
+		knownIDs = append(knownIDs, clusterState.ClusterID)
+	}
+
+	return knownIDs, nil
+}
+
+func printOrphans(stdout io.Writer, reports []orphanReport) error {
+	if len(reports) == 0 {
+		_, err := fmt.Fprintln(stdout, "No orphaned clusters.")
+		return err
+	}
+
+	writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	total := 0.0
+
+	fmt.Fprintln(writer, "ID\tNAME\tREGION\tAGE\tNODES\tMONTHLY COST\t")
+
+	for _, report := range reports {
+		status := ""
+
+		if report.Deleted {
+			status = "deleted"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t$%.2f\t%s\n", report.ID, report.Name, report.RegionSlug,
+			report.Age, report.NodeCount, report.MonthlyCost, status)
+
+		total += report.MonthlyCost
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(stdout, "%d orphaned clusters, $%.2f per month\n", len(reports), total)
+
+	return err
+}
+
+// formatAge writes ages in days past a day, and in hours and minutes below.
+func formatAge(age time.Duration) string {
+	if age >= 24*time.Hour {
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+
+	return age.Truncate(time.Minute).String()
+}
+
+// runOrphansCLI runs orphans with the DigitalOcean client settings of the
+// environment.
+func runOrphansCLI(args []string) error {
+	ctx, done, err := setupCLI()
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	return runOrphans(ctx, service.NewDigitalOceanFactory(), args, os.Stdout)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/service/fake"
+	"github.com/ribeiro-rodrigo/kontainer-engine-driver-doks/doks/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrphansCommand(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	factory := service.NewDigitalOceanFactoryWithConfig(service.ClientConfig{BaseURL: server.URL})
+
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	optionsFile := filepath.Join(dir, "options.yaml")
+	writeFile(t, optionsFile, "token: orphans-token\n")
+
+	created := time.Now().Add(-72 * time.Hour)
+	os.Setenv(state.OwnerTagEnv, "install-a")
+	defer os.Unsetenv(state.OwnerTagEnv)
+
+	managed := []string{state.ManagedTag, "rancher-owner:install-a"}
+
+	knownID := server.AddCluster(&godo.KubernetesCluster{Name: "known", RegionSlug: "nyc1", Tags: managed})
+	stateID := server.AddCluster(&godo.KubernetesCluster{Name: "saved", RegionSlug: "nyc1", Tags: managed})
+	orphanID := server.AddCluster(&godo.KubernetesCluster{Name: "orphan", RegionSlug: "nyc1", Tags: managed,
+		CreatedAt: created, NodePools: []*godo.KubernetesNodePool{{Name: "workers", Size: "s-1vcpu-2gb", Count: 2}}})
+	otherOwnerID := server.AddCluster(&godo.KubernetesCluster{Name: "other", RegionSlug: "nyc1",
+		Tags: []string{state.ManagedTag, "rancher-owner:install-b"}, CreatedAt: created})
+
+	stateFile := filepath.Join(dir, "state.json")
+	writeFile(t, stateFile, `{"metadata":{"state":"{\"cluster_id\":\"`+stateID+`\"}"}}`)
+
+	output := &bytes.Buffer{}
+	err := runOrphans(context.TODO(), factory, []string{"--options", optionsFile, "--known-ids", knownID,
+		"--state", stateFile}, output)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), orphanID, "Orphan reported")
+	assert.Contains(t, output.String(), "3d", "Age reported")
+	assert.Contains(t, output.String(), "1 orphaned clusters, $20.00 per month", "Cost summed")
+	assert.NotContains(t, output.String(), knownID, "Known cluster left out")
+	assert.NotContains(t, output.String(), stateID, "Cluster of the state file left out")
+	assert.NotContains(t, output.String(), otherOwnerID, "Cluster of another installation left out")
+
+	_, ok := server.Cluster(orphanID)
+	assert.True(t, ok, "Nothing deleted without --delete")
+
+	output.Reset()
+	err = runOrphans(context.TODO(), factory, []string{"--options", optionsFile, "--known-ids", knownID,
+		"--state", stateFile, "--delete", "--yes", "--format", "json"}, output)
+
+	assert.NoError(t, err)
+
+	reports := []orphanReport{}
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &reports))
+	assert.Len(t, reports, 1)
+	assert.True(t, reports[0].Deleted, "Deletion reported")
+	assert.Equal(t, 2, reports[0].NodeCount)
+
+	assert.Len(t, server.RequestsTo("DELETE", "/v2/kubernetes/clusters/"+orphanID), 1, "Orphan deleted")
+	assert.Empty(t, server.RequestsTo("DELETE", "/v2/kubernetes/clusters/"+knownID), "Known cluster kept")
+	assert.Empty(t, server.RequestsTo("DELETE", "/v2/kubernetes/clusters/"+otherOwnerID),
+		"Cluster of another installation kept")
+}
+
+func TestOrphansCommandSkipsYoungClusters(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	factory := service.NewDigitalOceanFactoryWithConfig(service.ClientConfig{BaseURL: server.URL})
+
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	optionsFile := filepath.Join(dir, "options.yaml")
+	writeFile(t, optionsFile, "token: orphans-token\n")
+
+	os.Setenv(state.OwnerTagEnv, "install-a")
+	defer os.Unsetenv(state.OwnerTagEnv)
+
+	managed := []string{state.ManagedTag, "rancher-owner:install-a"}
+
+	youngID := server.AddCluster(&godo.KubernetesCluster{Name: "creating", RegionSlug: "nyc1", Tags: managed,
+		CreatedAt: time.Now().Add(-10 * time.Minute)})
+	oldID := server.AddCluster(&godo.KubernetesCluster{Name: "old", RegionSlug: "nyc1", Tags: managed,
+		CreatedAt: time.Now().Add(-48 * time.Hour)})
+
+	output := &bytes.Buffer{}
+	err := runOrphans(context.TODO(), factory, []string{"--options", optionsFile}, output)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), oldID, "Old orphan reported")
+	assert.NotContains(t, output.String(), youngID, "Cluster being created left out")
+
+	output.Reset()
+	err = runOrphans(context.TODO(), factory, []string{"--options", optionsFile, "--min-age", "72"}, output)
+
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "No orphaned clusters.", "Clusters younger than --min-age left out")
+}
+
+func TestOrphansCommandRefusesDeleteWithoutKnownClusters(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	factory := service.NewDigitalOceanFactoryWithConfig(service.ClientConfig{BaseURL: server.URL})
+
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	optionsFile := filepath.Join(dir, "options.yaml")
+	writeFile(t, optionsFile, "token: orphans-token\n")
+
+	clusterID := server.AddCluster(&godo.KubernetesCluster{Name: "production", RegionSlug: "nyc1",
+		Tags: []string{state.ManagedTag}, CreatedAt: time.Now().Add(-72 * time.Hour)})
+
+	err := runOrphans(context.TODO(), factory, []string{"--options", optionsFile, "--known-ids", " , ",
+		"--delete", "--yes"}, &bytes.Buffer{})
+
+	assert.Error(t, err, "Delete refused without known clusters")
+	assert.Empty(t, server.RequestsTo("DELETE", "/v2/kubernetes/clusters/"+clusterID), "Nothing deleted")
+}
+
+func TestOrphansCommandRefusesWithoutOwner(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	factory := service.NewDigitalOceanFactoryWithConfig(service.ClientConfig{BaseURL: server.URL})
+
+	dir, cleanup := newCommandDir(t)
+	defer cleanup()
+
+	optionsFile := filepath.Join(dir, "options.yaml")
+	writeFile(t, optionsFile, "token: orphans-token\n")
+
+	clusterID := server.AddCluster(&godo.KubernetesCluster{Name: "production", RegionSlug: "nyc1",
+		Tags: []string{state.ManagedTag}, CreatedAt: time.Now().Add(-72 * time.Hour)})
+
+	err := runOrphans(context.TODO(), factory, []string{"--options", optionsFile, "--known-ids", "other",
+		"--delete", "--yes"}, &bytes.Buffer{})
+
+	assert.Error(t, err, "Orphans refused without an owner tag")
+	assert.Empty(t, server.RequestsTo("DELETE", "/v2/kubernetes/clusters/"+clusterID), "Nothing deleted")
+}
+
+func TestOrphansCommandArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"--options", "options.yaml", "--format", "csv"},
+		{"--options", "options.yaml", "extra"},
+		{"--options", "options.yaml", "--min-age", "-1"},
+		{"--options", "options.yaml", "--delete"},
+	} {
+		_, err := parseOrphansArgs(args)
+
+		assert.Error(t, err, "args %v", args)
+	}
+}